@@ -0,0 +1,47 @@
+// migrate-index 使用新的向量索引参数重建知识库索引，在 GopherAI-v2 目录下运行：
+//
+//	go run ./cmd/migrate-index -kb 1 -algorithm HNSW -m 32 -ef-construction 400
+//
+// 新索引在后台建好后才通过别名切换，迁移期间服务可以正常检索和入库
+package main
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/common/rag"
+	"GopherAI/common/redis"
+	"context"
+	"flag"
+	"log"
+)
+
+func main() {
+	kbID := flag.Uint("kb", 0, "要迁移的知识库 ID")
+	algorithm := flag.String("algorithm", "", "FLAT | HNSW，为空时沿用当前参数")
+	metric := flag.String("metric", "", "COSINE | L2 | IP，为空时沿用当前参数")
+	vectorType := flag.String("vector-type", "", "FLOAT32 | FLOAT64，为空时沿用当前参数")
+	m := flag.Int("m", 0, "HNSW 参数 M")
+	efConstruction := flag.Int("ef-construction", 0, "HNSW 参数 EF_CONSTRUCTION")
+	efRuntime := flag.Int("ef-runtime", 0, "HNSW 参数 EF_RUNTIME")
+	flag.Parse()
+
+	if *kbID == 0 {
+		log.Fatal("-kb is required")
+	}
+	if err := mysql.InitMysql(); err != nil {
+		log.Fatal("InitMysql error, ", err)
+	}
+	redis.Init()
+
+	opts := redis.VectorIndexOptions{
+		Algorithm:      *algorithm,
+		Metric:         *metric,
+		VectorType:     *vectorType,
+		M:              *m,
+		EfConstruction: *efConstruction,
+		EfRuntime:      *efRuntime,
+	}
+	if err := rag.MigrateKnowledgeBaseIndex(context.Background(), *kbID, opts); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("knowledge base %d migrated", *kbID)
+}
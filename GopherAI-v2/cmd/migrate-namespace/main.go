@@ -0,0 +1,48 @@
+// migrate-namespace 迁移旧版本的知识库数据，在 GopherAI-v2 目录下运行，升级后启动服务前执行一次：
+//
+//	go run ./cmd/migrate-namespace -dry-run
+//	go run ./cmd/migrate-namespace
+//
+// 按用户隔离之前创建的知识库索引（rag_docs:kb_<id>:*）迁移到带所有者的命名空间（rag_docs:u_<用户名>:kb_<id>:*），
+// 并在 MySQL 中记录索引的所有者。
+// 按知识库管理之前直接保存在用户目录下的文件（uploads/<用户名>/<文件>）导入用户的默认知识库并重新入库，
+// 再删除按文件建立的旧索引（rag_docs:<文件名>:*）及其数据；服务启动时同样会执行这一步（入库任务通过消息队列执行），
+// 这里在当前进程中直接入库。
+package main
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/common/rag"
+	"GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
+	"GopherAI/service/file"
+	"context"
+	"flag"
+	"log"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只打印将要迁移的索引和文件，不做修改")
+	flag.Parse()
+
+	if err := mysql.InitMysql(); err != nil {
+		log.Fatal("InitMysql error, ", err)
+	}
+	redis.Init()
+
+	ctx := context.Background()
+	migrated, err := rag.MigrateLegacyIndexes(ctx, *dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d knowledge base indexes migrated", migrated)
+
+	imported, dropped, err := file.MigrateLegacyUploads(ctx, *dryRun, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d legacy uploads imported, %d legacy file indexes dropped", imported, dropped)
+	if err := vectorstore.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
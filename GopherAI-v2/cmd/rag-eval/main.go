@@ -0,0 +1,124 @@
+// rag-eval 使用标注好的问题集离线评估 RAG 检索和回答质量，在 GopherAI-v2 目录下运行：
+//
+//	go run ./cmd/rag-eval -dataset eval/faq.yaml
+//	go run ./cmd/rag-eval -dataset eval/faq.yaml -a eval/hybrid.toml -b eval/vector.toml -model 1 -out result.json
+//
+// 问题集为 YAML（或每行一个问题的 JSONL），格式见 common/rag/eval.Dataset，例如：
+//
+//	documents: [../docs]           # 语料，提供时在内存中为每套配置建临时索引
+//	questions:
+//	  - id: reset-password
+//	    question: 忘记密码怎么办？
+//	    expected:
+//	      - document: faq.md
+//	        contains: 在登录页点击“忘记密码”
+//	    answer: 在登录页点击“忘记密码”，通过邮箱验证码重置。
+//
+// -a、-b 为 TOML 覆盖文件，只写与 config.toml 不同的部分（如 [chunkConfig] chunkSize = 300），
+// 同时提供时输出两套配置的对比表。问题集没有语料时在 -user 的已有知识库中检索，需要 MySQL 和 Redis
+package main
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/common/rag/eval"
+	"GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
+	"GopherAI/config"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	datasetPath := flag.String("dataset", "", "问题集文件（.yaml / .yml / .json / .jsonl）")
+	variantA := flag.String("a", "", "配置 A 的 TOML 覆盖文件，为空时使用当前配置")
+	variantB := flag.String("b", "", "配置 B 的 TOML 覆盖文件，提供时与配置 A 对比")
+	docs := flag.String("docs", "", "额外的语料文件或目录，多个用逗号分隔")
+	username := flag.String("user", "", "没有语料时，在该用户的知识库中检索")
+	kbID := flag.Uint("kb", 0, "没有语料时使用的知识库 ID，0 表示默认知识库")
+	k := flag.Int("k", 0, "recall@k 的 k，0 表示使用 retrievalConfig.topK")
+	modelType := flag.String("model", "", "生成回答使用的模型类型（1 OpenAI、2 阿里百炼、4 Ollama），为空时只评估检索")
+	out := flag.String("out", "", "将每个问题的详细结果写入 JSON 文件")
+	flag.Parse()
+
+	if *datasetPath == "" {
+		log.Fatal("-dataset is required")
+	}
+	dataset, err := eval.LoadDataset(*datasetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *docs != "" {
+		dataset.Documents = append(dataset.Documents, strings.Split(*docs, ",")...)
+	}
+
+	evaluator, err := eval.NewEvaluator(dataset, eval.Options{
+		Username:        *username,
+		KnowledgeBaseID: *kbID,
+		K:               *k,
+		ModelType:       *modelType,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if evaluator.UsesCorpus() {
+		// 临时索引只建在内存中，不需要 Redis，也不影响线上数据
+		conf := config.GetConfig()
+		conf.VectorStoreBackend = vectorstore.BackendMemory
+		conf.VectorStoreSnapshotPath = ""
+	} else {
+		if *username == "" {
+			log.Fatal("-user is required when the dataset has no documents")
+		}
+		if err := mysql.InitMysql(); err != nil {
+			log.Fatal("InitMysql error, ", err)
+		}
+		redis.Init()
+	}
+
+	variants := []eval.Variant{{Name: variantName(*variantA, "current"), Path: *variantA}}
+	if *variantB != "" {
+		variants = append(variants, eval.Variant{Name: variantName(*variantB, "B"), Path: *variantB})
+		if variants[0].Name == variants[1].Name {
+			variants[0].Name, variants[1].Name = "A", "B"
+		}
+	}
+
+	ctx := context.Background()
+	defer evaluator.Close(ctx)
+	results := make([]*eval.Result, 0, len(variants))
+	for _, v := range variants {
+		log.Printf("evaluating %s (%d questions)", v.Name, len(dataset.Questions))
+		result, err := evaluator.Run(ctx, v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		results = append(results, result)
+	}
+
+	if err := eval.WriteReport(os.Stdout, results); err != nil {
+		log.Fatal(err)
+	}
+	if *out != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*out, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("results written to %s", *out)
+	}
+}
+
+// variantName 以覆盖文件名作为配置名
+func variantName(path, fallback string) string {
+	if path == "" {
+		return fallback
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
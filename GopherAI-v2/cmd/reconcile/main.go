@@ -0,0 +1,71 @@
+// reconcile 对比上传目录、MySQL 中的文档记录和 Redis 中的索引与块，报告并修复差异，在 GopherAI-v2 目录下运行：
+//
+//	go run ./cmd/reconcile                # 只报告差异
+//	go run ./cmd/reconcile -dry-run=false  # 修复差异
+//
+// 服务端按 storageConfig.reconcileInterval 定期执行同样的对账；丢失了块的文档在本进程中直接重新入库
+package main
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
+	"GopherAI/service/file"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "只报告差异，不做修复；-dry-run=false 时修复")
+	asJSON := flag.Bool("json", false, "以 JSON 输出完整报告")
+	flag.Parse()
+
+	// memory 存储的数据只在服务进程中，命令行工具修改的是另一份快照
+	if _, ok := vectorstore.Default().(*vectorstore.RedisStore); !ok {
+		log.Fatal("reconcile command only supports the redis vector store, the memory store is reconciled by the server")
+	}
+	if err := mysql.InitMysql(); err != nil {
+		log.Fatal("InitMysql error, ", err)
+	}
+	redis.Init()
+
+	report, err := file.Reconcile(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tINDEX\tPATH / DOCUMENT\tCHUNKS\tRESULT")
+	for _, issue := range report.Issues {
+		target := issue.Path
+		if target == "" {
+			target = issue.Document
+		}
+		if issue.DocumentID > 0 {
+			target = fmt.Sprintf("%s (#%d)", target, issue.DocumentID)
+		}
+		result := "found"
+		switch {
+		case issue.Fixed:
+			result = "fixed"
+		case issue.Error != "":
+			result = "error: " + issue.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", issue.Kind, issue.Index, target, issue.Chunks, result)
+	}
+	w.Flush()
+	fmt.Println(report.Summary())
+}
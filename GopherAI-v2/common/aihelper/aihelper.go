@@ -2,10 +2,15 @@ package aihelper
 
 import (
 	"GopherAI/common/rabbitmq"
+	"GopherAI/common/rag"
+	"GopherAI/common/redact"
+	"GopherAI/config"
 	"GopherAI/model"
 	"GopherAI/utils"
 	"context"
 	"sync"
+
+	"github.com/cloudwego/eino/schema"
 )
 
 // AIHelper AI助手结构体，包含消息历史和AI模型
@@ -16,6 +21,10 @@ type AIHelper struct {
 	//一个会话绑定一个AIHelper
 	SessionID string
 	saveFunc  func(*model.Message) (*model.Message, error)
+	//敏感信息脱敏会话，为nil表示不做脱敏
+	pii *redact.Session
+	//会话关联的知识库，调用模型前在这些知识库中检索相关文档
+	knowledgeBaseIDs []uint
 }
 
 // NewAIHelper 创建新的AIHelper实例
@@ -25,21 +34,77 @@ func NewAIHelper(model_ AIModel, SessionID string) *AIHelper {
 		messages: make([]*model.Message, 0),
 		//异步推送到消息队列中
 		saveFunc: func(msg *model.Message) (*model.Message, error) {
-			data := rabbitmq.GenerateMessageMQParam(msg.SessionID, msg.Content, msg.UserName, msg.IsUser)
+			data := rabbitmq.GenerateMessageMQParam(msg.SessionID, msg.Content, msg.UserName, msg.IsUser, msg.Citations)
 			err := rabbitmq.RMQMessage.Publish(data)
 			return msg, err
 		},
 		SessionID: SessionID,
+		pii:       newPIISession(model_.GetModelType()),
+	}
+}
+
+// newPIISession 根据配置为会话创建脱敏会话，本地模型可按配置跳过
+func newPIISession(modelType string) *redact.Session {
+	redactor := redact.GetGlobalRedactor()
+	if redactor == nil {
+		return nil
+	}
+	if config.GetConfig().PIISkipLocalModels && isLocalModel(modelType) {
+		return nil
+	}
+	return redactor.NewSession()
+}
+
+// isLocalModel 判断模型是否运行在本地（数据不会离开内网）
+func isLocalModel(modelType string) bool {
+	return modelType == "4"
+}
+
+// skipsRedaction 会话使用本地模型并按配置跳过了脱敏，对话内容只能发送给本地模型
+func (a *AIHelper) skipsRedaction() bool {
+	return a.pii == nil && redact.GetGlobalRedactor() != nil
+}
+
+// redactMessages 发送给模型前，将消息中的敏感信息替换为占位符
+func (a *AIHelper) redactMessages(messages []*schema.Message) []*schema.Message {
+	if a.pii == nil {
+		return messages
 	}
+	for _, m := range messages {
+		m.Content = a.pii.Redact(m.Content)
+	}
+	return messages
+}
+
+// redactContent 发送给模型前，将单条内容中的敏感信息替换为占位符
+func (a *AIHelper) redactContent(content string) string {
+	if a.pii == nil {
+		return content
+	}
+	return a.pii.Redact(content)
+}
+
+// restoreContent 将模型回复中的占位符还原为原始内容
+func (a *AIHelper) restoreContent(content string) string {
+	if a.pii == nil {
+		return content
+	}
+	return a.pii.Restore(content)
 }
 
 // addMessage 添加消息到内存中并调用自定义存储函数
 func (a *AIHelper) AddMessage(Content string, UserName string, IsUser bool, Save bool) {
+	a.AddMessageWithCitations(Content, UserName, IsUser, nil, Save)
+}
+
+// AddMessageWithCitations 添加带引用来源的消息
+func (a *AIHelper) AddMessageWithCitations(Content string, UserName string, IsUser bool, Citations []model.Citation, Save bool) {
 	userMsg := model.Message{
 		SessionID: a.SessionID,
 		Content:   Content,
 		UserName:  UserName,
 		IsUser:    IsUser,
+		Citations: Citations,
 	}
 	a.messages = append(a.messages, &userMsg)
 	if Save {
@@ -73,17 +138,36 @@ func (a *AIHelper) GenerateResponse(userName string, ctx context.Context, userQu
 	messages := utils.ConvertToSchemaMessages(a.messages)
 	a.mu.RUnlock()
 
-	//调用模型生成回复
-	schemaMsg, err := a.model.GenerateResponse(ctx, messages)
-	if err != nil {
-		return nil, err
+	//脱敏后再发送给模型
+	messages = a.redactMessages(messages)
+
+	//会话关联了知识库时，检索相关文档并改写最后一条消息
+	messages, docs, outcome := a.augmentMessages(ctx, userName, messages)
+	refusal, notice := noAnswer(outcome)
+
+	//调用模型生成回复，使用了检索结果时同时返回引用来源；严格模式下没有相关文档时直接拒答
+	var schemaMsg *schema.Message
+	var citations []model.Citation
+	if refusal != "" {
+		schemaMsg = &schema.Message{Role: schema.Assistant, Content: refusal}
+	} else {
+		var err error
+		schemaMsg, err = a.model.GenerateResponse(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) > 0 {
+			citations = rag.ValidateCitations(schemaMsg.Content, rag.BuildCitations(docs))
+		}
+		schemaMsg.Content = notice + a.restoreContent(schemaMsg.Content)
 	}
 
 	//将schema.Message转化成model.Message
 	modelMsg := utils.ConvertToModelMessage(a.SessionID, userName, schemaMsg)
+	modelMsg.Citations = citations
 
 	//调用存储函数
-	a.AddMessage(modelMsg.Content, userName, false, true)
+	a.AddMessageWithCitations(modelMsg.Content, userName, false, citations, true)
 
 	return modelMsg, nil
 }
@@ -98,9 +182,37 @@ func (a *AIHelper) StreamResponse(userName string, ctx context.Context, cb Strea
 	messages := utils.ConvertToSchemaMessages(a.messages)
 	a.mu.RUnlock()
 
-	content, err := a.model.StreamResponse(ctx, messages, cb)
-	if err != nil {
-		return nil, err
+	messages = a.redactMessages(messages)
+
+	//流式片段中的占位符可能被截断，通过还原器暂存后再推送给前端
+	if a.pii != nil {
+		restorer := a.pii.NewStreamRestorer(cb)
+		cb = restorer.Write
+		defer restorer.Flush()
+	}
+
+	messages, docs, outcome := a.augmentMessages(ctx, userName, messages)
+	refusal, notice := noAnswer(outcome)
+
+	var content string
+	var citations []model.Citation
+	if refusal != "" {
+		content = refusal
+		cb(refusal)
+	} else {
+		if notice != "" {
+			cb(notice)
+		}
+		var err error
+		content, err = a.model.StreamResponse(ctx, messages, cb)
+		if err != nil {
+			return nil, err
+		}
+		//已推送的片段无法撤回，保存的回答与推送的内容一致，引用校验结果记录在引用列表中
+		if len(docs) > 0 {
+			citations = rag.ValidateCitations(content, rag.BuildCitations(docs))
+		}
+		content = notice + a.restoreContent(content)
 	}
 	//转化成model.Message
 	modelMsg := &model.Message{
@@ -108,10 +220,11 @@ func (a *AIHelper) StreamResponse(userName string, ctx context.Context, cb Strea
 		UserName:  userName,
 		Content:   content,
 		IsUser:    false,
+		Citations: citations,
 	}
 
 	//调用存储函数
-	a.AddMessage(modelMsg.Content, userName, false, true)
+	a.AddMessageWithCitations(modelMsg.Content, userName, false, citations, true)
 
 	return modelMsg, nil
 }
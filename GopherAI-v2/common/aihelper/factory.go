@@ -1,6 +1,7 @@
 package aihelper
 
 import (
+	ollamaCli "GopherAI/common/ollama"
 	"context"
 	"fmt"
 	"sync"
@@ -39,11 +40,7 @@ func (f *AIModelFactory) registerCreators() {
 
 	// 阿里百炼 RAG 模型
 	f.creators["2"] = func(ctx context.Context, config map[string]interface{}) (AIModel, error) {
-		username, ok := config["username"].(string)
-		if !ok {
-			return nil, fmt.Errorf("RAG model requires username")
-		}
-		return NewAliRAGModel(ctx, username)
+		return NewAliRAGModel(ctx)
 	}
 
 	// MCP 模型（集成MCP服务）
@@ -55,11 +52,17 @@ func (f *AIModelFactory) registerCreators() {
 		return NewMCPModel(ctx, username)
 	}
 
-	//Ollama（目前提供接口实现，暂不提供应用，因为考虑到本地模型会占用很多空间）todo做
+	// Ollama 本地模型，服务地址和默认模型来自配置，请求中可指定其他已安装的模型
 	f.creators["4"] = func(ctx context.Context, config map[string]interface{}) (AIModel, error) {
 		baseURL, _ := config["baseURL"].(string)
-		modelName, ok := config["modelName"].(string)
-		if !ok {
+		modelName, _ := config["modelName"].(string)
+		if baseURL == "" {
+			baseURL = ollamaCli.GetBaseURL()
+		}
+		if modelName == "" {
+			modelName = ollamaCli.GetDefaultModelName()
+		}
+		if modelName == "" {
 			return nil, fmt.Errorf("Ollama model requires modelName")
 		}
 		return NewOllamaModel(ctx, baseURL, modelName)
@@ -1,7 +1,8 @@
 package aihelper
 
 import (
-	"GopherAI/common/rag"
+	ollamaCli "GopherAI/common/ollama"
+	"GopherAI/common/prompt"
 	"GopherAI/config"
 	"context"
 	"encoding/json"
@@ -9,7 +10,9 @@ import (
 	"io"
 	"log"
 	"os"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/eino-ext/components/model/ollama"
 	"github.com/cloudwego/eino-ext/components/model/openai"
@@ -27,11 +30,14 @@ type AIModel interface {
 	GenerateResponse(ctx context.Context, messages []*schema.Message) (*schema.Message, error)
 	StreamResponse(ctx context.Context, messages []*schema.Message, cb StreamCallback) (string, error)
 	GetModelType() string
+	// GetModelName 实际调用的模型名，用于选择按模型覆盖的提示词模板
+	GetModelName() string
 }
 
 // =================== OpenAI 实现 ===================
 type OpenAIModel struct {
-	llm model.ToolCallingChatModel
+	llm       model.ToolCallingChatModel
+	modelName string
 }
 
 func NewOpenAIModel(ctx context.Context) (*OpenAIModel, error) {
@@ -47,7 +53,7 @@ func NewOpenAIModel(ctx context.Context) (*OpenAIModel, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create openai model failed: %v", err)
 	}
-	return &OpenAIModel{llm: llm}, nil
+	return &OpenAIModel{llm: llm, modelName: modelName}, nil
 }
 
 func (o *OpenAIModel) GenerateResponse(ctx context.Context, messages []*schema.Message) (*schema.Message, error) {
@@ -87,22 +93,54 @@ func (o *OpenAIModel) StreamResponse(ctx context.Context, messages []*schema.Mes
 
 func (o *OpenAIModel) GetModelType() string { return "1" }
 
+func (o *OpenAIModel) GetModelName() string { return o.modelName }
+
 // =================== Ollama 实现 ===================
 
 // OllamaModel Ollama模型实现
 type OllamaModel struct {
-	llm model.ToolCallingChatModel
+	llm       model.ToolCallingChatModel
+	modelName string
 }
 
 func NewOllamaModel(ctx context.Context, baseURL, modelName string) (*OllamaModel, error) {
+	conf := config.GetConfig().OllamaConfig
+
+	keepAlive, err := ollamaCli.ParseKeepAlive(conf.OllamaKeepAlive)
+	if err != nil {
+		return nil, fmt.Errorf("create ollama model failed: %v", err)
+	}
+
 	llm, err := ollama.NewChatModel(ctx, &ollama.ChatModelConfig{
-		BaseURL: baseURL,
-		Model:   modelName,
+		BaseURL:   baseURL,
+		Model:     modelName,
+		Timeout:   time.Duration(conf.OllamaTimeout) * time.Second,
+		KeepAlive: keepAlive,
+		Options:   buildOllamaOptions(conf.OllamaOptions),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create ollama model failed: %v", err)
 	}
-	return &OllamaModel{llm: llm}, nil
+	return &OllamaModel{llm: llm, modelName: modelName}, nil
+}
+
+// buildOllamaOptions 将配置转换为 Ollama 的 options 参数，未配置任何选项时返回 nil
+func buildOllamaOptions(conf config.OllamaOptions) *ollama.Options {
+	opts := &ollama.Options{
+		Temperature:   conf.Temperature,
+		TopP:          conf.TopP,
+		TopK:          conf.TopK,
+		NumPredict:    conf.NumPredict,
+		RepeatPenalty: conf.RepeatPenalty,
+		Seed:          conf.Seed,
+		Stop:          conf.Stop,
+	}
+	opts.NumCtx = conf.NumCtx
+
+	if reflect.ValueOf(*opts).IsZero() {
+		return nil
+	}
+	return opts
 }
 
 func (o *OllamaModel) GenerateResponse(ctx context.Context, messages []*schema.Message) (*schema.Message, error) {
@@ -126,7 +164,7 @@ func (o *OllamaModel) StreamResponse(ctx context.Context, messages []*schema.Mes
 			break
 		}
 		if err != nil {
-			return "", fmt.Errorf("openai stream recv failed: %v", err)
+			return "", fmt.Errorf("ollama stream recv failed: %v", err)
 		}
 		if len(msg.Content) > 0 {
 			fullResp.WriteString(msg.Content) // 聚合
@@ -138,13 +176,19 @@ func (o *OllamaModel) StreamResponse(ctx context.Context, messages []*schema.Mes
 
 func (o *OllamaModel) GetModelType() string { return "4" }
 
+func (o *OllamaModel) GetModelName() string { return o.modelName }
+
 // =================== RAG 实现 ===================
+
+// AliRAGModel 阿里百炼模型，使用 ragModelConfig 中的对话模型
+// 检索由 AIHelper 根据会话关联的知识库完成，任何模型都可以结合知识库使用；
+// 为兼容旧会话，该类型的会话没有关联知识库时默认检索用户的默认知识库
 type AliRAGModel struct {
-	llm      model.ToolCallingChatModel
-	username string // 用于获取用户的文档
+	llm       model.ToolCallingChatModel
+	modelName string
 }
 
-func NewAliRAGModel(ctx context.Context, username string) (*AliRAGModel, error) {
+func NewAliRAGModel(ctx context.Context) (*AliRAGModel, error) {
 	key := os.Getenv("OPENAI_API_KEY")
 	conf := config.GetConfig()
 	modelName := conf.RagModelConfig.RagChatModelName
@@ -158,57 +202,11 @@ func NewAliRAGModel(ctx context.Context, username string) (*AliRAGModel, error)
 	if err != nil {
 		return nil, fmt.Errorf("create ali rag model failed: %v", err)
 	}
-	return &AliRAGModel{
-		llm:      llm,
-		username: username,
-	}, nil
+	return &AliRAGModel{llm: llm, modelName: modelName}, nil
 }
 
 func (o *AliRAGModel) GenerateResponse(ctx context.Context, messages []*schema.Message) (*schema.Message, error) {
-	// 1. 创建 RAG 查询器
-	ragQuery, err := rag.NewRAGQuery(ctx, o.username)
-	if err != nil {
-		log.Printf("Failed to create RAG query (user may not have uploaded file): %v", err)
-		// 如果用户没有上传文件，直接使用原始问题
-		resp, err := o.llm.Generate(ctx, messages)
-		if err != nil {
-			return nil, fmt.Errorf("ali rag generate failed: %v", err)
-		}
-		return resp, nil
-	}
-
-	// 2. 获取用户最后一条消息作为查询
-	if len(messages) == 0 {
-		return nil, fmt.Errorf("no messages provided")
-	}
-	lastMessage := messages[len(messages)-1]
-	query := lastMessage.Content
-
-	// 3. 检索相关文档
-	docs, err := ragQuery.RetrieveDocuments(ctx, query)
-	if err != nil {
-		log.Printf("Failed to retrieve documents: %v", err)
-		// 检索失败，使用原始问题
-		resp, err := o.llm.Generate(ctx, messages)
-		if err != nil {
-			return nil, fmt.Errorf("ali rag generate failed: %v", err)
-		}
-		return resp, nil
-	}
-
-	// 4. 构建包含检索结果的提示词
-	ragPrompt := rag.BuildRAGPrompt(query, docs)
-
-	// 5. 替换最后一条消息为 RAG 提示词
-	ragMessages := make([]*schema.Message, len(messages))
-	copy(ragMessages, messages)
-	ragMessages[len(ragMessages)-1] = &schema.Message{
-		Role:    schema.User,
-		Content: ragPrompt,
-	}
-
-	// 6. 调用 LLM 生成回答
-	resp, err := o.llm.Generate(ctx, ragMessages)
+	resp, err := o.llm.Generate(ctx, messages)
 	if err != nil {
 		return nil, fmt.Errorf("ali rag generate failed: %v", err)
 	}
@@ -216,68 +214,6 @@ func (o *AliRAGModel) GenerateResponse(ctx context.Context, messages []*schema.M
 }
 
 func (o *AliRAGModel) StreamResponse(ctx context.Context, messages []*schema.Message, cb StreamCallback) (string, error) {
-	// 1. 创建 RAG 查询器
-	ragQuery, err := rag.NewRAGQuery(ctx, o.username)
-	if err != nil {
-		log.Printf("Failed to create RAG query (user may not have uploaded file): %v", err)
-		// 如果用户没有上传文件，直接使用原始问题
-		return o.streamWithoutRAG(ctx, messages, cb)
-	}
-
-	// 2. 获取用户最后一条消息作为查询
-	if len(messages) == 0 {
-		return "", fmt.Errorf("no messages provided")
-	}
-	lastMessage := messages[len(messages)-1]
-	query := lastMessage.Content
-
-	// 3. 检索相关文档
-	docs, err := ragQuery.RetrieveDocuments(ctx, query)
-	if err != nil {
-		log.Printf("Failed to retrieve documents: %v", err)
-		// 检索失败，使用原始问题
-		return o.streamWithoutRAG(ctx, messages, cb)
-	}
-
-	// 4. 构建包含检索结果的提示词
-	ragPrompt := rag.BuildRAGPrompt(query, docs)
-
-	// 5. 替换最后一条消息为 RAG 提示词
-	ragMessages := make([]*schema.Message, len(messages))
-	copy(ragMessages, messages)
-	ragMessages[len(ragMessages)-1] = &schema.Message{
-		Role:    schema.User,
-		Content: ragPrompt,
-	}
-
-	// 6. 流式调用 LLM
-	stream, err := o.llm.Stream(ctx, ragMessages)
-	if err != nil {
-		return "", fmt.Errorf("ali rag stream failed: %v", err)
-	}
-	defer stream.Close()
-
-	var fullResp strings.Builder
-
-	for {
-		msg, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", fmt.Errorf("ali rag stream recv failed: %v", err)
-		}
-		if len(msg.Content) > 0 {
-			fullResp.WriteString(msg.Content)
-			cb(msg.Content)
-		}
-	}
-
-	return fullResp.String(), nil
-}
-
-// streamWithoutRAG 当没有 RAG 文档时的流式响应
-func (o *AliRAGModel) streamWithoutRAG(ctx context.Context, messages []*schema.Message, cb StreamCallback) (string, error) {
 	stream, err := o.llm.Stream(ctx, messages)
 	if err != nil {
 		return "", fmt.Errorf("ali rag stream failed: %v", err)
@@ -305,6 +241,8 @@ func (o *AliRAGModel) streamWithoutRAG(ctx context.Context, messages []*schema.M
 
 func (o *AliRAGModel) GetModelType() string { return "2" }
 
+func (o *AliRAGModel) GetModelName() string { return o.modelName }
+
 // =================== MCP 实现 ===================
 
 // MCPModel MCP模型实现，集成MCP服务
@@ -313,6 +251,7 @@ type MCPModel struct {
 	mcpClient  *client.Client
 	username   string
 	mcpBaseURL string
+	modelName  string
 }
 
 // NewMCPModel 创建MCP模型实例
@@ -338,6 +277,7 @@ func NewMCPModel(ctx context.Context, username string) (*MCPModel, error) {
 		llm:        llm,
 		mcpBaseURL: mcpBaseURL,
 		username:   username,
+		modelName:  modelName,
 	}, nil
 }
 
@@ -535,40 +475,21 @@ type AIToolCall struct {
 	Args       map[string]interface{} `json:"args"`
 }
 
-// buildFirstPrompt 构建第一次调用的提示词
+// buildFirstPrompt 构建第一次调用的提示词（模板库 mcp_tool_select）
 func (m *MCPModel) buildFirstPrompt(query string) string {
-	return fmt.Sprintf(`你是一个智能助手，可以调用MCP工具来获取信息。
-
-可用工具:
-- get_weather: 获取指定城市的天气信息，参数: city（城市名称，支持中文和英文，如北京、Shanghai等）
-
-重要规则:
-1. 如果需要调用工具，必须严格返回以下JSON格式：
-{
-  "isToolCall": true,
-  "toolName": "工具名称",
-  "args": {"参数名": "参数值"}
-}
-2. 如果不需要调用工具，直接返回自然语言回答
-3. 请根据用户问题决定是否需要调用工具
-
-用户问题: %s
-
-请根据需要调用适当的工具，然后给出综合的回答。`, query)
+	return prompt.Render(prompt.MCPToolSelect, prompt.DefaultTarget(m.modelName), map[string]any{
+		"Query": query,
+	})
 }
 
-// buildSecondPrompt 构建第二次调用的提示词
+// buildSecondPrompt 构建第二次调用的提示词（模板库 mcp_tool_answer）
 func (m *MCPModel) buildSecondPrompt(query, toolName string, args map[string]interface{}, toolResult string) string {
-	return fmt.Sprintf(`你是一个智能助手，可以调用MCP工具来获取信息。
-
-工具执行结果:
-工具名称: %s
-工具参数: %v
-工具结果: %s
-
-用户问题: %s
-
-请根据工具结果和用户问题，给出最终的综合回答。`, toolName, args, toolResult, query)
+	return prompt.Render(prompt.MCPToolAnswer, prompt.DefaultTarget(m.modelName), map[string]any{
+		"Query":      query,
+		"ToolName":   toolName,
+		"ToolArgs":   fmt.Sprintf("%v", args),
+		"ToolResult": toolResult,
+	})
 }
 
 // parseAIResponse 解析AI响应，检查是否包含工具调用
@@ -640,6 +561,8 @@ func (m *MCPModel) extractCityFromResponse(response string) string {
 // GetModelType 获取模型类型
 func (m *MCPModel) GetModelType() string { return "3" }
 
+func (m *MCPModel) GetModelName() string { return m.modelName }
+
 // Close 关闭MCP客户端
 func (m *MCPModel) Close() {
 	if m.mcpClient != nil {
@@ -0,0 +1,115 @@
+package aihelper
+
+import (
+	"GopherAI/common/rag"
+	"GopherAI/common/redact"
+	"context"
+	"log"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// SetKnowledgeBases 设置会话关联的知识库，为空表示不检索
+func (a *AIHelper) SetKnowledgeBases(ids []uint) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.knowledgeBaseIDs = append([]uint(nil), ids...)
+}
+
+// KnowledgeBases 会话检索时使用的知识库
+// RAG 模型（类型 "2"）的会话没有关联知识库时使用默认知识库（ID 为 0），与原来的行为保持一致
+func (a *AIHelper) KnowledgeBases() []uint {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if len(a.knowledgeBaseIDs) == 0 && a.model.GetModelType() == "2" {
+		return []uint{0}
+	}
+	return append([]uint(nil), a.knowledgeBaseIDs...)
+}
+
+// 知识库检索的结果，记录到日志用于统计
+const (
+	retrievalSkipped = "skipped"  // 会话没有关联知识库
+	retrievalHit     = "hit"      // 检索到相关文档
+	retrievalNoMatch = "no_match" // 没有距离在阈值内的文档
+	retrievalError   = "error"    // 检索失败（知识库为空、向量服务不可用等）
+)
+
+// 知识库中没有相关内容时返回给用户的内容
+const (
+	strictNoMatchReply    = "知识库中没有找到相关内容，无法回答这个问题。"
+	strictErrorReply      = "知识库检索失败，暂时无法基于知识库回答，请稍后重试。"
+	fallbackNoMatchNotice = "（未在知识库中找到相关内容，以下回答未参考知识库）\n\n"
+	fallbackErrorNotice   = "（知识库检索失败，以下回答未参考知识库）\n\n"
+)
+
+// noAnswer 检索没有可用结果时的处理（见 retrievalConfig.noAnswerMode）
+// 严格模式返回 refusal，直接作为回答而不调用模型；回退模式返回 notice，由调用方加在模型回答的前面
+func noAnswer(outcome string) (refusal string, notice string) {
+	if outcome != retrievalNoMatch && outcome != retrievalError {
+		return "", ""
+	}
+	if rag.NoAnswerMode() == rag.NoAnswerStrict {
+		if outcome == retrievalError {
+			return strictErrorReply, ""
+		}
+		return strictNoMatchReply, ""
+	}
+	if outcome == retrievalError {
+		return "", fallbackErrorNotice
+	}
+	return "", fallbackNoMatchNotice
+}
+
+// augmentMessages 在会话关联的知识库中检索相关文档，并将最后一条消息替换为 RAG 提示词
+// 没有关联知识库、检索失败或没有相关文档时返回原始消息，由调用方按 noAnswer 处理
+func (a *AIHelper) augmentMessages(ctx context.Context, userName string, messages []*schema.Message) ([]*schema.Message, []*schema.Document, string) {
+	knowledgeBaseIDs := a.KnowledgeBases()
+	if len(knowledgeBaseIDs) == 0 || len(messages) == 0 {
+		return messages, nil, retrievalSkipped
+	}
+
+	// 使用最后一条消息作为查询，请求中带有过滤条件时只在匹配的文档中检索
+	query := messages[len(messages)-1].Content
+	filter, err := rag.FilterFromContext(ctx)
+	if err != nil {
+		log.Printf("Invalid retrieval filter: %v", err)
+		a.logRetrieval(userName, knowledgeBaseIDs, retrievalError, 0)
+		return messages, nil, retrievalError
+	}
+	// 追问往往依赖上下文，结合之前的对话改写为独立查询后再检索，提示词中仍使用原始问题
+	// 跳过了脱敏的会话只有对话模型在本地，改写模型、向量模型和 API 重排都可能是外部服务：
+	// 这类会话不改写（对话历史没有脱敏），检索时使用脱敏后的问题，原始问题只出现在发给本地模型的提示词中
+	retrievalQuery := query
+	if a.skipsRedaction() {
+		ctx = rag.WithoutRewrite(ctx)
+		retrievalQuery = redact.GetGlobalRedactor().NewSession().Redact(query)
+	}
+	rewritten := rag.RewriteQuery(ctx, messages[:len(messages)-1], retrievalQuery)
+	docs, err := rag.RetrieveFromKnowledgeBases(ctx, userName, knowledgeBaseIDs, rewritten, filter)
+	if err != nil {
+		log.Printf("Failed to retrieve documents (user may not have uploaded file): %v", err)
+		a.logRetrieval(userName, knowledgeBaseIDs, retrievalError, 0)
+		return messages, nil, retrievalError
+	}
+	if len(docs) == 0 {
+		a.logRetrieval(userName, knowledgeBaseIDs, retrievalNoMatch, 0)
+		return messages, nil, retrievalNoMatch
+	}
+	a.logRetrieval(userName, knowledgeBaseIDs, retrievalHit, len(docs))
+
+	// 调用方传入的消息已经脱敏，检索到的文档内容同样可能包含敏感信息，替换后的提示词需要再脱敏一次
+	ragMessages := make([]*schema.Message, len(messages))
+	copy(ragMessages, messages)
+	ragMessages[len(ragMessages)-1] = &schema.Message{
+		Role:    schema.User,
+		Content: a.redactContent(rag.BuildRAGPrompt(query, docs, a.model.GetModelName())),
+	}
+	return ragMessages, docs, retrievalHit
+}
+
+// logRetrieval 以 key=value 的形式记录检索结果，便于日志系统统计命中率
+func (a *AIHelper) logRetrieval(userName string, knowledgeBaseIDs []uint, outcome string, docs int) {
+	log.Printf("[rag] retrieval outcome=%s mode=%s user=%s session=%s model=%s knowledge_bases=%v docs=%d",
+		outcome, rag.NoAnswerMode(), userName, a.SessionID, a.model.GetModelType(), knowledgeBaseIDs, docs)
+}
@@ -17,6 +17,8 @@ const (
 	CodeInvalidCaptcha   Code = 2008
 	CodeRecordNotFound   Code = 2009
 	CodeIllegalPassword  Code = 2010
+	CodeQuotaExceeded    Code = 2011
+	CodeFileExist        Code = 2012
 
 	CodeForbidden Code = 3001
 
@@ -42,6 +44,8 @@ var msg = map[Code]string{
 	CodeInvalidCaptcha:   "验证码错误",
 	CodeRecordNotFound:   "记录不存在",
 	CodeIllegalPassword:  "密码不合法",
+	CodeQuotaExceeded:    "存储空间不足",
+	CodeFileExist:        "文件已存在",
 
 	CodeForbidden: "权限不足",
 
@@ -0,0 +1,183 @@
+package embedder
+
+import (
+	"GopherAI/config"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	embeddingArk "github.com/cloudwego/eino-ext/components/embedding/ark"
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// 默认向量模型 ID（未配置 embeddingConfig 时使用 ragModelConfig 中的向量模型）
+const defaultID = "default"
+
+// Spec 描述一个向量模型，索引会记录构建时使用的 Spec.ID 和维度
+type Spec struct {
+	ID        string
+	Provider  string
+	Model     string
+	BaseURL   string
+	APIKeyEnv string
+	Dimension int
+	BatchSize int
+
+	// onnx 本地向量模型使用
+	ModelPath    string
+	VocabPath    string
+	MaxSeqLength int
+	Pooling      string
+	Concurrency  int
+}
+
+// Creator 根据 Spec 创建向量生成器
+type Creator func(ctx context.Context, spec *Spec) (embedding.Embedder, error)
+
+var creators = map[string]Creator{
+	"ark":    newArkEmbedder,
+	"openai": newOpenAIEmbedder,
+	"ollama": newOllamaEmbedder,
+	"onnx":   newOnnxEmbedder,
+}
+
+// Register 注册新的向量模型提供方
+func Register(provider string, creator Creator) {
+	creators[provider] = creator
+}
+
+// GetSpec 根据 ID 获取向量模型配置，ID 为空时返回默认向量模型
+func GetSpec(id string) (*Spec, error) {
+	conf := config.GetConfig()
+	if id == "" {
+		id = conf.EmbeddingDefault
+	}
+
+	providers := conf.EmbeddingProviders
+	if len(providers) == 0 {
+		// 兼容旧配置：只配置了 ragModelConfig
+		providers = []config.EmbeddingProvider{{
+			ID:        defaultID,
+			Provider:  "ark",
+			Model:     conf.RagEmbeddingModel,
+			BaseUrl:   conf.RagBaseUrl,
+			ApiKeyEnv: "OPENAI_API_KEY",
+			Dimension: conf.RagDimension,
+		}}
+		if id == "" {
+			id = defaultID
+		}
+	}
+	if id == "" {
+		id = providers[0].ID
+	}
+
+	for _, p := range providers {
+		if p.ID == id {
+			return &Spec{
+				ID:        p.ID,
+				Provider:  p.Provider,
+				Model:     p.Model,
+				BaseURL:   p.BaseUrl,
+				APIKeyEnv: p.ApiKeyEnv,
+				Dimension: p.Dimension,
+				BatchSize: p.BatchSize,
+
+				ModelPath:    p.ModelPath,
+				VocabPath:    p.VocabPath,
+				MaxSeqLength: p.MaxSeqLength,
+				Pooling:      p.Pooling,
+				Concurrency:  p.Concurrency,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("embedder %q not configured", id)
+}
+
+// APIKey 从环境变量中读取 API Key
+func (s *Spec) APIKey() string {
+	if s.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(s.APIKeyEnv)
+}
+
+// 已创建的向量生成器缓存，避免每次检索都重新创建
+var (
+	cache   = make(map[string]embedding.Embedder)
+	cacheMu sync.Mutex
+)
+
+// New 创建（或复用）指定的向量生成器
+func New(ctx context.Context, spec *Spec) (embedding.Embedder, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if e, ok := cache[spec.ID]; ok {
+		return e, nil
+	}
+
+	creator, ok := creators[spec.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported embedding provider: %s", spec.Provider)
+	}
+	e, err := creator(ctx, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedder %s: %w", spec.ID, err)
+	}
+	cache[spec.ID] = e
+	return e, nil
+}
+
+// 探测得到的向量维度，按 Spec.ID 缓存；Spec 在多个请求之间共享，不能直接写回 Spec
+var (
+	dimensions   = make(map[string]int)
+	dimensionsMu sync.Mutex
+)
+
+// dimensioner 可以直接给出向量维度的向量生成器（如 onnx 模型的输出形状），无需探测
+type dimensioner interface {
+	Dimension() int
+}
+
+// Dimension 获取向量维度，配置中未指定时通过一次向量化请求探测
+func Dimension(ctx context.Context, spec *Spec, e embedding.Embedder) (int, error) {
+	if spec.Dimension > 0 {
+		return spec.Dimension, nil
+	}
+	dimensionsMu.Lock()
+	dimension, ok := dimensions[spec.ID]
+	dimensionsMu.Unlock()
+	if ok {
+		return dimension, nil
+	}
+
+	if d, ok := e.(dimensioner); ok && d.Dimension() > 0 {
+		dimension = d.Dimension()
+	} else {
+		vectors, err := e.EmbedStrings(ctx, []string{"dimension probe"})
+		if err != nil {
+			return 0, fmt.Errorf("probe embedding dimension failed: %w", err)
+		}
+		if len(vectors) == 0 || len(vectors[0]) == 0 {
+			return 0, fmt.Errorf("probe embedding dimension failed: empty vector")
+		}
+		dimension = len(vectors[0])
+	}
+
+	dimensionsMu.Lock()
+	dimensions[spec.ID] = dimension
+	dimensionsMu.Unlock()
+	return dimension, nil
+}
+
+// =================== Ark 实现 ===================
+
+func newArkEmbedder(ctx context.Context, spec *Spec) (embedding.Embedder, error) {
+	return embeddingArk.NewEmbedder(ctx, &embeddingArk.EmbeddingConfig{
+		BaseURL: spec.BaseURL,
+		APIKey:  spec.APIKey(),
+		Model:   spec.Model,
+	})
+}
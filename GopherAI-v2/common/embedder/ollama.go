@@ -0,0 +1,97 @@
+package embedder
+
+import (
+	ollamaCli "GopherAI/common/ollama"
+	"GopherAI/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// =================== Ollama 实现 ===================
+
+type ollamaEmbedder struct {
+	spec    *Spec
+	baseURL string
+	client  *http.Client
+}
+
+type ollamaEmbedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive *float64 `json:"keep_alive,omitempty"` // 秒数，负数表示常驻内存
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+func newOllamaEmbedder(ctx context.Context, spec *Spec) (embedding.Embedder, error) {
+	baseURL := spec.BaseURL
+	if baseURL == "" {
+		baseURL = ollamaCli.GetBaseURL()
+	}
+	return &ollamaEmbedder{
+		spec:    spec,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (e *ollamaEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	return embedInBatches(texts, e.spec.BatchSize, func(batch []string) ([][]float64, error) {
+		return e.embedBatch(ctx, batch)
+	})
+}
+
+func (e *ollamaEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	payload := ollamaEmbedRequest{
+		Model: e.spec.Model,
+		Input: texts,
+	}
+	if keepAlive, err := ollamaCli.ParseKeepAlive(config.GetConfig().OllamaKeepAlive); err == nil && keepAlive != nil {
+		seconds := keepAlive.Seconds()
+		payload.KeepAlive = &seconds
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama embed request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ollamaEmbedResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("ollama embed returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("ollama embed failed: %s", result.Error)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama embed returned %d vectors, expected %d", len(result.Embeddings), len(texts))
+	}
+	return result.Embeddings, nil
+}
@@ -0,0 +1,273 @@
+package embedder
+
+import (
+	"GopherAI/common/onnx"
+	"GopherAI/common/tokenizer"
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+
+	"github.com/cloudwego/eino/components/embedding"
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// =================== ONNX 本地实现 ===================
+// 在 CPU 上运行 bge-small-zh 等句向量模型，完全不依赖外部 API
+// 流程：WordPiece 分词 -> onnx 推理得到每个 token 的向量 -> 池化 -> L2 归一化
+
+type onnxEmbedder struct {
+	spec         *Spec
+	session      *ort.DynamicAdvancedSession
+	tokenizer    *tokenizer.WordPiece
+	inputNames   []string
+	outputName   string
+	pooled       bool // 模型输出已经是句向量（二维），无需池化
+	dimension    int  // 模型输出形状中的向量维度，动态形状时为 0
+	maxSeqLength int
+	// 限制同时推理的请求数，避免并发请求占满内存
+	sem chan struct{}
+}
+
+func newOnnxEmbedder(ctx context.Context, spec *Spec) (embedding.Embedder, error) {
+	if spec.ModelPath == "" || spec.VocabPath == "" {
+		return nil, fmt.Errorf("onnx embedder requires modelPath and vocabPath")
+	}
+	if err := onnx.InitEnvironment(); err != nil {
+		return nil, err
+	}
+
+	tk, err := tokenizer.LoadWordPiece(spec.VocabPath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// 读取模型的输入输出，兼容是否包含 token_type_ids、输出是否已池化等不同导出方式
+	inputs, outputs, err := ort.GetInputOutputInfo(spec.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("read onnx model info failed: %w", err)
+	}
+	inputNames := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		switch in.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			inputNames = append(inputNames, in.Name)
+		default:
+			return nil, fmt.Errorf("unsupported onnx model input %s", in.Name)
+		}
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("onnx model has no output")
+	}
+	output := outputs[0]
+	for _, out := range outputs {
+		if out.Name == "sentence_embedding" || out.Name == "last_hidden_state" {
+			output = out
+			break
+		}
+	}
+	dimension := 0
+	if dims := output.Dimensions; len(dims) > 0 && dims[len(dims)-1] > 0 {
+		dimension = int(dims[len(dims)-1])
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(spec.ModelPath, inputNames, []string{output.Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create onnx session failed: %w", err)
+	}
+
+	maxSeqLength := spec.MaxSeqLength
+	if maxSeqLength <= 0 {
+		maxSeqLength = 512
+	}
+	concurrency := spec.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	return &onnxEmbedder{
+		spec:         spec,
+		session:      session,
+		tokenizer:    tk,
+		inputNames:   inputNames,
+		outputName:   output.Name,
+		pooled:       len(output.Dimensions) == 2,
+		dimension:    dimension,
+		maxSeqLength: maxSeqLength,
+		sem:          make(chan struct{}, concurrency),
+	}, nil
+}
+
+// Dimension 模型输出的向量维度，供 embedder.Dimension 使用，避免一次探测推理
+func (e *onnxEmbedder) Dimension() int {
+	return e.dimension
+}
+
+func (e *onnxEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	batchSize := e.spec.BatchSize
+	if batchSize <= 0 {
+		batchSize = 16
+	}
+	return embedInBatches(texts, batchSize, func(batch []string) ([][]float64, error) {
+		select {
+		case e.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-e.sem }()
+		return e.embedBatch(batch)
+	})
+}
+
+// embedBatch 对一批文本做推理
+// 每次调用都创建独立的输入输出 Tensor，onnxruntime 的 Session 本身支持并发 Run
+func (e *onnxEmbedder) embedBatch(texts []string) ([][]float64, error) {
+	encodings := make([]tokenizer.Encoding, len(texts))
+	seqLen := 0
+	for i, text := range texts {
+		encodings[i] = e.tokenizer.Encode(text, e.maxSeqLength)
+		if n := len(encodings[i].InputIDs); n > seqLen {
+			seqLen = n
+		}
+	}
+
+	// 按本批最长序列补齐
+	batch := len(texts)
+	inputIDs := make([]int64, batch*seqLen)
+	attentionMask := make([]int64, batch*seqLen)
+	typeIDs := make([]int64, batch*seqLen)
+	for i, enc := range encodings {
+		for j := 0; j < seqLen; j++ {
+			idx := i*seqLen + j
+			if j < len(enc.InputIDs) {
+				inputIDs[idx] = enc.InputIDs[j]
+				attentionMask[idx] = enc.AttentionMask[j]
+				typeIDs[idx] = enc.TypeIDs[j]
+			} else {
+				inputIDs[idx] = e.tokenizer.PadID()
+			}
+		}
+	}
+
+	shape := ort.NewShape(int64(batch), int64(seqLen))
+	inputs := make([]ort.Value, 0, len(e.inputNames))
+	defer func() {
+		for _, v := range inputs {
+			v.Destroy()
+		}
+	}()
+	for _, name := range e.inputNames {
+		var data []int64
+		switch name {
+		case "input_ids":
+			data = inputIDs
+		case "attention_mask":
+			data = attentionMask
+		case "token_type_ids":
+			data = typeIDs
+		}
+		tensor, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, fmt.Errorf("create input tensor failed: %w", err)
+		}
+		inputs = append(inputs, tensor)
+	}
+
+	// 输出由 onnxruntime 按实际形状分配
+	outputs := []ort.Value{nil}
+	if err := e.session.Run(inputs, outputs); err != nil {
+		return nil, fmt.Errorf("onnx run error: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	out, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("unexpected onnx output type")
+	}
+	data := out.GetData()
+	outShape := out.GetShape()
+	// 句向量输出为 [batch, hidden]，token 向量输出为 [batch, seqLen, hidden]
+	rank, expected := 3, []int64{int64(batch), int64(seqLen)}
+	if e.pooled {
+		rank, expected = 2, []int64{int64(batch)}
+	}
+	if len(outShape) != rank || outShape[rank-1] <= 0 {
+		return nil, fmt.Errorf("unexpected onnx output shape %v", outShape)
+	}
+	for i, d := range expected {
+		if outShape[i] != d {
+			return nil, fmt.Errorf("unexpected onnx output shape %v for batch %d, sequence length %d", outShape, batch, seqLen)
+		}
+	}
+	hidden := int(outShape[rank-1])
+	if len(data) != int(outShape.FlattenedSize()) {
+		return nil, fmt.Errorf("unexpected onnx output size %d for shape %v", len(data), outShape)
+	}
+
+	vectors := make([][]float64, batch)
+	for i := range vectors {
+		var vec []float64
+		if e.pooled {
+			vec = toFloat64(data[i*hidden : (i+1)*hidden])
+		} else {
+			tokens := data[i*seqLen*hidden : (i+1)*seqLen*hidden]
+			vec = e.pool(tokens, attentionMask[i*seqLen:(i+1)*seqLen], hidden)
+		}
+		vectors[i] = normalize(vec)
+	}
+	return vectors, nil
+}
+
+// pool 将 token 向量池化为句向量
+func (e *onnxEmbedder) pool(tokens []float32, mask []int64, hidden int) []float64 {
+	vec := make([]float64, hidden)
+	if e.spec.Pooling == "cls" {
+		for k := 0; k < hidden; k++ {
+			vec[k] = float64(tokens[k])
+		}
+		return vec
+	}
+
+	// mean pooling：只对有效 token（attention_mask 为 1）求平均
+	count := 0.0
+	for j, m := range mask {
+		if m == 0 {
+			continue
+		}
+		count++
+		row := tokens[j*hidden : (j+1)*hidden]
+		for k, v := range row {
+			vec[k] += float64(v)
+		}
+	}
+	if count > 0 {
+		for k := range vec {
+			vec[k] /= count
+		}
+	}
+	return vec
+}
+
+// normalize L2 归一化，使余弦相似度等价于点积
+func normalize(vec []float64) []float64 {
+	var sum float64
+	for _, v := range vec {
+		sum += v * v
+	}
+	norm := math.Sqrt(sum)
+	if norm == 0 {
+		return vec
+	}
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
+
+func toFloat64(data []float32) []float64 {
+	out := make([]float64, len(data))
+	for i, v := range data {
+		out[i] = float64(v)
+	}
+	return out
+}
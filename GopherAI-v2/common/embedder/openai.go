@@ -0,0 +1,135 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// =================== OpenAI 兼容实现 ===================
+// 适用于 OpenAI 以及提供 /embeddings 接口的兼容服务（DashScope compatible-mode、vLLM、Xinference 等）
+
+type openAIEmbedder struct {
+	spec   *Spec
+	client *http.Client
+}
+
+type openAIEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func newOpenAIEmbedder(ctx context.Context, spec *Spec) (embedding.Embedder, error) {
+	if spec.BaseURL == "" {
+		return nil, fmt.Errorf("openai embedder requires baseUrl")
+	}
+	// 保存一份副本，调用方之后修改 Spec 不影响请求参数；
+	// Dimension 只在配置中指定时发送，不支持 dimensions 参数的服务使用模型默认维度
+	copied := *spec
+	return &openAIEmbedder{
+		spec:   &copied,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (e *openAIEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	return embedInBatches(texts, e.spec.BatchSize, func(batch []string) ([][]float64, error) {
+		return e.embedBatch(ctx, batch)
+	})
+}
+
+func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	body, err := json.Marshal(openAIEmbeddingRequest{
+		Model:          e.spec.Model,
+		Input:          texts,
+		Dimensions:     e.spec.Dimension,
+		EncodingFormat: "float",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(e.spec.BaseURL, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := e.spec.APIKey(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openai embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result openAIEmbeddingResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("openai embedding returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("openai embedding failed: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai embedding returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("openai embedding returned %d vectors, expected %d", len(result.Data), len(texts))
+	}
+
+	// 按 index 还原输入顺序
+	vectors := make([][]float64, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("openai embedding returned invalid index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// embedInBatches 按批次调用向量化接口，batchSize <= 0 时一次性处理
+func embedInBatches(texts []string, batchSize int, embed func(batch []string) ([][]float64, error)) ([][]float64, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := embed(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
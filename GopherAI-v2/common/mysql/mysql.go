@@ -64,6 +64,11 @@ func migration() error {
 		new(model.User),
 		new(model.Session),
 		new(model.Message),
+		new(model.PromptTemplate),
+		new(model.KnowledgeBase),
+		new(model.Document),
+		new(model.IngestJob),
+		new(model.VectorIndex),
 	)
 }
 
@@ -0,0 +1,98 @@
+package ollama
+
+import (
+	"GopherAI/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ------------------ 已安装模型列表 ------------------
+
+type ModelDetails struct {
+	Format            string `json:"format"`
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+type Model struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+type tagsResponse struct {
+	Models []Model `json:"models"`
+}
+
+// GetBaseURL 获取 Ollama 服务地址，未配置时使用 Ollama 默认地址
+func GetBaseURL() string {
+	baseURL := config.GetConfig().OllamaBaseUrl
+	if baseURL == "" {
+		baseURL = "http://127.0.0.1:11434"
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+// ListModels 通过 Ollama 的 tags 接口获取本机已安装的模型
+func ListModels(ctx context.Context) ([]Model, error) {
+	url := GetBaseURL() + "/api/tags"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request ollama tags failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama tags returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result tagsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return result.Models, nil
+}
+
+// ParseKeepAlive 解析 keep_alive 配置
+// 支持 Go 的时长格式（如 "10m"），以及 Ollama 的纯数字秒数写法（如 "-1" 表示常驻内存）
+func ParseKeepAlive(s string) (*time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return &d, nil
+	}
+	seconds, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keepAlive %q", s)
+	}
+	d := time.Duration(seconds) * time.Second
+	return &d, nil
+}
+
+// GetDefaultModelName 获取配置中的默认模型
+func GetDefaultModelName() string {
+	return config.GetConfig().OllamaModelName
+}
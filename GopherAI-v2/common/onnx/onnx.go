@@ -0,0 +1,29 @@
+package onnx
+
+import (
+	"GopherAI/config"
+	"fmt"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+var (
+	initOnce sync.Once
+	initErr  error
+)
+
+// InitEnvironment 初始化 ONNX Runtime 环境（全局一次）
+// 图像识别、本地向量模型、本地重排模型共用同一个环境
+func InitEnvironment() error {
+	initOnce.Do(func() {
+		if path := config.GetConfig().OnnxLibraryPath; path != "" {
+			ort.SetSharedLibraryPath(path)
+		}
+		initErr = ort.InitializeEnvironment()
+	})
+	if initErr != nil {
+		return fmt.Errorf("onnxruntime initialize error: %w", initErr)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package prompt
+
+// 内置模板，数据库和模板目录中都没有对应模板时使用
+// 变量说明见 Variables
+
+const (
+	RAGAnswer     = "rag_answer"        // RAG 问答
+	RAGStrict     = "rag_answer_strict" // RAG 问答（严格模式）：只能根据参考文档回答
+	QueryRewrite  = "query_rewrite"     // 检索前结合对话历史把追问改写为独立查询
+	QueryExpand   = "query_expand"      // 检索前生成问题的其他表述（多查询扩展）
+	HyDE          = "hyde"              // 检索前生成假设性回答，用其向量检索
+	MCPToolSelect = "mcp_tool_select"   // MCP 第一次调用：判断是否需要调用工具
+	MCPToolAnswer = "mcp_tool_answer"   // MCP 第二次调用：根据工具结果回答
+)
+
+const defaultLocale = "zh-CN"
+
+var builtinTemplates = map[string]string{
+	RAGAnswer: `基于以下参考文档回答用户的问题。如果文档中没有相关信息，请说明无法找到相关信息。
+
+参考文档：
+{{range .Documents}}[文档 {{.Index}}]: {{.Content}}
+
+{{end}}
+用户问题：{{.Query}}
+
+请提供准确、完整的回答。回答中用到某篇文档的内容时，在对应句子末尾用 [n] 标注来源编号（如 [1] 或 [1,3]），只能使用上面列出的编号：`,
+
+	RAGStrict: `你只能根据下面的参考文档回答用户的问题，不要使用参考文档以外的知识。
+如果参考文档中没有能够回答问题的信息，只回答“知识库中没有找到相关内容，无法回答这个问题。”，不要猜测。
+
+参考文档：
+{{range .Documents}}[文档 {{.Index}}]: {{.Content}}
+
+{{end}}
+用户问题：{{.Query}}
+
+回答中用到某篇文档的内容时，在对应句子末尾用 [n] 标注来源编号（如 [1] 或 [1,3]），只能使用上面列出的编号：`,
+
+	QueryRewrite: `根据对话历史，把用户的最新问题改写为一个不依赖上下文、可以直接用于检索知识库的独立问题。
+把问题中的代词和省略（如“它”“第二个”“那个呢”）替换为对话历史中对应的具体内容，保留专有名词、错误码和标识符原样。
+如果最新问题本身已经完整，原样输出。只输出改写后的问题，不要回答问题，不要添加任何解释。
+
+对话历史：
+{{.History}}
+最新问题：{{.Query}}`,
+
+	QueryExpand: `为了在知识库中检索到更多相关内容，请把下面的问题换 {{.Count}} 种不同的表述方式，可以使用同义词、不同的角度或更具体的说法。
+每行输出一种表述，不要编号，不要输出其他内容。
+
+问题：{{.Query}}`,
+
+	HyDE: `请写一段可能出现在技术文档中的文字来回答下面的问题，长度在 100 字左右。
+不确定的细节可以合理假设，只输出这段文字本身。
+
+问题：{{.Query}}`,
+
+	MCPToolSelect: `你是一个智能助手，可以调用MCP工具来获取信息。
+
+可用工具:
+- get_weather: 获取指定城市的天气信息，参数: city（城市名称，支持中文和英文，如北京、Shanghai等）
+
+重要规则:
+1. 如果需要调用工具，必须严格返回以下JSON格式：
+{
+  "isToolCall": true,
+  "toolName": "工具名称",
+  "args": {"参数名": "参数值"}
+}
+2. 如果不需要调用工具，直接返回自然语言回答
+3. 请根据用户问题决定是否需要调用工具
+
+用户问题: {{.Query}}
+
+请根据需要调用适当的工具，然后给出综合的回答。`,
+
+	MCPToolAnswer: `你是一个智能助手，可以调用MCP工具来获取信息。
+
+工具执行结果:
+工具名称: {{.ToolName}}
+工具参数: {{.ToolArgs}}
+工具结果: {{.ToolResult}}
+
+用户问题: {{.Query}}
+
+请根据工具结果和用户问题，给出最终的综合回答。`,
+}
+
+// Variables 各模板可用的变量，供管理端展示和预览时参考
+var Variables = map[string]map[string]string{
+	RAGAnswer: {
+		"Query":     "用户问题",
+		"Documents": "检索到的文档列表，每项包含 Index（从 1 开始的编号）和 Content",
+		"Context":   "已拼接好的文档内容（[文档 N]: 内容）",
+	},
+	RAGStrict: {
+		"Query":     "用户问题",
+		"Documents": "检索到的文档列表，每项包含 Index（从 1 开始的编号）和 Content",
+		"Context":   "已拼接好的文档内容（[文档 N]: 内容）",
+	},
+	QueryRewrite: {
+		"Query":   "用户的最新问题",
+		"History": "最近的对话历史，每条一行（用户：… / 助手：…）",
+	},
+	QueryExpand: {
+		"Query": "改写后的独立问题",
+		"Count": "需要生成的表述数量",
+	},
+	HyDE: {
+		"Query": "改写后的独立问题",
+	},
+	MCPToolSelect: {
+		"Query": "用户问题",
+	},
+	MCPToolAnswer: {
+		"Query":      "用户问题",
+		"ToolName":   "工具名称",
+		"ToolArgs":   "工具参数",
+		"ToolResult": "工具返回结果",
+	},
+}
@@ -0,0 +1,259 @@
+package prompt
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/config"
+	promptDao "GopherAI/dao/prompt"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Target 渲染目标：语言和模型，用于选择对应的模板变体
+type Target struct {
+	Locale string
+	Model  string
+}
+
+type key struct {
+	name   string
+	locale string
+	model  string
+}
+
+type entry struct {
+	source  string // db | file | builtin
+	version int
+	tmpl    *template.Template
+}
+
+// Registry 模板注册表
+// 查找顺序：先找 语言+模型 的专属模板，再找该语言的通用模板，最后回退到默认语言；
+// 同一级别下按 数据库 -> 模板目录 -> 内置模板 的优先级选择
+type Registry struct {
+	mu       sync.RWMutex
+	db       map[key]*entry
+	files    map[key]*entry
+	builtin  map[key]*entry
+	loadedAt time.Time
+
+	reloading atomic.Bool // 缓存过期时只允许一个调用方重新加载，其他调用方继续使用旧的模板
+}
+
+var (
+	globalRegistry *Registry
+	registryOnce   sync.Once
+)
+
+// GetRegistry 获取全局模板注册表
+func GetRegistry() *Registry {
+	registryOnce.Do(func() {
+		globalRegistry = &Registry{
+			db:      make(map[key]*entry),
+			files:   make(map[key]*entry),
+			builtin: make(map[key]*entry),
+		}
+		for name, content := range builtinTemplates {
+			globalRegistry.builtin[key{name: name, locale: defaultLocale}] = &entry{
+				source: "builtin",
+				tmpl:   template.Must(Parse(name, content)),
+			}
+		}
+		if err := globalRegistry.Reload(); err != nil {
+			log.Printf("[prompt] load templates failed: %v", err)
+		}
+	})
+	return globalRegistry
+}
+
+// Parse 解析模板，缺少变量时直接报错，避免渲染出 "<no value>"
+func Parse(name, content string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(content)
+}
+
+// Reload 重新加载模板目录和数据库中的模板
+func (r *Registry) Reload() error {
+	files, fileErr := loadFromDir(config.GetConfig().PromptDir)
+	db, dbErr := loadFromDB()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fileErr == nil {
+		r.files = files
+	}
+	if dbErr == nil {
+		r.db = db
+	}
+	r.loadedAt = time.Now()
+
+	if fileErr != nil {
+		return fileErr
+	}
+	return dbErr
+}
+
+// Invalidate 模板发生变更后调用，下次渲染时重新加载
+func (r *Registry) Invalidate() {
+	r.mu.Lock()
+	r.loadedAt = time.Time{}
+	r.mu.Unlock()
+}
+
+// Render 渲染指定模板
+func (r *Registry) Render(name string, target Target, vars any) (string, error) {
+	r.reloadIfExpired()
+
+	e := r.lookup(name, target)
+	if e == nil {
+		return "", fmt.Errorf("prompt template %s not found", name)
+	}
+	var buf bytes.Buffer
+	if err := e.tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render prompt template %s (%s v%d) failed: %w", name, e.source, e.version, err)
+	}
+	return buf.String(), nil
+}
+
+// Render 渲染模板，出错时回退到内置模板，保证对话不受模板配置错误影响
+func Render(name string, target Target, vars any) string {
+	r := GetRegistry()
+	text, err := r.Render(name, target, vars)
+	if err == nil {
+		return text
+	}
+	log.Printf("[prompt] %v, fallback to builtin", err)
+
+	e := r.builtin[key{name: name, locale: defaultLocale}]
+	if e == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := e.tmpl.Execute(&buf, vars); err != nil {
+		log.Printf("[prompt] render builtin %s failed: %v", name, err)
+	}
+	return buf.String()
+}
+
+// Preview 使用示例变量渲染一段模板内容（不保存）
+func Preview(name, content string, vars map[string]any) (string, error) {
+	tmpl, err := Parse(name, content)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DefaultTarget 根据配置获取默认渲染目标
+func DefaultTarget(modelName string) Target {
+	locale := config.GetConfig().PromptDefaultLocale
+	if locale == "" {
+		locale = defaultLocale
+	}
+	return Target{Locale: locale, Model: modelName}
+}
+
+func (r *Registry) reloadIfExpired() {
+	ttl := time.Duration(config.GetConfig().PromptCacheSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	r.mu.RLock()
+	expired := time.Since(r.loadedAt) > ttl
+	r.mu.RUnlock()
+	if !expired || !r.reloading.CompareAndSwap(false, true) {
+		return
+	}
+	defer r.reloading.Store(false)
+	if err := r.Reload(); err != nil {
+		log.Printf("[prompt] reload templates failed: %v", err)
+	}
+}
+
+func (r *Registry) lookup(name string, target Target) *entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	candidates := []key{
+		{name: name, locale: target.Locale, model: target.Model},
+		{name: name, locale: target.Locale},
+		{name: name, locale: defaultLocale, model: target.Model},
+		{name: name, locale: defaultLocale},
+	}
+	for _, k := range candidates {
+		for _, layer := range []map[key]*entry{r.db, r.files, r.builtin} {
+			if e, ok := layer[k]; ok {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// loadFromDir 从模板目录加载模板
+// 目录结构：<dir>/<模板名>/<语言>.tmpl，模型专属模板为 <dir>/<模板名>/<语言>@<模型名>.tmpl
+func loadFromDir(dir string) (map[key]*entry, error) {
+	result := make(map[key]*entry)
+	if dir == "" {
+		return result, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		name := filepath.Base(filepath.Dir(path))
+		locale := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+		modelName := ""
+		if i := strings.Index(locale, "@"); i >= 0 {
+			locale, modelName = locale[:i], locale[i+1:]
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read prompt template %s failed: %w", path, err)
+		}
+		tmpl, err := Parse(name, string(content))
+		if err != nil {
+			log.Printf("[prompt] skip invalid template %s: %v", path, err)
+			continue
+		}
+		result[key{name: name, locale: locale, model: modelName}] = &entry{source: "file", tmpl: tmpl}
+	}
+	return result, nil
+}
+
+// loadFromDB 从数据库加载生效中的模板
+func loadFromDB() (map[key]*entry, error) {
+	result := make(map[key]*entry)
+	if mysql.DB == nil {
+		return result, nil
+	}
+	templates, err := promptDao.GetActivePromptTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		tmpl, err := Parse(t.Name, t.Content)
+		if err != nil {
+			log.Printf("[prompt] skip invalid template %s v%d: %v", t.Name, t.Version, err)
+			continue
+		}
+		result[key{name: t.Name, locale: t.Locale, model: t.ModelName}] = &entry{
+			source:  "db",
+			version: t.Version,
+			tmpl:    tmpl,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package rabbitmq
+
+import (
+	"GopherAI/common/rag"
+	"context"
+	"encoding/json"
+
+	"github.com/streadway/amqp"
+)
+
+type IngestMQParam struct {
+	JobID uint `json:"job_id"`
+}
+
+func GenerateIngestMQParam(jobID uint) []byte {
+	param := IngestMQParam{
+		JobID: jobID,
+	}
+	data, _ := json.Marshal(param)
+	return data
+}
+
+// MQIngest 消费文档入库任务，解析、切块、向量化都在这里完成，不占用上传请求
+func MQIngest(msg *amqp.Delivery) error {
+	var param IngestMQParam
+	err := json.Unmarshal(msg.Body, &param)
+	if err != nil {
+		return err
+	}
+	return rag.RunIngestJob(context.Background(), param.JobID)
+}
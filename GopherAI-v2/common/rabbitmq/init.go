@@ -3,6 +3,7 @@ package rabbitmq
 var (
 
 	RMQMessage *RabbitMQ
+	RMQIngest  *RabbitMQ
 )
 
 func InitRabbitMQ() {
@@ -13,9 +14,14 @@ func InitRabbitMQ() {
 	RMQMessage = NewWorkRabbitMQ("Message")
 	go RMQMessage.Consume(MQMessage)
 
+	// 文档入库（解析、切块、向量化）耗时较长，使用单独的队列，不影响消息落库
+	RMQIngest = NewWorkRabbitMQ("Ingest")
+	go RMQIngest.ConsumeWithAck(MQIngest)
+
 }
 
 // DestroyRabbitMQ 销毁RabbitMQ
 func DestroyRabbitMQ() {
 	RMQMessage.Destroy()
+	RMQIngest.Destroy()
 }
@@ -13,14 +13,17 @@ type MessageMQParam struct {
 	Content   string `json:"content"`
 	UserName  string `json:"user_name"`
 	IsUser    bool   `json:"is_user"`
+
+	Citations []model.Citation `json:"citations,omitempty"`
 }
 
-func GenerateMessageMQParam(sessionID string, content string, userName string, IsUser bool) []byte {
+func GenerateMessageMQParam(sessionID string, content string, userName string, IsUser bool, citations []model.Citation) []byte {
 	param := MessageMQParam{
 		SessionID: sessionID,
 		Content:   content,
 		UserName:  userName,
 		IsUser:    IsUser,
+		Citations: citations,
 	}
 	data, _ := json.Marshal(param)
 	return data
@@ -37,6 +40,7 @@ func MQMessage(msg *amqp.Delivery) error {
 		Content:   param.Content,
 		UserName:  param.UserName,
 		IsUser:    param.IsUser,
+		Citations: param.Citations,
 	}
 	//消费者异步插入到数据库中
 	message.CreateMessage(newMsg)
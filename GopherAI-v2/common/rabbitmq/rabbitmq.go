@@ -4,6 +4,7 @@ import (
 	"GopherAI/config"
 	"fmt"
 	"log"
+	"runtime/debug"
 
 	"github.com/streadway/amqp"
 )
@@ -87,6 +88,7 @@ func (r *RabbitMQ) Publish(message []byte) error {
 
 // Consume 消费者
 // handle: 消息的消费业务函数，用于消费消息
+// 消息收到后自动确认，适合处理很快、丢失也可以接受的消息（如消息落库）
 func (r *RabbitMQ) Consume(handle func(msg *amqp.Delivery) error) {
 	// 创建队列
 	q, err := r.channel.QueueDeclare(r.Key, false, false, false, false, nil)
@@ -102,8 +104,48 @@ func (r *RabbitMQ) Consume(handle func(msg *amqp.Delivery) error) {
 
 	// 处理消息
 	for msg := range msgs {
-		if err := handle(&msg); err != nil {
-			fmt.Println(err.Error())
+		if err := safeHandle(handle, &msg); err != nil {
+			log.Printf("[rabbitmq] %s: %v", r.Key, err)
 		}
 	}
 }
+
+// ConsumeWithAck 逐条处理的消费者，用于耗时较长的任务（如文档入库）
+// 每次只取一条未确认的消息，多实例部署时任务平均分配；处理完成后才确认消息，进程在处理过程中退出时消息会重新投递；
+// 处理失败的消息同样确认，失败状态由业务自己记录（如入库任务标记为失败后可以手动重试），避免反复投递
+func (r *RabbitMQ) ConsumeWithAck(handle func(msg *amqp.Delivery) error) {
+	// 创建队列
+	q, err := r.channel.QueueDeclare(r.Key, false, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+	if err := r.channel.Qos(1, 0, false); err != nil {
+		panic(err)
+	}
+
+	// 接收消息
+	msgs, err := r.channel.Consume(q.Name, "", false, false, false, false, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// 处理消息
+	for msg := range msgs {
+		if err := safeHandle(handle, &msg); err != nil {
+			log.Printf("[rabbitmq] %s: %v", r.Key, err)
+		}
+		if err := msg.Ack(false); err != nil {
+			log.Printf("[rabbitmq] %s: ack failed: %v", r.Key, err)
+		}
+	}
+}
+
+// safeHandle 处理消息时的 panic 转为错误，一条消息出错不会使消费者退出
+func safeHandle(handle func(msg *amqp.Delivery) error, msg *amqp.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handle(msg)
+}
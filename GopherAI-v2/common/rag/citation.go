@@ -0,0 +1,152 @@
+package rag
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/common/rag/loader"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// snippetLength 引用片段保留的最大字符数
+const snippetLength = 200
+
+// citationMarker 匹配回答中的引用标记，如 [1]、[1,2]、[文档 3]
+var citationMarker = regexp.MustCompile(`\[(?:文档\s*)?(\d+(?:\s*[,，、]\s*\d+)*)\]`)
+
+// BuildCitations 根据检索到的文档块构建引用列表，编号与 BuildRAGPrompt 中的 [文档 N] 一致
+func BuildCitations(docs []*schema.Document) []model.Citation {
+	if len(docs) == 0 {
+		return nil
+	}
+	names := documentNames(docs)
+
+	citations := make([]model.Citation, 0, len(docs))
+	for i, doc := range docs {
+		source, _ := doc.MetaData["source"].(string)
+		name := names[source]
+		if name == "" && source != "" {
+			name = filepath.Base(source)
+		}
+		page, _ := toFloat(doc.MetaData[loader.MetaPage])
+		section, _ := doc.MetaData[loader.MetaSection].(string)
+		if section == "" {
+			section, _ = doc.MetaData["heading_path"].(string)
+		}
+		citations = append(citations, model.Citation{
+			Index:        i + 1,
+			DocumentName: name,
+			ChunkID:      doc.ID,
+			Score:        documentScore(doc),
+			Snippet:      snippet(doc.Content),
+			Page:         int(page),
+			Section:      section,
+		})
+	}
+	return citations
+}
+
+// codeSpan 匹配回答中的代码块和行内代码，其中的 arr[0] 之类不是引用标记
+// 流式输出可能在代码块中途结束，没有闭合的代码块一直匹配到末尾
+var codeSpan = regexp.MustCompile("(?s)```.*?(?:```|$)|~~~.*?(?:~~~|$)|`[^`\n]*`")
+
+// ValidateCitations 校验回答中的 [n] 标记，代码块和行内代码中的内容不作为引用标记
+// 编号有效的引用标记为 Cited；超出范围的标记视为模型编造，只记录日志
+// 回答本身不做修改，保存的内容与推送给前端的内容保持一致，校验结果记录在引用列表中
+func ValidateCitations(answer string, citations []model.Citation) []model.Citation {
+	if len(citations) == 0 {
+		return citations
+	}
+
+	invalid := make([]int, 0)
+	text := codeSpan.ReplaceAllString(answer, " ")
+	for _, match := range citationMarker.FindAllStringSubmatch(text, -1) {
+		for _, part := range strings.FieldsFunc(match[1], isListSeparator) {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 1 || n > len(citations) {
+				invalid = append(invalid, n)
+				continue
+			}
+			citations[n-1].Cited = true
+		}
+	}
+
+	if len(invalid) > 0 {
+		log.Printf("[rag] answer cites unknown documents %v (only %d retrieved)", invalid, len(citations))
+	}
+	return citations
+}
+
+func isListSeparator(r rune) bool {
+	return r == ',' || r == '，' || r == '、'
+}
+
+// documentNames 根据块元数据中的文件路径查询上传时的原始文件名
+func documentNames(docs []*schema.Document) map[string]string {
+	names := make(map[string]string)
+	if mysql.DB == nil {
+		return names
+	}
+	paths := make([]string, 0, len(docs))
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		source, _ := doc.MetaData["source"].(string)
+		if source != "" && !seen[source] {
+			seen[source] = true
+			paths = append(paths, source)
+		}
+	}
+	documents, err := knowledgeDao.GetDocumentsByFilePaths(paths)
+	if err != nil {
+		log.Printf("[rag] query document names failed: %v", err)
+		return names
+	}
+	for _, d := range documents {
+		names[d.FilePath] = d.FileName
+	}
+	return names
+}
+
+// documentScore 优先使用重排分数，其次是融合分数，纯向量检索时由距离换算为相似度
+func documentScore(doc *schema.Document) float64 {
+	if v, ok := toFloat(doc.MetaData["rerank_score"]); ok {
+		return v
+	}
+	if v, ok := toFloat(doc.MetaData["score"]); ok {
+		return v
+	}
+	if v, ok := toFloat(doc.MetaData["distance"]); ok {
+		return 1 - v
+	}
+	return 0
+}
+
+func toFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// snippet 截取文档块开头的一段文字，合并多余空白
+func snippet(content string) string {
+	text := []rune(strings.Join(strings.Fields(content), " "))
+	if len(text) <= snippetLength {
+		return string(text)
+	}
+	return string(text[:snippetLength]) + "…"
+}
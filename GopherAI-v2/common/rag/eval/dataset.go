@@ -0,0 +1,183 @@
+// Package eval 离线评估 RAG 检索和回答质量
+// 读取标注好的问题集（YAML 或 JSONL），按给定配置运行与对话相同的检索流程，
+// 统计 recall@k、MRR、回答相似度和耗时，并对比两套配置（切块大小、混合检索与向量检索等）的结果
+package eval
+
+import (
+	"GopherAI/common/rag"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Dataset 评估问题集
+type Dataset struct {
+	// Documents 语料文件，相对路径相对于问题集所在目录
+	// 提供时为每套配置在内存中单独建索引（可以对比不同的切块参数），不提供时在已有知识库中检索
+	Documents []string   `yaml:"documents" json:"documents"`
+	Questions []Question `yaml:"questions" json:"questions"`
+}
+
+// Question 一个评估问题，Expected 和 Answer 至少提供一个
+type Question struct {
+	ID       string            `yaml:"id" json:"id"`
+	Question string            `yaml:"question" json:"question"`
+	History  []Message         `yaml:"history" json:"history"` // 之前的对话，用于评估追问的查询改写
+	Filter   string            `yaml:"filter" json:"filter"`   // 检索过滤条件，语法同对话接口
+	Expected []ExpectedSource  `yaml:"expected" json:"expected"`
+	Chunks   []string          `yaml:"expected_chunks" json:"expected_chunks"` // 期望命中的块 ID，等价于只填 chunk 的 Expected
+	Answer   string            `yaml:"answer" json:"answer"`                   // 参考答案
+	Extra    map[string]string `yaml:"extra" json:"extra"`                     // 备注等，原样写入结果
+}
+
+// Message 对话历史中的一条消息
+type Message struct {
+	Role    string `yaml:"role" json:"role"` // user | assistant
+	Content string `yaml:"content" json:"content"`
+}
+
+// ExpectedSource 期望检索到的来源
+// 块 ID 随切块参数变化，对比切块配置时应使用 document + contains 描述来源
+type ExpectedSource struct {
+	Chunk    string `yaml:"chunk" json:"chunk"`       // 块 ID，填写时忽略其他字段
+	Document string `yaml:"document" json:"document"` // 文件名，扩展名可省略
+	Contains string `yaml:"contains" json:"contains"` // 块内容中包含的文本，忽略空白差异
+}
+
+// LoadDataset 读取问题集，.jsonl 文件每行一个问题，其他文件按 YAML 解析（JSON 也是合法的 YAML）
+func LoadDataset(path string) (*Dataset, error) {
+	var ds Dataset
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		questions, err := loadJSONL(path)
+		if err != nil {
+			return nil, err
+		}
+		ds.Questions = questions
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &ds); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	for i, doc := range ds.Documents {
+		if !filepath.IsAbs(doc) {
+			ds.Documents[i] = filepath.Join(dir, doc)
+		}
+	}
+	if err := ds.validate(); err != nil {
+		return nil, fmt.Errorf("invalid dataset %s: %w", path, err)
+	}
+	return &ds, nil
+}
+
+func loadJSONL(path string) ([]Question, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var questions []Question
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var q Question
+		if err := json.Unmarshal([]byte(text), &q); err != nil {
+			return nil, fmt.Errorf("parse %s line %d: %w", path, line, err)
+		}
+		questions = append(questions, q)
+	}
+	return questions, scanner.Err()
+}
+
+func (ds *Dataset) validate() error {
+	if len(ds.Questions) == 0 {
+		return fmt.Errorf("no questions")
+	}
+	for i := range ds.Questions {
+		q := &ds.Questions[i]
+		if q.ID == "" {
+			q.ID = fmt.Sprintf("q%d", i+1)
+		}
+		if strings.TrimSpace(q.Question) == "" {
+			return fmt.Errorf("question %s is empty", q.ID)
+		}
+		for _, chunk := range q.Chunks {
+			q.Expected = append(q.Expected, ExpectedSource{Chunk: chunk})
+		}
+		q.Chunks = nil
+		for _, exp := range q.Expected {
+			if exp.Chunk == "" && exp.Document == "" && exp.Contains == "" {
+				return fmt.Errorf("question %s has an empty expected source", q.ID)
+			}
+		}
+		if len(q.Expected) == 0 && q.Answer == "" {
+			return fmt.Errorf("question %s has neither expected sources nor an answer", q.ID)
+		}
+	}
+	return nil
+}
+
+// messages 转换为模型使用的对话历史
+func (q *Question) messages() []*schema.Message {
+	messages := make([]*schema.Message, 0, len(q.History))
+	for _, msg := range q.History {
+		if strings.EqualFold(msg.Role, string(schema.Assistant)) {
+			messages = append(messages, schema.AssistantMessage(msg.Content, nil))
+		} else {
+			messages = append(messages, schema.UserMessage(msg.Content))
+		}
+	}
+	return messages
+}
+
+// Match 检索到的块是否命中期望的来源
+func (e ExpectedSource) Match(doc *schema.Document) bool {
+	if e.Chunk != "" {
+		return doc.ID == e.Chunk
+	}
+	if e.Document != "" && !matchDocument(doc, e.Document) {
+		return false
+	}
+	if e.Contains != "" && !strings.Contains(compactSpace(doc.Content), compactSpace(e.Contains)) {
+		return false
+	}
+	return true
+}
+
+// matchDocument 按来源文件名或文档标识匹配，不区分大小写，扩展名可省略
+func matchDocument(doc *schema.Document, name string) bool {
+	candidates := make([]string, 0, 3)
+	if source, ok := doc.MetaData["source"].(string); ok && source != "" {
+		candidates = append(candidates, filepath.Base(source), rag.DocumentKey(source))
+	}
+	if docID, ok := doc.MetaData["doc_id"].(string); ok && docID != "" {
+		candidates = append(candidates, docID)
+	}
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate, name) || strings.EqualFold(candidate, rag.DocumentKey(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compactSpace 去掉所有空白，切块时的换行、缩进差异不影响匹配
+func compactSpace(text string) string {
+	return strings.Join(strings.Fields(text), "")
+}
@@ -0,0 +1,269 @@
+package eval
+
+import (
+	"GopherAI/common/aihelper"
+	"GopherAI/common/embedder"
+	"GopherAI/common/rag"
+	"GopherAI/common/vectorstore"
+	"GopherAI/config"
+	"GopherAI/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Variant 一套待评估的配置
+// Path 为 TOML 覆盖文件，只需写出与 config.toml 不同的部分，例如：
+//
+//	[retrievalConfig]
+//	mode = "vector"
+//
+// Path 为空时使用当前配置。切块参数（chunkConfig）和向量模型（embeddingConfig.default）只在提供语料时生效
+type Variant struct {
+	Name string
+	Path string
+}
+
+// Options 评估选项
+type Options struct {
+	Username        string // 在已有知识库中检索时使用的用户
+	KnowledgeBaseID uint   // 在已有知识库中检索时使用的知识库，0 表示默认知识库
+	K               int    // recall@k 的 k，0 表示使用各配置的 retrievalConfig.topK
+	ModelType       string // 生成回答使用的模型类型（同对话接口的 modelType），为空时只评估检索
+}
+
+// Result 一套配置的评估结果
+type Result struct {
+	Variant   string            `json:"variant"`
+	Summary   Summary           `json:"summary"`
+	Questions []*QuestionResult `json:"questions"`
+}
+
+// Evaluator 在同一个问题集上依次评估多套配置
+// 提供语料时按切块参数和向量模型缓存临时索引，参数相同的配置共用索引
+type Evaluator struct {
+	dataset   *Dataset
+	opts      Options
+	documents []string
+	indexes   map[string]string // 切块参数和向量模型 -> 临时索引名
+}
+
+func NewEvaluator(dataset *Dataset, opts Options) (*Evaluator, error) {
+	documents, err := expandDocuments(dataset.Documents)
+	if err != nil {
+		return nil, err
+	}
+	return &Evaluator{
+		dataset:   dataset,
+		opts:      opts,
+		documents: documents,
+		indexes:   make(map[string]string),
+	}, nil
+}
+
+// UsesCorpus 是否在语料建立的临时索引中检索（否则在已有知识库中检索）
+func (e *Evaluator) UsesCorpus() bool {
+	return len(e.documents) > 0
+}
+
+// Run 使用一套配置评估整个问题集，评估期间修改全局配置，结束后恢复
+func (e *Evaluator) Run(ctx context.Context, v Variant) (*Result, error) {
+	restore, err := applyVariant(v.Path)
+	if err != nil {
+		return nil, fmt.Errorf("apply variant %s: %w", v.Name, err)
+	}
+	defer restore()
+
+	query, err := e.query(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	k := e.opts.K
+	if k <= 0 {
+		k = max(config.GetConfig().RetrievalTopK, 1)
+	}
+
+	var chatModel aihelper.AIModel
+	var similarity embedding.Embedder
+	if e.opts.ModelType != "" {
+		chatModel, err = aihelper.GetGlobalFactory().CreateAIModel(ctx, e.opts.ModelType, map[string]interface{}{
+			"username": e.opts.Username,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create model %s: %w", e.opts.ModelType, err)
+		}
+		// 回答相似度使用默认向量模型计算，不可用时只统计 F1
+		if spec, err := embedder.GetSpec(""); err == nil {
+			if similarity, err = embedder.New(ctx, spec); err != nil {
+				log.Printf("[eval] answer similarity disabled: %v", err)
+			}
+		}
+	}
+
+	result := &Result{Variant: v.Name}
+	for i := range e.dataset.Questions {
+		q := &e.dataset.Questions[i]
+		res := evaluate(ctx, query, chatModel, similarity, q, k)
+		if res.Error != "" {
+			log.Printf("[eval] %s %s: %s", v.Name, q.ID, res.Error)
+		}
+		result.Questions = append(result.Questions, res)
+	}
+	result.Summary = summarize(result.Questions, k)
+	return result, nil
+}
+
+// Close 删除评估时建立的临时索引
+func (e *Evaluator) Close(ctx context.Context) {
+	for _, name := range e.indexes {
+		if err := vectorstore.Default().DropCollection(ctx, name); err != nil {
+			log.Printf("[eval] drop index %s failed: %v", name, err)
+		}
+	}
+}
+
+// query 创建检索使用的查询器
+func (e *Evaluator) query(ctx context.Context, v Variant) (*rag.RAGQuery, error) {
+	conf := config.GetConfig()
+	if !e.UsesCorpus() {
+		return rag.NewRAGQuery(ctx, e.opts.Username, e.opts.KnowledgeBaseID)
+	}
+
+	key := fmt.Sprintf("%s|%d|%d|%s", conf.ChunkSplitter, conf.ChunkSize, conf.ChunkOverlap, conf.EmbeddingDefault)
+	name, ok := e.indexes[key]
+	if !ok {
+		name = fmt.Sprintf("eval_%d_%d", time.Now().Unix(), len(e.indexes))
+		log.Printf("[eval] building index %s for %s (splitter=%s chunkSize=%d chunkOverlap=%d embedder=%s)",
+			name, v.Name, conf.ChunkSplitter, conf.ChunkSize, conf.ChunkOverlap, conf.EmbeddingDefault)
+		indexer, err := rag.NewRAGIndexer(name, "", rag.IndexOptions(model.VectorIndexSettings{}))
+		if err != nil {
+			return nil, fmt.Errorf("create index: %w", err)
+		}
+		e.indexes[key] = name
+		chunks := 0
+		for _, doc := range e.documents {
+			n, err := indexer.IndexFile(ctx, doc)
+			if err != nil {
+				return nil, fmt.Errorf("index %s: %w", doc, err)
+			}
+			chunks += n
+		}
+		log.Printf("[eval] indexed %d documents, %d chunks", len(e.documents), chunks)
+	}
+	return rag.NewIndexQuery(ctx, name)
+}
+
+// evaluate 评估一个问题：按对话的检索流程检索，可选地让模型根据检索结果回答
+func evaluate(ctx context.Context, query *rag.RAGQuery, chatModel aihelper.AIModel, similarity embedding.Embedder, q *Question, k int) *QuestionResult {
+	res := &QuestionResult{ID: q.ID, Extra: q.Extra}
+	filter, err := rag.ParseFilter(q.Filter)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	ctx, trace := rag.WithTrace(ctx)
+	history := q.messages()
+	start := time.Now()
+	docs, err := query.RetrieveDocuments(ctx, rag.RewriteQuery(ctx, history, q.Question), filter)
+	res.RetrieveMs = milliseconds(time.Since(start))
+	res.Trace = trace
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Chunks = make([]string, 0, len(docs))
+	for _, doc := range docs {
+		res.Chunks = append(res.Chunks, doc.ID)
+	}
+	res.Recall, res.Reciprocal = scoreRetrieval(q.Expected, docs, k)
+
+	if chatModel == nil {
+		return res
+	}
+	// 与对话相同：检索到文档时使用 RAG 提示词，没有检索到时直接提问
+	content := q.Question
+	if len(docs) > 0 {
+		content = rag.BuildRAGPrompt(q.Question, docs, chatModel.GetModelName())
+	}
+	start = time.Now()
+	resp, err := chatModel.GenerateResponse(ctx, append(history, schema.UserMessage(content)))
+	res.AnswerMs = milliseconds(time.Since(start))
+	if err != nil {
+		res.Error = fmt.Sprintf("generate answer: %v", err)
+		return res
+	}
+	res.Answer = resp.Content
+	if q.Answer == "" {
+		return res
+	}
+	f1 := tokenF1(res.Answer, q.Answer)
+	res.F1 = &f1
+	if similarity != nil {
+		vectors, err := similarity.EmbedStrings(ctx, []string{res.Answer, q.Answer})
+		if err != nil || len(vectors) != 2 {
+			log.Printf("[eval] embed answer of %s failed: %v", q.ID, err)
+			return res
+		}
+		sim := cosine(vectors[0], vectors[1])
+		res.Similarity = &sim
+	}
+	return res
+}
+
+// applyVariant 将覆盖文件合并到全局配置，返回恢复原配置的函数
+// 原配置通过 JSON 深拷贝保存：toml 解码切片时会复用原有的底层数组
+func applyVariant(path string) (func(), error) {
+	conf := config.GetConfig()
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	saved := new(config.Config)
+	if err := json.Unmarshal(data, saved); err != nil {
+		return nil, err
+	}
+	restore := func() { *conf = *saved }
+	if path == "" {
+		return restore, nil
+	}
+	if _, err := toml.DecodeFile(path, conf); err != nil {
+		restore()
+		return nil, err
+	}
+	return restore, nil
+}
+
+// expandDocuments 展开语料中的目录
+func expandDocuments(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read documents %s: %w", path, err)
+		}
+	}
+	return files, nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,216 @@
+package eval
+
+import (
+	"GopherAI/common/rag"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// QuestionResult 一个问题在一套配置下的评估结果
+type QuestionResult struct {
+	ID         string            `json:"id"`
+	Chunks     []string          `json:"chunks"`               // 检索到的块 ID，按排名排列
+	Recall     *float64          `json:"recall,omitempty"`     // recall@k，没有标注来源时为空
+	Reciprocal *float64          `json:"reciprocal,omitempty"` // 第一个命中块排名的倒数，未命中为 0
+	Answer     string            `json:"answer,omitempty"`     // 模型的回答
+	Similarity *float64          `json:"similarity,omitempty"` // 回答与参考答案向量的余弦相似度
+	F1         *float64          `json:"f1,omitempty"`         // 回答与参考答案的字词重合 F1
+	RetrieveMs float64           `json:"retrieve_ms"`          // 检索耗时（含查询改写）
+	AnswerMs   float64           `json:"answer_ms,omitempty"`  // 模型回答耗时
+	Trace      *rag.Trace        `json:"trace,omitempty"`      // 改写后的查询和各阶段耗时
+	Error      string            `json:"error,omitempty"`
+	Extra      map[string]string `json:"extra,omitempty"`
+}
+
+// Summary 一套配置在整个问题集上的汇总指标，没有可统计样本的指标为 nil
+type Summary struct {
+	Questions   int      `json:"questions"`
+	Errors      int      `json:"errors"`
+	K           int      `json:"k"`
+	Recall      *float64 `json:"recall"`
+	HitRate     *float64 `json:"hit_rate"` // 至少命中一个期望来源的问题比例
+	MRR         *float64 `json:"mrr"`
+	Similarity  *float64 `json:"similarity"`
+	F1          *float64 `json:"f1"`
+	RetrieveAvg *float64 `json:"retrieve_ms_avg"`
+	RetrieveP50 *float64 `json:"retrieve_ms_p50"`
+	RetrieveP95 *float64 `json:"retrieve_ms_p95"`
+	AnswerAvg   *float64 `json:"answer_ms_avg"`
+}
+
+// scoreRetrieval 计算 recall@k 和倒数排名，没有标注来源时返回 nil
+func scoreRetrieval(expected []ExpectedSource, docs []*schema.Document, k int) (recall, reciprocal *float64) {
+	if len(expected) == 0 {
+		return nil, nil
+	}
+	if len(docs) > k {
+		docs = docs[:k]
+	}
+
+	found := 0
+	for _, exp := range expected {
+		for _, doc := range docs {
+			if exp.Match(doc) {
+				found++
+				break
+			}
+		}
+	}
+	r := float64(found) / float64(len(expected))
+
+	rr := 0.0
+	for rank, doc := range docs {
+		if matchAny(expected, doc) {
+			rr = 1 / float64(rank+1)
+			break
+		}
+	}
+	return &r, &rr
+}
+
+func matchAny(expected []ExpectedSource, doc *schema.Document) bool {
+	for _, exp := range expected {
+		if exp.Match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+// cosine 两个向量的余弦相似度
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// tokenF1 回答与参考答案的字词重合 F1，中文按字、其他语言按词切分，不依赖向量模型
+func tokenF1(answer, reference string) float64 {
+	pred, gold := tokens(answer), tokens(reference)
+	if len(pred) == 0 || len(gold) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(gold))
+	for _, t := range gold {
+		counts[t]++
+	}
+	common := 0
+	for _, t := range pred {
+		if counts[t] > 0 {
+			counts[t]--
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	precision := float64(common) / float64(len(pred))
+	recall := float64(common) / float64(len(gold))
+	return 2 * precision * recall / (precision + recall)
+}
+
+func tokens(text string) []string {
+	var result []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			result = append(result, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			result = append(result, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+// summarize 汇总各问题的结果
+func summarize(results []*QuestionResult, k int) Summary {
+	s := Summary{Questions: len(results), K: k}
+	var recall, hit, rr, sim, f1, answer []float64
+	retrieve := make([]float64, 0, len(results))
+	for _, r := range results {
+		if r.Error != "" {
+			s.Errors++
+			continue
+		}
+		retrieve = append(retrieve, r.RetrieveMs)
+		if r.Recall != nil {
+			recall = append(recall, *r.Recall)
+			rr = append(rr, *r.Reciprocal)
+			hit = append(hit, boolScore(*r.Reciprocal > 0))
+		}
+		if r.Similarity != nil {
+			sim = append(sim, *r.Similarity)
+		}
+		if r.F1 != nil {
+			f1 = append(f1, *r.F1)
+		}
+		if r.Answer != "" {
+			answer = append(answer, r.AnswerMs)
+		}
+	}
+	s.Recall = mean(recall)
+	s.HitRate = mean(hit)
+	s.MRR = mean(rr)
+	s.Similarity = mean(sim)
+	s.F1 = mean(f1)
+	s.RetrieveAvg = mean(retrieve)
+	s.RetrieveP50 = percentile(retrieve, 0.5)
+	s.RetrieveP95 = percentile(retrieve, 0.95)
+	s.AnswerAvg = mean(answer)
+	return s
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func mean(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	m := sum / float64(len(values))
+	return &m
+}
+
+// percentile 最近秩法计算分位数
+func percentile(values []float64, p float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return &sorted[max(idx, 0)]
+}
@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type metricRow struct {
+	name  string
+	value func(Summary) *float64
+	unit  string // 为空时按比例显示（三位小数），ms 时按毫秒显示
+}
+
+func metricRows(k string) []metricRow {
+	return []metricRow{
+		{name: "recall@" + k, value: func(s Summary) *float64 { return s.Recall }},
+		{name: "hit@" + k, value: func(s Summary) *float64 { return s.HitRate }},
+		{name: "mrr", value: func(s Summary) *float64 { return s.MRR }},
+		{name: "answer similarity", value: func(s Summary) *float64 { return s.Similarity }},
+		{name: "answer f1", value: func(s Summary) *float64 { return s.F1 }},
+		{name: "retrieve avg", value: func(s Summary) *float64 { return s.RetrieveAvg }, unit: "ms"},
+		{name: "retrieve p50", value: func(s Summary) *float64 { return s.RetrieveP50 }, unit: "ms"},
+		{name: "retrieve p95", value: func(s Summary) *float64 { return s.RetrieveP95 }, unit: "ms"},
+		{name: "answer avg", value: func(s Summary) *float64 { return s.AnswerAvg }, unit: "ms"},
+	}
+}
+
+// WriteReport 输出各配置的指标对比表，两套配置时额外输出差值和排名变化的问题
+func WriteReport(w io.Writer, results []*Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	compare := len(results) == 2
+
+	header := []string{"metric"}
+	for _, r := range results {
+		header = append(header, r.Variant)
+	}
+	if compare {
+		header = append(header, "delta")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+
+	// 各配置的 k 不同时（未指定 -k 且 topK 不同）单独列出 k
+	k := strconv.Itoa(results[0].Summary.K)
+	for _, r := range results[1:] {
+		if r.Summary.K != results[0].Summary.K {
+			k = "k"
+			cells := []string{"k"}
+			for _, r := range results {
+				cells = append(cells, strconv.Itoa(r.Summary.K))
+			}
+			if compare {
+				cells = append(cells, "")
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t")+"\t")
+			break
+		}
+	}
+
+	for _, row := range metricRows(k) {
+		cells := []string{row.name}
+		for _, r := range results {
+			cells = append(cells, formatMetric(row.value(r.Summary), row.unit))
+		}
+		if compare {
+			cells = append(cells, formatDelta(row.value(results[0].Summary), row.value(results[1].Summary), row.unit))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t")+"\t")
+	}
+	counts := []string{"questions / errors"}
+	for _, r := range results {
+		counts = append(counts, fmt.Sprintf("%d / %d", r.Summary.Questions, r.Summary.Errors))
+	}
+	if compare {
+		counts = append(counts, "")
+	}
+	fmt.Fprintln(tw, strings.Join(counts, "\t")+"\t")
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if compare {
+		writeRankChanges(w, results[0], results[1])
+	}
+	return nil
+}
+
+// writeRankChanges 列出两套配置下第一个命中块排名不同的问题
+func writeRankChanges(w io.Writer, a, b *Result) {
+	var changes []string
+	for i, qa := range a.Questions {
+		if i >= len(b.Questions) {
+			break
+		}
+		qb := b.Questions[i]
+		if qa.Reciprocal == nil || qb.Reciprocal == nil || *qa.Reciprocal == *qb.Reciprocal {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("  %s: %s -> %s", qa.ID, formatRank(*qa.Reciprocal), formatRank(*qb.Reciprocal)))
+	}
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nfirst relevant rank changed (%s -> %s):\n", a.Variant, b.Variant)
+	fmt.Fprintln(w, strings.Join(changes, "\n"))
+}
+
+func formatMetric(v *float64, unit string) string {
+	if v == nil {
+		return "-"
+	}
+	if unit == "ms" {
+		return fmt.Sprintf("%.1fms", *v)
+	}
+	return fmt.Sprintf("%.3f", *v)
+}
+
+func formatDelta(a, b *float64, unit string) string {
+	if a == nil || b == nil {
+		return "-"
+	}
+	if unit == "ms" {
+		return fmt.Sprintf("%+.1fms", *b-*a)
+	}
+	return fmt.Sprintf("%+.3f", *b-*a)
+}
+
+// formatRank 倒数排名转换为排名，未命中显示为 miss
+func formatRank(reciprocal float64) string {
+	if reciprocal == 0 {
+		return "miss"
+	}
+	return fmt.Sprintf("#%.0f", 1/reciprocal)
+}
@@ -0,0 +1,200 @@
+package rag
+
+import (
+	redisPkg "GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseFilter 解析检索过滤表达式，表达式由若干条件组成，条件之间用空格（或 AND）分隔，全部满足才会被检索到：
+//
+//	doc_id:12|15                 只在指定文档中检索，取值之一即可
+//	tags:release-notes           带有指定标签的文档
+//	language:en
+//	updated_at>=2025-01-01       支持 > >= < <= = :，日期可以写到年、月、日或 RFC3339 时间
+//
+// 取值中包含空格时用双引号括起来，如 tags:"release notes"
+func ParseFilter(expr string) (vectorstore.Filter, error) {
+	filter := vectorstore.Filter{}
+	terms, err := splitFilterTerms(expr)
+	if err != nil {
+		return filter, err
+	}
+	for _, term := range terms {
+		if strings.EqualFold(term, "AND") {
+			continue
+		}
+		idx := strings.IndexAny(term, ":<>=")
+		if idx <= 0 {
+			return filter, fmt.Errorf("invalid filter condition %q", term)
+		}
+		name, rest := term[:idx], term[idx:]
+		op := rest[:1]
+		if strings.HasPrefix(rest, ">=") || strings.HasPrefix(rest, "<=") {
+			op = rest[:2]
+		}
+		value := strings.Trim(rest[len(op):], `"`)
+		if value == "" {
+			return filter, fmt.Errorf("missing value in filter condition %q", term)
+		}
+
+		field, ok := redisPkg.LookupMetadataField(name)
+		if !ok {
+			return filter, fmt.Errorf("unknown filter field %q", name)
+		}
+		switch field.Type {
+		case redisPkg.MetadataTag:
+			if op != ":" && op != "=" {
+				return filter, fmt.Errorf("operator %q is not supported by %s", op, name)
+			}
+			if filter.Tags == nil {
+				filter.Tags = make(map[string][]string)
+			}
+			if _, exists := filter.Tags[name]; exists {
+				return filter, fmt.Errorf("duplicate filter field %q, use %s:a|b to match any of several values", name, name)
+			}
+			for _, v := range strings.Split(value, "|") {
+				if v = strings.TrimSpace(v); v != "" {
+					filter.Tags[name] = append(filter.Tags[name], v)
+				}
+			}
+		case redisPkg.MetadataNumeric:
+			start, end, err := parseFilterNumber(value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid value in filter condition %q: %w", term, err)
+			}
+			if filter.Ranges == nil {
+				filter.Ranges = make(map[string]vectorstore.NumericRange)
+			}
+			r, exists := filter.Ranges[name]
+			if !exists {
+				r = vectorstore.NumericRange{Min: math.Inf(-1), Max: math.Inf(1)}
+			}
+			filter.Ranges[name] = narrowRange(r, op, start, end)
+		}
+	}
+	return filter, nil
+}
+
+// splitFilterTerms 按空白拆分条件，双引号内的空白不拆分
+func splitFilterTerms(expr string) ([]string, error) {
+	terms := make([]string, 0)
+	var b strings.Builder
+	quoted := false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if b.Len() > 0 {
+				terms = append(terms, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in filter %q", expr)
+	}
+	if b.Len() > 0 {
+		terms = append(terms, b.String())
+	}
+	return terms, nil
+}
+
+// 日期取值支持的格式，只写到年、月、日时表示一整段时间
+var filterTimeLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{time.RFC3339, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{time.DateOnly, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// parseFilterNumber 解析数值或日期，返回取值覆盖的区间 [start, end)
+// 普通数值的 start 与 end 相同；日期转换为 Unix 时间戳（本地时区），如 2025-01 覆盖整个一月
+func parseFilterNumber(value string) (start, end float64, err error) {
+	for _, l := range filterTimeLayouts {
+		t, err := time.ParseInLocation(l.layout, value, time.Local)
+		if err == nil {
+			return float64(t.Unix()), float64(l.next(t).Unix()), nil
+		}
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is neither a number nor a date", value)
+	}
+	return v, v, nil
+}
+
+// narrowRange 用一个比较条件收窄取值范围，[start, end) 为取值覆盖的区间
+func narrowRange(r vectorstore.NumericRange, op string, start, end float64) vectorstore.NumericRange {
+	period := end > start
+	switch op {
+	case ">":
+		if period {
+			r = raiseMin(r, end, false)
+		} else {
+			r = raiseMin(r, start, true)
+		}
+	case ">=":
+		r = raiseMin(r, start, false)
+	case "<":
+		r = lowerMax(r, start, true)
+	case "<=":
+		if period {
+			r = lowerMax(r, end, true)
+		} else {
+			r = lowerMax(r, start, false)
+		}
+	default:
+		r = raiseMin(r, start, false)
+		if period {
+			r = lowerMax(r, end, true)
+		} else {
+			r = lowerMax(r, start, false)
+		}
+	}
+	return r
+}
+
+func raiseMin(r vectorstore.NumericRange, v float64, exclusive bool) vectorstore.NumericRange {
+	if v > r.Min || (v == r.Min && exclusive) {
+		r.Min, r.ExclusiveMin = v, exclusive
+	}
+	return r
+}
+
+func lowerMax(r vectorstore.NumericRange, v float64, exclusive bool) vectorstore.NumericRange {
+	if v < r.Max || (v == r.Max && exclusive) {
+		r.Max, r.ExclusiveMax = v, exclusive
+	}
+	return r
+}
+
+type filterKey struct{}
+
+// WithFilter 将检索过滤表达式放入 context，对话时由模型在检索知识库时取出
+func WithFilter(ctx context.Context, expr string) context.Context {
+	if strings.TrimSpace(expr) == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, filterKey{}, expr)
+}
+
+// FilterFromContext 取出并解析 context 中的检索过滤表达式，没有时返回空的过滤条件
+func FilterFromContext(ctx context.Context) (vectorstore.Filter, error) {
+	expr, _ := ctx.Value(filterKey{}).(string)
+	return ParseFilter(expr)
+}
@@ -0,0 +1,320 @@
+package rag
+
+import (
+	"GopherAI/common/rag/rerank"
+	"GopherAI/common/vectorstore"
+	"GopherAI/config"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// 检索方式
+const (
+	RetrievalHybrid   = "hybrid"   // 向量检索 + 全文检索，RRF 融合
+	RetrievalVector   = "vector"   // 仅向量检索
+	RetrievalFullText = "fulltext" // 仅全文检索（BM25）
+)
+
+type retrievalConfig struct {
+	mode         string
+	topK         int
+	candidates   int
+	vectorWeight float64
+	textWeight   float64
+	rrfK         int
+	maxDistance  float64
+}
+
+// retrievalOptions 读取检索配置并补全默认值
+func retrievalOptions() retrievalConfig {
+	conf := config.GetConfig()
+	opts := retrievalConfig{
+		mode:         conf.RetrievalMode,
+		topK:         conf.RetrievalTopK,
+		candidates:   conf.RetrievalCandidates,
+		vectorWeight: conf.RetrievalVectorWeight,
+		textWeight:   conf.RetrievalTextWeight,
+		rrfK:         conf.RetrievalRRFK,
+		maxDistance:  conf.RetrievalMaxDistance,
+	}
+	if opts.mode == "" {
+		opts.mode = RetrievalHybrid
+	}
+	if opts.topK <= 0 {
+		opts.topK = 5
+	}
+	if opts.candidates < opts.topK {
+		opts.candidates = max(opts.topK, 20)
+	}
+	if opts.vectorWeight == 0 && opts.textWeight == 0 {
+		opts.vectorWeight, opts.textWeight = 1, 1
+	}
+	if opts.rrfK <= 0 {
+		opts.rrfK = 60
+	}
+	return opts
+}
+
+// RetrieveDocuments 检索相关文档，只在满足过滤条件的块中检索（见 ParseFilter）
+// 改写后的查询和扩展出的每种表述分别检索，HyDE 的假设性回答只做向量检索，各路结果用 RRF 合并后再重排
+// 启用重排时先召回 rerankConfig.depth 个候选，重排后保留 topK 个
+func (r *RAGQuery) RetrieveDocuments(ctx context.Context, query *Query, filter vectorstore.Filter) ([]*schema.Document, error) {
+	opts := retrievalOptions()
+
+	reranker := rerank.GetGlobalReranker()
+	limit := opts.topK
+	if reranker != nil {
+		limit = rerank.Depth(opts.topK)
+	}
+
+	candidates, err := r.retrieveAll(ctx, query, limit, opts, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+	}
+	docs := filterByDistance(candidates, opts.maxDistance)
+	if len(docs) == 0 || reranker == nil {
+		recordCandidates(ctx, len(candidates), len(candidates)-len(docs), false)
+		logDocuments(r.indexName, query, docs)
+		return docs, nil
+	}
+
+	start := time.Now()
+	reranked, err := rerank.Rerank(ctx, reranker, query.Standalone, docs, opts.topK)
+	recordTiming(ctx, stageRerank, start)
+	if err != nil {
+		// 重排失败时使用检索阶段的排序
+		log.Printf("[rag] rerank failed, using retrieval order: %v", err)
+		reranked = docs[:min(len(docs), opts.topK)]
+	}
+	recordCandidates(ctx, len(candidates), len(candidates)-len(docs), err == nil)
+	logDocuments(r.indexName, query, reranked)
+	return reranked, nil
+}
+
+// RetrieveFromKnowledgeBases 在多个知识库中检索相关文档，knowledgeBaseID 为 0 表示默认知识库
+// 各知识库分别检索（可能使用不同的向量模型，距离不可直接比较），再按排名用 RRF 合并为 topK 个
+// 单个知识库不可用（尚未上传文档、无权访问等）时跳过，全部不可用时返回最后一个错误
+func RetrieveFromKnowledgeBases(ctx context.Context, username string, knowledgeBaseIDs []uint, query *Query, filter vectorstore.Filter) ([]*schema.Document, error) {
+	opts := retrievalOptions()
+	lists := make([]rankedList, 0, len(knowledgeBaseIDs))
+	var lastErr error
+	for _, id := range knowledgeBaseIDs {
+		ragQuery, err := NewRAGQuery(ctx, username, id)
+		if err == nil {
+			var docs []*schema.Document
+			if docs, err = ragQuery.RetrieveDocuments(ctx, query, filter); err == nil {
+				lists = append(lists, rankedList{docs: docs, weight: 1})
+				continue
+			}
+		}
+		log.Printf("[rag] retrieve from knowledge base %d failed: %v", id, err)
+		lastErr = err
+	}
+	if len(lists) == 0 {
+		return nil, lastErr
+	}
+	if len(lists) == 1 {
+		return lists[0].docs, nil
+	}
+	return fuseRRF(opts.topK, opts.rrfK, lists...), nil
+}
+
+// retrieveAll 对查询的每种表述分别召回 limit 个文档块，并用 RRF 合并
+// 某一路失败时使用其他路的结果，全部失败时返回第一个错误
+func (r *RAGQuery) retrieveAll(ctx context.Context, query *Query, limit int, opts retrievalConfig, filter vectorstore.Filter) ([]*schema.Document, error) {
+	texts := query.texts()
+	if len(texts) == 1 && query.HyDE == "" {
+		return r.retrieve(ctx, texts[0], limit, opts, filter)
+	}
+
+	results := make([][]*schema.Document, len(texts)+1)
+	errs := make([]error, len(texts)+1)
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = r.retrieve(ctx, text, limit, opts, filter)
+		}()
+	}
+	if query.HyDE != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[len(texts)], errs[len(texts)] = r.vectorSearch(ctx, query.HyDE, limit, filter)
+		}()
+	}
+	wg.Wait()
+
+	lists := make([]rankedList, 0, len(results))
+	var firstErr error
+	for i, docs := range results {
+		if errs[i] != nil {
+			log.Printf("[rag] retrieval %d of %d failed: %v", i+1, len(results), errs[i])
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		lists = append(lists, rankedList{docs: docs, weight: 1})
+	}
+	if len(lists) == 0 {
+		return nil, firstErr
+	}
+	return fuseRRF(limit, opts.rrfK, lists...), nil
+}
+
+// retrieve 召回 limit 个文档块
+// 混合检索时向量检索和全文检索并行执行，再用加权 RRF（Reciprocal Rank Fusion）合并排名：
+// score(d) = Σ weight_i / (k + rank_i(d))
+// 全文检索可以命中错误码、函数名等精确标识符，这类内容用向量检索往往召回不到
+func (r *RAGQuery) retrieve(ctx context.Context, query string, limit int, opts retrievalConfig, filter vectorstore.Filter) ([]*schema.Document, error) {
+	switch opts.mode {
+	case RetrievalVector:
+		return r.vectorSearch(ctx, query, limit, filter)
+	case RetrievalFullText:
+		return r.fullTextSearch(ctx, query, limit, filter)
+	}
+
+	candidates := max(opts.candidates, limit)
+	var (
+		wg                   sync.WaitGroup
+		vectorDocs, textDocs []*schema.Document
+		vectorErr, textErr   error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		vectorDocs, vectorErr = r.vectorSearch(ctx, query, candidates, filter)
+	}()
+	go func() {
+		defer wg.Done()
+		textDocs, textErr = r.fullTextSearch(ctx, query, candidates, filter)
+	}()
+	wg.Wait()
+
+	// 一路失败时使用另一路的结果
+	if vectorErr != nil && textErr != nil {
+		return nil, vectorErr
+	}
+	if vectorErr != nil {
+		log.Printf("[rag] vector search failed, using full-text results only: %v", vectorErr)
+	}
+	if textErr != nil {
+		log.Printf("[rag] full-text search failed, using vector results only: %v", textErr)
+	}
+
+	return fuseRRF(limit, opts.rrfK,
+		rankedList{docs: vectorDocs, weight: opts.vectorWeight},
+		rankedList{docs: textDocs, weight: opts.textWeight},
+	), nil
+}
+
+// vectorSearch KNN 向量检索
+func (r *RAGQuery) vectorSearch(ctx context.Context, query string, topK int, filter vectorstore.Filter) ([]*schema.Document, error) {
+	start := time.Now()
+	vectors, err := r.embedding.EmbedStrings(ctx, []string{query})
+	recordTiming(ctx, stageEmbedding, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for query", len(vectors))
+	}
+
+	start = time.Now()
+	results, err := r.store.Search(ctx, r.indexName, vectors[0], topK, filter)
+	recordTiming(ctx, stageSearch, start)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]*schema.Document, 0, len(results))
+	for _, result := range results {
+		docs = append(docs, convertDocument(result, true))
+	}
+	return docs, nil
+}
+
+// fullTextSearch 全文检索，使用 BM25 打分
+func (r *RAGQuery) fullTextSearch(ctx context.Context, query string, topK int, filter vectorstore.Filter) ([]*schema.Document, error) {
+	start := time.Now()
+	results, err := r.store.TextSearch(ctx, r.indexName, query, topK, filter)
+	recordTiming(ctx, stageSearch, start)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]*schema.Document, 0, len(results))
+	for _, result := range results {
+		docs = append(docs, convertDocument(result, false))
+	}
+	return docs, nil
+}
+
+// filterByDistance 去掉向量距离超过阈值的块，maxDistance 为 0 时不过滤
+// 只由全文检索召回的块没有向量距离，关键词命中视为相关，予以保留
+func filterByDistance(docs []*schema.Document, maxDistance float64) []*schema.Document {
+	if maxDistance <= 0 {
+		return docs
+	}
+	kept := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if distance, ok := doc.MetaData["distance"].(float64); ok && distance > maxDistance {
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	if dropped := len(docs) - len(kept); dropped > 0 {
+		log.Printf("[rag] distance threshold %.3f dropped %d of %d candidates", maxDistance, dropped, len(docs))
+	}
+	return kept
+}
+
+type rankedList struct {
+	docs   []*schema.Document
+	weight float64
+}
+
+// fuseRRF 按加权 RRF 合并多路检索结果，融合分数记录在 MetaData["score"] 中
+func fuseRRF(topK, k int, lists ...rankedList) []*schema.Document {
+	scores := make(map[string]float64)
+	docs := make(map[string]*schema.Document)
+	order := make([]string, 0)
+
+	for _, list := range lists {
+		for rank, doc := range list.docs {
+			if _, ok := docs[doc.ID]; !ok {
+				docs[doc.ID] = doc
+				order = append(order, doc.ID)
+			} else {
+				// 向量检索的结果带有 distance，合并元数据保留该字段
+				for key, val := range doc.MetaData {
+					if _, exists := docs[doc.ID].MetaData[key]; !exists {
+						docs[doc.ID].MetaData[key] = val
+					}
+				}
+			}
+			scores[doc.ID] += list.weight / float64(k+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	if len(order) > topK {
+		order = order[:topK]
+	}
+
+	result := make([]*schema.Document, 0, len(order))
+	for _, id := range order {
+		doc := docs[id]
+		doc.MetaData["score"] = scores[id]
+		result = append(result, doc)
+	}
+	return result
+}
@@ -0,0 +1,204 @@
+package rag
+
+import (
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// ingestBatchSize 每批向量化写入的块数量，每批完成后更新一次进度
+const ingestBatchSize = 10
+
+// IngestHeartbeatInterval 任务执行期间更新 updated_at 的间隔
+// 解析大文件、等待知识库锁或单批向量化都可能长时间没有进度，心跳保证执行中的任务不会被当作中断的任务重新投递
+const IngestHeartbeatInterval = time.Minute
+
+// RunIngestJob 执行文档入库任务：解析、切块、分批向量化写入索引，并记录进度
+// 入库是增量的：内容未变化的块（索引中已存在同一内容哈希的块）不重新向量化，
+// 新版本中已不存在的块会从索引中删除；重试失败的任务时，已写入成功的块同样会被跳过
+// 解析等过程中的 panic 会将任务标记为失败，不会使任务一直停留在处理中
+func RunIngestJob(ctx context.Context, jobID uint) (err error) {
+	job, err := knowledgeDao.GetIngestJobByID(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get ingest job %d: %w", jobID, err)
+	}
+	// 消息重复投递（如处理完成后确认前进程退出）
+	if job.Status == model.IngestJobDone {
+		return nil
+	}
+	doc, err := knowledgeDao.GetDocument(job.DocumentID)
+	if err != nil {
+		return failIngestJob(job, nil, fmt.Errorf("failed to get document %d: %w", job.DocumentID, err))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[rag] ingest job %d panic: %v\n%s", job.ID, r, debug.Stack())
+			err = failIngestJob(job, doc, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	stop := startIngestHeartbeat(job.ID)
+	defer stop()
+
+	job.Attempts++
+	job.Error = ""
+	if err := updateIngestJob(job, model.IngestJobParsing); err != nil {
+		return err
+	}
+
+	// 1. 解析并切块
+	chunks, err := loadDocumentChunks(ctx, doc)
+	if err != nil {
+		return failIngestJob(job, doc, err)
+	}
+
+	// 2. 准备索引，索引是用其他向量模型构建的时，先重建知识库中的其他文档
+	// 写入索引期间持有知识库锁，避免与后台重建（triggerReindex）同时删除、重建同一个索引
+	unlock := lockKnowledgeBase(job.KnowledgeBaseID)
+	defer unlock()
+	indexName, err := claimIndex(job.UserName, job.KnowledgeBaseID)
+	if err != nil {
+		return failIngestJob(job, doc, err)
+	}
+	stale, err := isIndexStale(ctx, indexName)
+	if err != nil {
+		return failIngestJob(job, doc, err)
+	}
+	indexer, err := NewRAGIndexer(indexName, "", knowledgeBaseIndexOptions(job.KnowledgeBaseID))
+	if err != nil {
+		return failIngestJob(job, doc, err)
+	}
+	if stale {
+		reindexDocuments(ctx, indexer, job.KnowledgeBaseID, doc.FilePath)
+	}
+
+	// 3. 与索引中已有的块对比，块 ID 包含内容哈希，ID 已存在说明内容未变化
+	existing, err := indexer.ExistingChunks(ctx, DocumentKey(doc.FilePath))
+	if err != nil {
+		return failIngestJob(job, doc, err)
+	}
+	pending := make([]*schema.Document, 0, len(chunks))
+	unchanged := make([]*schema.Document, 0)
+	for _, chunk := range chunks {
+		if existing[chunk.ID] {
+			unchanged = append(unchanged, chunk)
+			delete(existing, chunk.ID)
+		} else {
+			pending = append(pending, chunk)
+		}
+	}
+	// 未变化的块在文档中的位置、文档的标签等可能改变，只更新元数据
+	if err := indexer.UpdateChunkMetadata(ctx, unchanged); err != nil {
+		return failIngestJob(job, doc, fmt.Errorf("failed to update chunk metadata: %w", err))
+	}
+	// 新版本中已不存在的块
+	removed := make([]string, 0, len(existing))
+	for id := range existing {
+		removed = append(removed, id)
+	}
+	if err := indexer.DeleteChunks(ctx, removed); err != nil {
+		return failIngestJob(job, doc, fmt.Errorf("failed to delete removed chunks: %w", err))
+	}
+
+	job.TotalChunks = len(chunks)
+	job.IndexedChunks = len(unchanged)
+	job.SkippedChunks = len(unchanged)
+	job.RemovedChunks = len(removed)
+	job.FailedChunks = nil
+
+	// 4. 分批向量化并写入新增或变化的块，单批失败不影响其他批次
+	if err := updateIngestJob(job, model.IngestJobEmbedding); err != nil {
+		return err
+	}
+	for start := 0; start < len(pending); start += ingestBatchSize {
+		end := min(start+ingestBatchSize, len(pending))
+		batch := pending[start:end]
+		if err := indexer.StoreChunks(ctx, batch); err != nil {
+			log.Printf("[rag] ingest job %d: store chunks %d-%d failed: %v", job.ID, start, end-1, err)
+			for _, chunk := range batch {
+				job.FailedChunks = append(job.FailedChunks, chunk.ID)
+			}
+			job.Error = err.Error()
+		} else {
+			job.IndexedChunks += len(batch)
+		}
+		if err := knowledgeDao.SaveIngestJob(job); err != nil {
+			log.Printf("[rag] ingest job %d: save progress failed: %v", job.ID, err)
+		}
+	}
+
+	// 5. 更新文档和任务状态
+	if len(job.FailedChunks) > 0 {
+		return failIngestJob(job, doc, fmt.Errorf("%d of %d chunks failed: %s", len(job.FailedChunks), job.TotalChunks, job.Error))
+	}
+	if err := knowledgeDao.UpdateDocumentStatus(doc.ID, model.DocumentStatusIndexed, job.IndexedChunks); err != nil {
+		log.Printf("[rag] ingest job %d: update document status failed: %v", job.ID, err)
+	}
+	log.Printf("[rag] ingest job %d done: %s (%d chunks, %d unchanged, %d removed)",
+		job.ID, doc.FileName, job.TotalChunks, job.SkippedChunks, job.RemovedChunks)
+	return updateIngestJob(job, model.IngestJobDone)
+}
+
+// startIngestHeartbeat 定期更新任务的 updated_at，返回停止函数
+func startIngestHeartbeat(jobID uint) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(IngestHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := knowledgeDao.TouchIngestJob(jobID); err != nil {
+					log.Printf("[rag] ingest job %d: heartbeat failed: %v", jobID, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// loadDocumentChunks 解析并切分文档，每个块附带文档的可过滤元数据（见 redis.MetadataFields）
+func loadDocumentChunks(ctx context.Context, doc *model.Document) ([]*schema.Document, error) {
+	chunks, err := LoadChunks(ctx, doc.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		chunk.MetaData["doc_id"] = strconv.FormatUint(uint64(doc.ID), 10)
+		chunk.MetaData["tags"] = doc.Tags
+		chunk.MetaData["language"] = doc.Language
+		chunk.MetaData["updated_at"] = doc.UpdatedAt.Unix()
+	}
+	return chunks, nil
+}
+
+func updateIngestJob(job *model.IngestJob, status string) error {
+	job.Status = status
+	if err := knowledgeDao.SaveIngestJob(job); err != nil {
+		return fmt.Errorf("failed to update ingest job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// failIngestJob 将任务和文档标记为失败，已写入的块保留在索引中，重试时只处理未写入的块
+func failIngestJob(job *model.IngestJob, doc *model.Document, cause error) error {
+	job.Error = cause.Error()
+	if doc != nil {
+		if err := knowledgeDao.UpdateDocumentStatus(doc.ID, model.DocumentStatusFailed, job.IndexedChunks); err != nil {
+			log.Printf("[rag] ingest job %d: update document status failed: %v", job.ID, err)
+		}
+	}
+	if err := updateIngestJob(job, model.IngestJobFailed); err != nil {
+		log.Printf("[rag] %v", err)
+	}
+	return fmt.Errorf("ingest job %d failed: %w", job.ID, cause)
+}
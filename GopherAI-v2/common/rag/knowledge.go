@@ -0,0 +1,240 @@
+package rag
+
+import (
+	"GopherAI/common/embedder"
+	redisPkg "GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrIndexForbidden 索引不属于当前用户
+var ErrIndexForbidden = errors.New("index belongs to another user")
+
+// errIndexNotFound 索引没有所有者记录，知识库还没有入库过文档
+var errIndexNotFound = errors.New("index not found")
+
+// KnowledgeBaseIndex 知识库对应的索引标识，同一知识库的所有文档存放在同一个索引中
+// 标识包含所有者，索引名、key 前缀和元信息 key 都按用户隔离
+func KnowledgeBaseIndex(owner string, knowledgeBaseID uint) string {
+	return fmt.Sprintf("u_%s:kb_%d", owner, knowledgeBaseID)
+}
+
+// claimIndex 写入索引前在 MySQL 中记录索引的所有者，已被其他用户记录时拒绝写入
+func claimIndex(username string, knowledgeBaseID uint) (string, error) {
+	indexName := KnowledgeBaseIndex(username, knowledgeBaseID)
+	owner, err := knowledgeDao.ClaimVectorIndex(&model.VectorIndex{
+		Name:            indexName,
+		UserName:        username,
+		KnowledgeBaseID: knowledgeBaseID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to record owner of %s: %w", indexName, err)
+	}
+	if owner.UserName != username || owner.KnowledgeBaseID != knowledgeBaseID {
+		return "", fmt.Errorf("%s: %w", indexName, ErrIndexForbidden)
+	}
+	return indexName, nil
+}
+
+// checkIndexOwner 校验索引的所有者，索引没有所有者记录时同样拒绝访问
+func checkIndexOwner(username, indexName string) error {
+	owner, err := knowledgeDao.GetVectorIndex(indexName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s: %w", indexName, errIndexNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get owner of %s: %w", indexName, err)
+	}
+	if owner.UserName != username {
+		return fmt.Errorf("%s: %w", indexName, ErrIndexForbidden)
+	}
+	return nil
+}
+
+// resolveKnowledgeBase 获取用户可以检索的知识库（自己的或共享的），knowledgeBaseID 为 0 时使用默认知识库
+func resolveKnowledgeBase(username string, knowledgeBaseID uint) (*model.KnowledgeBase, error) {
+	var (
+		kb  *model.KnowledgeBase
+		err error
+	)
+	if knowledgeBaseID == 0 {
+		kb, err = knowledgeDao.GetDefaultKnowledgeBase(username)
+	} else {
+		kb, err = knowledgeDao.GetReadableKnowledgeBase(username, knowledgeBaseID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no knowledge base found for user %s", username)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge base: %w", err)
+	}
+	return kb, nil
+}
+
+// IndexOptions 将知识库的索引设置转换为索引参数，未设置的字段使用配置文件中的默认值
+func IndexOptions(settings model.VectorIndexSettings) redisPkg.VectorIndexOptions {
+	return redisPkg.VectorIndexOptions{
+		Algorithm:      settings.Algorithm,
+		Metric:         settings.Metric,
+		VectorType:     settings.VectorType,
+		M:              settings.M,
+		EfConstruction: settings.EfConstruction,
+		EfRuntime:      settings.EfRuntime,
+	}.Normalize()
+}
+
+// IndexSettings IndexOptions 的逆过程，FLAT 索引不记录 HNSW 参数
+func IndexSettings(opts redisPkg.VectorIndexOptions) model.VectorIndexSettings {
+	settings := model.VectorIndexSettings{
+		Algorithm:  opts.Algorithm,
+		Metric:     opts.Metric,
+		VectorType: opts.VectorType,
+	}
+	if opts.Algorithm == redisPkg.VectorAlgorithmHNSW {
+		settings.M = opts.M
+		settings.EfConstruction = opts.EfConstruction
+		settings.EfRuntime = opts.EfRuntime
+	}
+	return settings
+}
+
+// knowledgeBaseIndexOptions 读取知识库的索引参数，知识库不存在时使用默认参数
+func knowledgeBaseIndexOptions(knowledgeBaseID uint) redisPkg.VectorIndexOptions {
+	kb, err := knowledgeDao.GetKnowledgeBaseByID(knowledgeBaseID)
+	if err != nil {
+		log.Printf("[rag] get knowledge base %d failed, use default index options: %v", knowledgeBaseID, err)
+		return redisPkg.VectorIndexOptions{}.Normalize()
+	}
+	return IndexOptions(kb.VectorIndex)
+}
+
+// DropKnowledgeBaseIndex 删除知识库的索引以及所有向量数据，只有索引的所有者可以删除
+func DropKnowledgeBaseIndex(ctx context.Context, username string, knowledgeBaseID uint) error {
+	indexName := KnowledgeBaseIndex(username, knowledgeBaseID)
+	if err := checkIndexOwner(username, indexName); err != nil {
+		// 知识库还没有入库过文档，没有索引
+		if errors.Is(err, errIndexNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := vectorstore.Default().DropCollection(ctx, indexName); err != nil {
+		return fmt.Errorf("failed to drop knowledge base index: %w", err)
+	}
+	return knowledgeDao.DeleteVectorIndex(indexName)
+}
+
+// DeleteDocumentChunks 从知识库索引中删除文档的所有块，返回删除的块数量，只有索引的所有者可以删除
+func DeleteDocumentChunks(ctx context.Context, username string, knowledgeBaseID uint, filePath string) (int, error) {
+	indexName := KnowledgeBaseIndex(username, knowledgeBaseID)
+	if err := checkIndexOwner(username, indexName); err != nil {
+		// 知识库还没有索引，文档没有写入过块
+		if errors.Is(err, errIndexNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	deleted, err := vectorstore.Default().Delete(ctx, indexName, vectorstore.Filter{Documents: []string{DocumentKey(filePath)}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete chunks of %s: %w", filePath, err)
+	}
+	return deleted, nil
+}
+
+// isIndexStale 索引已存在但不是用当前默认向量模型构建的
+func isIndexStale(ctx context.Context, indexName string) (bool, error) {
+	spec, err := embedder.GetSpec("")
+	if err != nil {
+		return false, err
+	}
+	collection, err := vectorstore.Default().GetCollection(ctx, indexName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return collection != nil && collection.EmbedderID != spec.ID, nil
+}
+
+// reindexDocuments 将知识库中已索引的文档重新写入索引，skipPath 对应的文档由调用方处理
+func reindexDocuments(ctx context.Context, indexer *RAGIndexer, knowledgeBaseID uint, skipPath string) {
+	docs, err := knowledgeDao.GetDocumentsByKnowledgeBase(knowledgeBaseID)
+	if err != nil {
+		log.Printf("[rag] list documents of knowledge base %d failed: %v", knowledgeBaseID, err)
+		return
+	}
+	for _, doc := range docs {
+		if doc.FilePath == skipPath || doc.Status == model.DocumentStatusFailed {
+			continue
+		}
+		chunks, err := loadDocumentChunks(ctx, &doc)
+		if err == nil {
+			err = indexer.StoreChunks(ctx, chunks)
+		}
+		status, count := model.DocumentStatusIndexed, len(chunks)
+		if err != nil {
+			log.Printf("[rag] reindex document %s failed: %v", doc.FilePath, err)
+			status, count = model.DocumentStatusFailed, 0
+		}
+		if err := knowledgeDao.UpdateDocumentStatus(doc.ID, status, count); err != nil {
+			log.Printf("[rag] update document %d status failed: %v", doc.ID, err)
+		}
+	}
+}
+
+// 每个知识库一把锁：入库和向量模型切换后的重建都会修改同一个索引（重建时会先删除整个索引），不能同时进行
+var knowledgeBaseLocks sync.Map
+
+// lockKnowledgeBase 锁定知识库的索引，返回解锁函数
+func lockKnowledgeBase(knowledgeBaseID uint) func() {
+	v, _ := knowledgeBaseLocks.LoadOrStore(knowledgeBaseID, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// 正在等待或执行重建的知识库，避免同一个知识库被重复重建
+var reindexing sync.Map
+
+// triggerReindex 在后台使用默认向量模型重建知识库索引
+// 与入库任务共用知识库锁；等待期间入库任务可能已经完成了重建，拿到锁后重新检查
+func triggerReindex(indexName string, knowledgeBaseID uint) {
+	if _, loaded := reindexing.LoadOrStore(knowledgeBaseID, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer reindexing.Delete(knowledgeBaseID)
+		unlock := lockKnowledgeBase(knowledgeBaseID)
+		defer unlock()
+
+		ctx := context.Background()
+		spec, err := embedder.GetSpec("")
+		if err != nil {
+			log.Printf("[rag] reindex %s failed: %v", indexName, err)
+			return
+		}
+		collection, err := vectorstore.Default().GetCollection(ctx, indexName)
+		if err != nil {
+			log.Printf("[rag] reindex %s failed: %v", indexName, err)
+			return
+		}
+		if collection != nil && collection.EmbedderID == spec.ID {
+			return
+		}
+
+		log.Printf("[rag] embedder changed, reindexing %s", indexName)
+		indexer, err := NewRAGIndexer(indexName, "", knowledgeBaseIndexOptions(knowledgeBaseID))
+		if err != nil {
+			log.Printf("[rag] reindex %s failed: %v", indexName, err)
+			return
+		}
+		reindexDocuments(ctx, indexer, knowledgeBaseID, "")
+		log.Printf("[rag] reindex %s done", indexName)
+	}()
+}
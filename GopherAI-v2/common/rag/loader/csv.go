@@ -0,0 +1,78 @@
+package loader
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// csvLoader 每一行转换为 "列名: 值" 形式的文本，每行一个文档，MetaData 中记录行号
+type csvLoader struct{}
+
+func (csvLoader) Load(ctx context.Context, data []byte) ([]*schema.Document, error) {
+	text := decodeText(data)
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = sniffDelimiter(text)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+		if header[i] == "" {
+			header[i] = fmt.Sprintf("列%d", i+1)
+		}
+	}
+
+	docs := make([]*schema.Document, 0)
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv at row %d: %w", row, err)
+		}
+
+		lines := make([]string, 0, len(record))
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			name := fmt.Sprintf("列%d", i+1)
+			if i < len(header) {
+				name = header[i]
+			}
+			lines = append(lines, name+": "+value)
+		}
+		docs = appendDocument(docs, strings.Join(lines, "\n"), map[string]any{MetaRow: row})
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no rows found in csv")
+	}
+	return docs, nil
+}
+
+// sniffDelimiter 根据首行判断分隔符（逗号、分号或制表符）
+func sniffDelimiter(text string) rune {
+	first := text
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		first = text[:i]
+	}
+	best, bestCount := ',', strings.Count(first, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(first, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,238 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// docxLoader 解析 Word（OOXML）文档
+// 按标题拆分章节，MetaData 记录章节路径和页码；
+// 页码根据 Word 保存时记录的分页位置（lastRenderedPageBreak）和手动分页符估算
+type docxLoader struct{}
+
+// 样式名称中的标题级别，例如 "heading 1"、"标题 2"
+var headingStyleRegex = regexp.MustCompile(`(?i)^(heading|标题)\s*(\d)$`)
+
+func (docxLoader) Load(ctx context.Context, data []byte) ([]*schema.Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid docx: %w", err)
+	}
+
+	var document []byte
+	styles := map[string]int{}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			if document, err = readZipFile(f); err != nil {
+				return nil, err
+			}
+		case "word/styles.xml":
+			if content, err := readZipFile(f); err == nil {
+				styles = parseDocxStyles(content)
+			}
+		}
+	}
+	if document == nil {
+		return nil, fmt.Errorf("invalid docx: word/document.xml not found")
+	}
+	return parseDocxBody(document, styles)
+}
+
+// readZipFile 读取压缩包中的文件，解压后超过 MaxDecodedSize 时返回错误
+// 文件头中记录的大小可以伪造，读取时同样限制大小
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > MaxDecodedSize {
+		return nil, fmt.Errorf("invalid docx: %s is too large (%d bytes)", f.Name, f.UncompressedSize64)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, MaxDecodedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDecodedSize {
+		return nil, fmt.Errorf("invalid docx: %s exceeds %d bytes", f.Name, MaxDecodedSize)
+	}
+	return data, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseDocxStyles 解析样式表，返回 样式 ID -> 标题级别（1 开始）
+func parseDocxStyles(data []byte) map[string]int {
+	levels := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	styleID := ""
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		e, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch e.Name.Local {
+		case "style":
+			styleID = attr(e, "styleId")
+		case "name":
+			name := attr(e, "val")
+			if m := headingStyleRegex.FindStringSubmatch(name); m != nil {
+				levels[styleID], _ = strconv.Atoi(m[2])
+			} else if strings.EqualFold(name, "title") {
+				levels[styleID] = 1
+			}
+		case "outlineLvl":
+			if _, ok := levels[styleID]; !ok {
+				if lvl, err := strconv.Atoi(attr(e, "val")); err == nil && lvl < 9 {
+					levels[styleID] = lvl + 1
+				}
+			}
+		}
+	}
+	return levels
+}
+
+// parseDocxBody 解析正文
+func parseDocxBody(data []byte, styles map[string]int) ([]*schema.Document, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		docs     = make([]*schema.Document, 0)
+		headings = make([]string, 0)
+		levels   = make([]int, 0)
+		section  strings.Builder // 当前章节（当前页）的内容
+		para     strings.Builder // 当前段落
+		page     = 1
+		docPage  = 1 // 当前章节内容开始的页码
+		paraPage = 1
+		level    = 0 // 当前段落的标题级别，0 表示正文
+		inText   = false
+		tables   = 0
+	)
+
+	flush := func() {
+		meta := map[string]any{MetaPage: docPage}
+		if len(headings) > 0 {
+			meta[MetaSection] = sectionPath(headings)
+		}
+		docs = appendDocument(docs, section.String(), meta)
+		section.Reset()
+	}
+
+	// 分页出现在段落开头时，整个段落属于新的一页
+	newPage := func() {
+		page++
+		if strings.TrimSpace(para.String()) == "" {
+			paraPage = page
+		}
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid docx xml: %w", err)
+		}
+
+		switch e := tok.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "p":
+				para.Reset()
+				level = 0
+				paraPage = page
+			case "pStyle":
+				if lvl, ok := styles[attr(e, "val")]; ok {
+					level = lvl
+				}
+			case "outlineLvl":
+				if lvl, err := strconv.Atoi(attr(e, "val")); err == nil && lvl < 9 {
+					level = lvl + 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br":
+				if attr(e, "type") == "page" {
+					newPage()
+				} else {
+					para.WriteString("\n")
+				}
+			case "lastRenderedPageBreak":
+				newPage()
+			case "tbl":
+				tables++
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(e)
+			}
+		case xml.EndElement:
+			switch e.Name.Local {
+			case "t":
+				inText = false
+			case "tc":
+				section.WriteString(" | ")
+			case "tr":
+				section.WriteString("\n")
+			case "tbl":
+				tables--
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if level > 0 && text != "" && tables == 0 {
+					// 新的标题：结束上一章节
+					flush()
+					for len(levels) > 0 && levels[len(levels)-1] >= level {
+						levels = levels[:len(levels)-1]
+						headings = headings[:len(headings)-1]
+					}
+					levels = append(levels, level)
+					headings = append(headings, text)
+					docPage = paraPage
+					section.WriteString(text + "\n")
+					continue
+				}
+				if paraPage != docPage {
+					// 跨页时拆分，保证每个文档的页码准确
+					flush()
+					docPage = paraPage
+				}
+				if tables > 0 {
+					section.WriteString(text)
+				} else if text != "" {
+					section.WriteString(text + "\n")
+				}
+			}
+		}
+	}
+	flush()
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no text found in docx")
+	}
+	return docs, nil
+}
@@ -0,0 +1,22 @@
+package loader
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// decodeText 将文本转换为 UTF-8
+// 去掉 UTF-8 BOM；非 UTF-8 内容按 GB18030 解码（Windows 下 Excel、记事本导出的中文文件常见）
+func decodeText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
@@ -0,0 +1,231 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlLoader 提取网页正文（类似 Readability）
+// 去掉脚本、导航、页眉页脚等噪音后，按段落文本量给候选容器打分，选出正文所在的容器，
+// 再按 h1-h6 标题拆分章节
+type htmlLoader struct{}
+
+var (
+	positiveClassRegex = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story|正文`)
+	negativeClassRegex = regexp.MustCompile(`(?i)comment|combx|foot|header|menu|meta|nav|related|sidebar|sponsor|share|social|banner|ad-|advert|breadcrumb|popup|modal`)
+)
+
+// 不包含正文的标签
+var skipTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Svg: true, atom.Template: true,
+}
+
+func (htmlLoader) Load(ctx context.Context, data []byte) ([]*schema.Document, error) {
+	root, err := html.Parse(bytes.NewReader([]byte(decodeText(data))))
+	if err != nil {
+		return nil, fmt.Errorf("invalid html: %w", err)
+	}
+
+	title := ""
+	if t := findFirst(root, atom.Title); t != nil {
+		title = strings.TrimSpace(textOf(t))
+	}
+
+	removeNoise(root)
+	content := mainContent(root)
+	if content == nil {
+		return nil, fmt.Errorf("no content found in html")
+	}
+
+	e := &htmlExtractor{title: title}
+	e.walk(content)
+	e.flush()
+	if len(e.docs) == 0 {
+		return nil, fmt.Errorf("no text found in html")
+	}
+	return e.docs, nil
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func classAndID(n *html.Node) string {
+	s := ""
+	for _, a := range n.Attr {
+		if a.Key == "class" || a.Key == "id" {
+			s += " " + a.Val
+		}
+	}
+	return s
+}
+
+// removeNoise 删除不包含正文的节点
+func removeNoise(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode {
+			ci := classAndID(c)
+			if skipTags[c.DataAtom] || (c.DataAtom != atom.Body && c.DataAtom != atom.Html &&
+				negativeClassRegex.MatchString(ci) && !positiveClassRegex.MatchString(ci)) {
+				n.RemoveChild(c)
+			} else {
+				removeNoise(c)
+			}
+		}
+		c = next
+	}
+}
+
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// mainContent 选出正文容器：段落文本越多、逗号越多得分越高，父节点得到全部分数，祖父节点得到一半
+func mainContent(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td || n.DataAtom == atom.Li) {
+			text := strings.TrimSpace(textOf(n))
+			if len([]rune(text)) >= 20 {
+				score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "。"))
+				score += float64(min(len([]rune(text))/100, 3))
+				if parent := n.Parent; parent != nil {
+					scores[parent] += score
+					if grand := parent.Parent; grand != nil {
+						scores[grand] += score / 2
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		ci := classAndID(n)
+		if positiveClassRegex.MatchString(ci) {
+			score += 25
+		}
+		if negativeClassRegex.MatchString(ci) {
+			score -= 25
+		}
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+			score += 25
+		}
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best != nil {
+		return best
+	}
+	// 没有明显的正文段落（例如只有列表或表格的页面），使用整个 body
+	if body := findFirst(root, atom.Body); body != nil {
+		return body
+	}
+	return root
+}
+
+// htmlExtractor 提取文本并按标题拆分章节
+type htmlExtractor struct {
+	title    string
+	docs     []*schema.Document
+	headings []string
+	levels   []int
+	sb       strings.Builder
+}
+
+var headingLevels = map[atom.Atom]int{atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6}
+
+// 块级元素前后换行
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true, atom.Pre: true,
+	atom.Blockquote: true, atom.Section: true, atom.Article: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+}
+
+func (e *htmlExtractor) flush() {
+	meta := map[string]any{}
+	if e.title != "" {
+		meta[MetaTitle] = e.title
+	}
+	if len(e.headings) > 0 {
+		meta[MetaSection] = sectionPath(e.headings)
+	}
+	e.docs = appendDocument(e.docs, collapseBlankLines(e.sb.String()), meta)
+	e.sb.Reset()
+}
+
+func (e *htmlExtractor) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		e.sb.WriteString(strings.Join(strings.Fields(n.Data), " "))
+		return
+	case html.ElementNode:
+		if level, ok := headingLevels[n.DataAtom]; ok {
+			text := strings.TrimSpace(strings.Join(strings.Fields(textOf(n)), " "))
+			if text != "" {
+				e.flush()
+				for len(e.levels) > 0 && e.levels[len(e.levels)-1] >= level {
+					e.levels = e.levels[:len(e.levels)-1]
+					e.headings = e.headings[:len(e.headings)-1]
+				}
+				e.levels = append(e.levels, level)
+				e.headings = append(e.headings, text)
+				e.sb.WriteString(text + "\n")
+			}
+			return
+		}
+		if n.DataAtom == atom.Td || n.DataAtom == atom.Th {
+			defer e.sb.WriteString(" | ")
+		}
+		if blockTags[n.DataAtom] {
+			e.sb.WriteString("\n")
+			defer e.sb.WriteString("\n")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		e.walk(c)
+	}
+}
+
+var blankLinesRegex = regexp.MustCompile(`[ \t]*\n[ \t\n]*\n`)
+
+func collapseBlankLines(s string) string {
+	return blankLinesRegex.ReplaceAllString(s, "\n\n")
+}
@@ -0,0 +1,153 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 文档元数据字段，用于回答时标注引用来源
+const (
+	MetaPage    = "page"    // 页码（从 1 开始），PDF 为实际页码，DOCX 为根据分页符估算的页码
+	MetaSection = "section" // 所在章节（标题路径，以 " > " 连接）
+	MetaRow     = "row"     // CSV 行号（从 1 开始，不含表头）
+	MetaTitle   = "title"   // 文档标题（HTML <title>）
+)
+
+// Loader 将文件解析为若干文档，文档会再经过切分器切块
+// 按页、章节或行拆分，并在 MetaData 中记录 page / section / row，便于引用
+type Loader interface {
+	Load(ctx context.Context, data []byte) ([]*schema.Document, error)
+}
+
+type registration struct {
+	name       string
+	loader     Loader
+	mimeTypes  []string
+	extensions []string
+}
+
+var registry = make([]*registration, 0)
+
+// MaxDecodedSize 解压后内容（DOCX 中的 XML、PDF 中的压缩流）的大小上限，防止压缩炸弹耗尽内存
+const MaxDecodedSize = 64 << 20
+
+// SniffLimit 内容嗅探读取的字节数
+// DOCX 等 zip 格式需要遍历压缩包的文件列表才能识别，默认的 3KB 不够
+const SniffLimit = 64 * 1024
+
+// Register 注册加载器，mimeTypes 为内容嗅探得到的 MIME 类型，extensions 为允许的扩展名（含点号）
+func Register(name string, l Loader, mimeTypes, extensions []string) {
+	registry = append(registry, &registration{
+		name:       name,
+		loader:     l,
+		mimeTypes:  mimeTypes,
+		extensions: extensions,
+	})
+}
+
+func init() {
+	mimetype.SetLimit(SniffLimit)
+
+	Register("text", textLoader{}, []string{"text/plain"}, []string{".txt", ".md", ".markdown"})
+	Register("pdf", pdfLoader{}, []string{"application/pdf"}, []string{".pdf"})
+	Register("docx", docxLoader{}, []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}, []string{".docx"})
+	Register("html", htmlLoader{}, []string{"text/html"}, []string{".html", ".htm"})
+	Register("csv", csvLoader{}, []string{"text/csv", "text/plain"}, []string{".csv"})
+}
+
+// SupportedExtensions 返回所有支持的扩展名
+func SupportedExtensions() []string {
+	exts := make([]string, 0)
+	for _, r := range registry {
+		exts = append(exts, r.extensions...)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Detect 根据文件内容嗅探 MIME 类型并选择加载器
+// 扩展名只用于在同一 MIME 类型的多个加载器之间选择，内容与扩展名不符（例如把可执行文件改名为 .pdf）时返回错误
+func Detect(filename string, head []byte) (Loader, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	var byExt *registration
+	for _, r := range registry {
+		for _, e := range r.extensions {
+			if e == ext {
+				byExt = r
+			}
+		}
+	}
+	if byExt == nil {
+		return nil, fmt.Errorf("不支持的文件类型 %s，支持的扩展名: %s", ext, strings.Join(SupportedExtensions(), ", "))
+	}
+
+	detected := mimetype.Detect(head)
+	// 沿着 MIME 类型的继承关系匹配，例如 text/csv -> text/plain
+	for m := detected; m != nil; m = m.Parent() {
+		for _, mt := range byExt.mimeTypes {
+			if m.Is(mt) {
+				return byExt.loader, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("文件内容（%s）与扩展名 %s 不匹配", detected.String(), ext)
+}
+
+// LoadFile 读取并解析文件
+func LoadFile(ctx context.Context, path string) ([]*schema.Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	l, err := Detect(path, data)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := load(ctx, l, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return docs, nil
+}
+
+// load 解析器都是手写的，畸形文件触发的 panic 转为错误，不影响进程中的其他任务
+func load(ctx context.Context, l Loader, data []byte) (docs []*schema.Document, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			docs, err = nil, fmt.Errorf("malformed file: %v", r)
+		}
+	}()
+	return l.Load(ctx, data)
+}
+
+// newDocument 创建文档，空白内容返回 nil
+func newDocument(content string, meta map[string]any) *schema.Document {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil
+	}
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	return &schema.Document{Content: content, MetaData: meta}
+}
+
+// appendDocument 追加非空文档
+func appendDocument(docs []*schema.Document, content string, meta map[string]any) []*schema.Document {
+	if doc := newDocument(content, meta); doc != nil {
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// sectionPath 将标题路径拼接为 section 元数据
+func sectionPath(headings []string) string {
+	return strings.Join(headings, " > ")
+}
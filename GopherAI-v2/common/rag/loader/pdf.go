@@ -0,0 +1,480 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// pdfLoader 按页提取 PDF 文本，每页一个文档，MetaData 中记录页码
+// 扫描件（只有图片没有文字层）无法提取文本，会返回错误
+type pdfLoader struct{}
+
+func (pdfLoader) Load(ctx context.Context, data []byte) ([]*schema.Document, error) {
+	f, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	pages := f.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found in pdf")
+	}
+
+	docs := make([]*schema.Document, 0, len(pages))
+	for i, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		docs = appendDocument(docs, f.pageText(page), map[string]any{MetaPage: i + 1})
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no extractable text in pdf (scanned document?)")
+	}
+	return docs, nil
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages 按页面树顺序返回所有页面，Resources 可以从父节点继承
+func (f *pdfFile) pages() []pdfPage {
+	var root any
+	for _, obj := range f.objects {
+		if dict := f.dictOf(obj); dict != nil && dict["Type"] == pdfName("Catalog") {
+			root = dict["Pages"]
+			break
+		}
+	}
+
+	pages := make([]pdfPage, 0)
+	visited := make(map[int]bool)
+	var walk func(node any, resources pdfDict, depth int)
+	walk = func(node any, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict := f.dictOf(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+		if r := f.dictOf(dict["Resources"]); r != nil {
+			resources = r
+		}
+		if kids, ok := f.resolve(dict["Kids"]).([]any); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: dict, resources: resources})
+	}
+	if root != nil {
+		walk(root, nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// 没有找到页面树（文件损坏），按对象编号顺序收集所有页面
+	nums := make([]int, 0)
+	for num, obj := range f.objects {
+		if dict := f.dictOf(obj); dict != nil && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		dict := f.dictOf(f.objects[num])
+		pages = append(pages, pdfPage{dict: dict, resources: f.dictOf(dict["Resources"])})
+	}
+	return pages
+}
+
+// pageText 提取一页的文本
+func (f *pdfFile) pageText(page pdfPage) string {
+	var content bytes.Buffer
+	switch c := f.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		if data, err := f.decodeStream(c); err == nil {
+			content.Write(data)
+		}
+	case []any:
+		// 多个内容流按顺序拼接
+		for _, item := range c {
+			if s, ok := f.resolve(item).(*pdfStream); ok {
+				if data, err := f.decodeStream(s); err == nil {
+					content.Write(data)
+					content.WriteByte('\n')
+				}
+			}
+		}
+	}
+
+	w := &pdfTextWriter{}
+	f.interpret(content.Bytes(), page.resources, w, make(map[any]*pdfFont), 0)
+	return w.String()
+}
+
+// pdfTextWriter 根据文本位置的变化插入换行和空格
+type pdfTextWriter struct {
+	sb    strings.Builder
+	lastY float64
+	hasY  bool
+}
+
+func (w *pdfTextWriter) write(s string) { w.sb.WriteString(s) }
+
+func (w *pdfTextWriter) newline() {
+	if w.sb.Len() > 0 && !strings.HasSuffix(w.sb.String(), "\n") {
+		w.sb.WriteByte('\n')
+	}
+}
+
+func (w *pdfTextWriter) space() {
+	s := w.sb.String()
+	if s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.sb.WriteByte(' ')
+	}
+}
+
+// moveTo 文本移动到新的纵坐标时换行
+func (w *pdfTextWriter) moveTo(y float64) {
+	if w.hasY && math.Abs(y-w.lastY) > 1 {
+		w.newline()
+	}
+	w.lastY, w.hasY = y, true
+}
+
+func (w *pdfTextWriter) String() string { return w.sb.String() }
+
+// interpret 解释内容流中的文本操作符
+func (f *pdfFile) interpret(content []byte, resources pdfDict, w *pdfTextWriter, fonts map[any]*pdfFont, depth int) {
+	fontDict := f.dictOf(resources["Font"])
+	xobjects := f.dictOf(resources["XObject"])
+
+	var font *pdfFont
+	show := func(s any) {
+		if str, ok := s.(pdfString); ok {
+			if font == nil {
+				font = &pdfFont{codeLen: 1}
+			}
+			w.write(font.decode(str))
+		}
+	}
+
+	l := &pdfLexer{data: content}
+	operands := make([]any, 0)
+	for {
+		tok := l.token()
+		if tok == nil && l.pos >= len(content) {
+			break
+		}
+		kw, isKeyword := tok.(pdfKeyword)
+		if !isKeyword || kw == "[" || kw == "<<" {
+			operands = append(operands, l.finish(tok))
+			continue
+		}
+
+		switch kw {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = f.font(fontDict[name], fonts)
+				}
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				arr, _ := operands[len(operands)-1].([]any)
+				for _, item := range arr {
+					if n, ok := item.(float64); ok {
+						// 较大的字距调整通常表示单词间隔
+						if n < -200 {
+							w.space()
+						}
+						continue
+					}
+					show(item)
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[1].(float64); ty != 0 {
+					w.newline()
+				} else if tx, _ := operands[0].(float64); tx > 0 {
+					w.space()
+				}
+			}
+		case "T*":
+			w.newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, ok := operands[5].(float64); ok {
+					w.moveTo(y)
+				}
+			}
+		case "Do":
+			// 表单 XObject 中也可能包含文字
+			if len(operands) > 0 && depth < 8 {
+				if name, ok := operands[0].(pdfName); ok {
+					if s, ok := f.resolve(xobjects[name]).(*pdfStream); ok && s.dict["Subtype"] == pdfName("Form") {
+						if data, err := f.decodeStream(s); err == nil {
+							formResources := f.dictOf(s.dict["Resources"])
+							if formResources == nil {
+								formResources = resources
+							}
+							f.interpret(data, formResources, w, fonts, depth+1)
+						}
+					}
+				}
+			}
+		case "ID":
+			// 内联图片：跳过 ID 和 EI 之间的二进制数据
+			if end := bytes.Index(content[l.pos:], []byte("EI")); end >= 0 {
+				l.pos += end + 2
+			} else {
+				l.pos = len(content)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// ======================= 字体编码 =======================
+
+type pdfFont struct {
+	codeLen   int               // 每个字符编码的字节数
+	toUnicode map[uint32]string // ToUnicode 映射
+	ucs2      bool              // 编码本身就是 UCS-2（如 UniGB-UCS2-H）
+	simple    map[byte]string   // 简单字体 Differences 中可识别的字形
+}
+
+// font 加载字体的编码信息，同一字体对象只解析一次
+func (f *pdfFile) font(obj any, cache map[any]*pdfFont) *pdfFont {
+	key := obj
+	if ref, ok := obj.(pdfRef); ok {
+		key = ref.num
+	}
+	if _, ok := key.(pdfDict); ok {
+		key = nil
+	}
+	if key != nil {
+		if font, ok := cache[key]; ok {
+			return font
+		}
+	}
+
+	font := &pdfFont{codeLen: 1}
+	dict := f.dictOf(obj)
+	if dict != nil {
+		if dict["Subtype"] == pdfName("Type0") {
+			font.codeLen = 2
+			if enc, ok := f.resolve(dict["Encoding"]).(pdfName); ok && (strings.Contains(string(enc), "UCS2") || strings.Contains(string(enc), "UTF16")) {
+				font.ucs2 = true
+			}
+		} else if enc := f.dictOf(dict["Encoding"]); enc != nil {
+			font.simple = f.differences(enc)
+		}
+		if s, ok := f.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+			if data, err := f.decodeStream(s); err == nil {
+				m, codeLen := parseCMap(data)
+				if len(m) > 0 {
+					font.toUnicode = m
+					if codeLen > 0 {
+						font.codeLen = codeLen
+					}
+				}
+			}
+		}
+	}
+	if key != nil {
+		cache[key] = font
+	}
+	return font
+}
+
+// differences 解析简单字体的 Differences 数组，只识别 uniXXXX 和单字符字形名
+func (f *pdfFile) differences(enc pdfDict) map[byte]string {
+	arr, ok := f.resolve(enc["Differences"]).([]any)
+	if !ok {
+		return nil
+	}
+	m := make(map[byte]string)
+	code := 0
+	for _, item := range arr {
+		switch v := item.(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			if glyph := glyphToString(string(v)); glyph != "" && code < 256 {
+				m[byte(code)] = glyph
+			}
+			code++
+		}
+	}
+	return m
+}
+
+var glyphNames = map[string]string{
+	"space": " ", "period": ".", "comma": ",", "hyphen": "-", "colon": ":", "semicolon": ";",
+	"parenleft": "(", "parenright": ")", "quotesingle": "'", "quotedbl": "\"", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+func glyphToString(name string) string {
+	if len(name) == 1 {
+		return name
+	}
+	if s, ok := glyphNames[name]; ok {
+		return s
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if v, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+func (font *pdfFont) decode(s pdfString) string {
+	var sb strings.Builder
+	for i := 0; i+font.codeLen <= len(s); i += font.codeLen {
+		var code uint32
+		for _, b := range s[i : i+font.codeLen] {
+			code = code<<8 | uint32(b)
+		}
+		if text, ok := font.toUnicode[code]; ok {
+			sb.WriteString(text)
+			continue
+		}
+		switch {
+		case font.codeLen == 1:
+			if text, ok := font.simple[byte(code)]; ok {
+				sb.WriteString(text)
+			} else if code >= 32 {
+				// 没有映射时按 Latin-1 处理，与 WinAnsi / PDFDocEncoding 的可见 ASCII 部分一致
+				sb.WriteRune(rune(code))
+			}
+		case font.ucs2:
+			sb.WriteRune(rune(code))
+		}
+		// 其他情况（复合字体且没有 ToUnicode）无法还原字符，直接跳过
+	}
+	return sb.String()
+}
+
+// parseCMap 解析 ToUnicode CMap，返回 编码 -> 文本 映射以及编码字节数
+func parseCMap(data []byte) (map[uint32]string, int) {
+	m := make(map[uint32]string)
+	codeLen := 0
+	l := &pdfLexer{data: data}
+	stack := make([]any, 0)
+
+	for {
+		tok := l.token()
+		if tok == nil && l.pos >= len(data) {
+			break
+		}
+		kw, ok := tok.(pdfKeyword)
+		if !ok || kw == "[" {
+			stack = append(stack, l.finish(tok))
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			if len(stack) > 0 && codeLen == 0 {
+				if lo, ok := stack[0].(pdfString); ok {
+					codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(stack); i += 2 {
+				src, ok1 := stack[i].(pdfString)
+				dst, ok2 := stack[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[codeOf(src)] = utf16BE(dst)
+					if codeLen == 0 {
+						codeLen = len(src)
+					}
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(stack); i += 3 {
+				lo, ok1 := stack[i].(pdfString)
+				hi, ok2 := stack[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				if codeLen == 0 {
+					codeLen = len(lo)
+				}
+				start, end := codeOf(lo), codeOf(hi)
+				if end < start || end-start > 0xFFFF {
+					continue
+				}
+				switch dst := stack[i+2].(type) {
+				case pdfString:
+					// 范围内的编码依次映射到连续的字符
+					for c := start; c <= end; c++ {
+						next := append(pdfString{}, dst...)
+						if n := len(next); n >= 2 {
+							last := uint32(next[n-2])<<8 | uint32(next[n-1])
+							last += c - start
+							next[n-2], next[n-1] = byte(last>>8), byte(last)
+						}
+						m[c] = utf16BE(next)
+					}
+				case []any:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+uint32(j) <= end {
+							m[start+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		stack = stack[:0]
+	}
+	return m, codeLen
+}
+
+func codeOf(s pdfString) uint32 {
+	var code uint32
+	for _, b := range s {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+func utf16BE(s pdfString) string {
+	if len(s)%2 == 1 {
+		return string(rune(s[len(s)-1]))
+	}
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,469 @@
+package loader
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// 一个只用于提取文本的精简 PDF 解析器：
+// 不依赖交叉引用表，直接扫描文件中的 "n g obj"（兼容交叉引用表损坏的文件），
+// 支持对象流（ObjStm）、FlateDecode / ASCIIHex / ASCII85 压缩和 ToUnicode 字体映射
+
+type (
+	pdfName   string
+	pdfString []byte
+	pdfRef    struct{ num, gen int }
+	pdfDict   map[pdfName]any
+	pdfStream struct {
+		dict pdfDict
+		raw  []byte
+	}
+	// pdfKeyword 内容流中的操作符以及 obj、R 等关键字
+	pdfKeyword string
+)
+
+// ======================= 词法分析 =======================
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// token 读取下一个词法单元，返回 nil 表示结束
+// 数组和字典的起止符号以 pdfKeyword 返回（"[" "]" "<<" ">>"）
+func (l *pdfLexer) token() any {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.readName()
+	case c == '(':
+		return l.readLiteralString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<")
+		}
+		return l.readHexString()
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>")
+		}
+		l.pos++
+		return pdfKeyword(">")
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(string(c))
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if word == "" {
+		// 无法识别的字符，跳过
+		l.pos++
+		return pdfKeyword("")
+	}
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '-' || word[0] == '+' || word[0] == '.' || (word[0] >= '0' && word[0] <= '9')) {
+		return n
+	}
+	switch word {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	return pdfKeyword(word)
+}
+
+func (l *pdfLexer) readName() pdfName {
+	l.pos++ // '/'
+	var buf []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if b, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				buf = append(buf, b[0])
+				l.pos += 3
+				continue
+			}
+		}
+		buf = append(buf, c)
+		l.pos++
+	}
+	return pdfName(buf)
+}
+
+func (l *pdfLexer) readLiteralString() pdfString {
+	l.pos++ // '('
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return buf
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// 续行
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					// 八进制转义，最多 3 位
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+func (l *pdfLexer) readHexString() pdfString {
+	l.pos++ // '<'
+	digits := make([]byte, 0)
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, _ := hex.DecodeString(string(digits))
+	return b
+}
+
+// ======================= 语法分析 =======================
+
+// object 读取一个完整的对象（数组、字典会递归读取），间接引用 "n g R" 解析为 pdfRef
+func (l *pdfLexer) object() any {
+	return l.finish(l.token())
+}
+
+func (l *pdfLexer) finish(tok any) any {
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			arr := make([]any, 0)
+			for {
+				next := l.token()
+				if next == nil && l.pos >= len(l.data) {
+					return arr
+				}
+				if k, ok := next.(pdfKeyword); ok && k == "]" {
+					return arr
+				}
+				arr = append(arr, l.finish(next))
+			}
+		case "<<":
+			dict := make(pdfDict)
+			for {
+				next := l.token()
+				if next == nil && l.pos >= len(l.data) {
+					return dict
+				}
+				if k, ok := next.(pdfKeyword); ok && k == ">>" {
+					return dict
+				}
+				key, ok := next.(pdfName)
+				if !ok {
+					continue
+				}
+				dict[key] = l.object()
+			}
+		}
+		return t
+	case float64:
+		// 尝试识别间接引用 "n g R"
+		save := l.pos
+		gen, ok := l.token().(float64)
+		if ok {
+			if k, ok := l.token().(pdfKeyword); ok && k == "R" {
+				return pdfRef{num: int(t), gen: int(gen)}
+			}
+		}
+		l.pos = save
+		return t
+	}
+	return tok
+}
+
+// ======================= 文档对象 =======================
+
+type pdfFile struct {
+	objects map[int]any
+}
+
+var objHeaderRegex = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// parsePDF 扫描文件中的所有间接对象
+func parsePDF(data []byte) (*pdfFile, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return nil, fmt.Errorf("not a pdf file")
+	}
+	f := &pdfFile{objects: make(map[int]any)}
+
+	skipUntil := 0
+	for _, m := range objHeaderRegex.FindAllSubmatchIndex(data, -1) {
+		// 落在上一个流数据内部的匹配是二进制数据的巧合，忽略
+		if m[0] < skipUntil {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &pdfLexer{data: data, pos: m[1]}
+		obj := l.object()
+
+		// 字典后面紧跟 stream 关键字时读取流数据
+		if dict, ok := obj.(pdfDict); ok {
+			save := l.pos
+			if k, ok := l.token().(pdfKeyword); ok && k == "stream" {
+				raw, end, err := readStreamData(data, l.pos, dict)
+				if err != nil {
+					return nil, fmt.Errorf("object %d: %w", num, err)
+				}
+				obj = &pdfStream{dict: dict, raw: raw}
+				skipUntil = end
+			} else {
+				l.pos = save
+			}
+		}
+		// 文件增量更新时同一个对象会出现多次，以最后出现的为准
+		f.objects[num] = obj
+	}
+
+	if len(f.objects) == 0 {
+		return nil, fmt.Errorf("no objects found")
+	}
+	for _, obj := range f.objects {
+		if dict := f.dictOf(obj); dict != nil && dict["Encrypt"] != nil {
+			return nil, fmt.Errorf("encrypted pdf is not supported")
+		}
+	}
+	f.expandObjectStreams()
+	return f, nil
+}
+
+// readStreamData 读取 stream 和 endstream 之间的数据，同时返回数据结束的位置
+// Length 为负数或超出文件末尾时返回错误
+func readStreamData(data []byte, pos int, dict pdfDict) ([]byte, int, error) {
+	// stream 关键字后是 CRLF 或 LF
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+	// 优先使用 Length（直接数值时），校验后面确实是 endstream
+	if n, ok := dict["Length"].(float64); ok {
+		// 先用浮点数比较，避免超大的 Length 转为 int 时溢出
+		if n < 0 || n > float64(len(data)-pos) {
+			return nil, 0, fmt.Errorf("invalid stream length %v", n)
+		}
+		end := pos + int(n)
+		if end < pos || end > len(data) {
+			return nil, 0, fmt.Errorf("invalid stream length %v", n)
+		}
+		if bytes.HasPrefix(bytes.TrimLeft(data[end:], "\r\n \t"), []byte("endstream")) {
+			return data[pos:end], end, nil
+		}
+	}
+	end := bytes.Index(data[pos:], []byte("endstream"))
+	if end < 0 {
+		return data[pos:], len(data), nil
+	}
+	return bytes.TrimRight(data[pos:pos+end], "\r\n"), pos + end, nil
+}
+
+// expandObjectStreams 展开对象流中压缩存储的对象
+func (f *pdfFile) expandObjectStreams() {
+	for _, obj := range f.objects {
+		s, ok := obj.(*pdfStream)
+		if !ok || s.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := f.decodeStream(s)
+		if err != nil {
+			continue
+		}
+		n, _ := f.resolve(s.dict["N"]).(float64)
+		first, _ := f.resolve(s.dict["First"]).(float64)
+
+		header := &pdfLexer{data: data}
+		for i := 0; i < int(n); i++ {
+			num, ok1 := header.token().(float64)
+			offset, ok2 := header.token().(float64)
+			if !ok1 || !ok2 {
+				break
+			}
+			pos := int(first) + int(offset)
+			if pos >= len(data) {
+				continue
+			}
+			if _, exists := f.objects[int(num)]; exists {
+				continue
+			}
+			l := &pdfLexer{data: data, pos: pos}
+			f.objects[int(num)] = l.object()
+		}
+	}
+}
+
+// resolve 解析间接引用
+func (f *pdfFile) resolve(obj any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.objects[ref.num]
+	}
+	return nil
+}
+
+// dictOf 获取对象的字典（流对象返回其字典）
+func (f *pdfFile) dictOf(obj any) pdfDict {
+	switch o := f.resolve(obj).(type) {
+	case pdfDict:
+		return o
+	case *pdfStream:
+		return o.dict
+	}
+	return nil
+}
+
+// decodeStream 按 Filter 解码流数据
+func (f *pdfFile) decodeStream(s *pdfStream) ([]byte, error) {
+	data := s.raw
+	var filters []any
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{v}
+	case []any:
+		filters = v
+	}
+	for _, filter := range filters {
+		name, _ := f.resolve(filter).(pdfName)
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			data = (&pdfLexer{data: append(append([]byte{'<'}, data...), '>')}).readHexString()
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, MaxDecodedSize+1))
+	if len(out) > MaxDecodedSize {
+		return nil, fmt.Errorf("decoded stream exceeds %d bytes", MaxDecodedSize)
+	}
+	// 部分文件的压缩流末尾不完整，已解出的内容仍然可用
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
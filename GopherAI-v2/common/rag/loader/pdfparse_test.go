@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// buildPDF 生成只有一页的最小 PDF，content 为页面内容流，length 为写入字典的 Length
+func buildPDF(content []byte, length string, filter string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	b.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	b.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&b, "4 0 obj << /Length %s%s >>\nstream\n", length, filter)
+	b.Write(content)
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
+
+func TestPDFLoader(t *testing.T) {
+	content := []byte("BT /F1 12 Tf (Hello PDF) Tj ET")
+	compressed := deflate(content)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{name: "plain", data: buildPDF(content, fmt.Sprint(len(content)), ""), want: "Hello PDF"},
+		{name: "flate", data: buildPDF(compressed, fmt.Sprint(len(compressed)), " /Filter /FlateDecode"), want: "Hello PDF"},
+		{name: "length mismatch falls back to endstream", data: buildPDF(content, "5", ""), want: "Hello PDF"},
+		{name: "indirect length", data: buildPDF(content, "9 0 R", ""), want: "Hello PDF"},
+		{name: "negative length", data: buildPDF(content, "-100", ""), wantErr: "invalid stream length"},
+		{name: "length past end of file", data: buildPDF(content, "100000", ""), wantErr: "invalid stream length"},
+		{name: "huge length", data: buildPDF(content, "1e300", ""), wantErr: "invalid stream length"},
+		{name: "not a pdf", data: []byte("hello"), wantErr: "not a pdf"},
+		{name: "no objects", data: []byte("%PDF-1.4\n%%EOF"), wantErr: "no objects"},
+		{name: "truncated", data: buildPDF(content, fmt.Sprint(len(content)), "")[:120], wantErr: "no pages found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := pdfLoader{}.Load(context.Background(), tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(docs) != 1 || !strings.Contains(docs[0].Content, tt.want) {
+				t.Fatalf("docs = %+v, want %q", docs, tt.want)
+			}
+			if docs[0].MetaData[MetaPage] != 1 {
+				t.Errorf("page = %v, want 1", docs[0].MetaData[MetaPage])
+			}
+		})
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	bomb := deflate(make([]byte, MaxDecodedSize+1))
+	if _, err := inflate(bomb); err == nil {
+		t.Fatal("expected error for oversized stream")
+	}
+}
+
+// FuzzPDFLoader 任意输入都不能让解析器 panic
+func FuzzPDFLoader(f *testing.F) {
+	content := []byte("BT (Hello) Tj ET")
+	f.Add(buildPDF(content, fmt.Sprint(len(content)), ""))
+	f.Add(buildPDF(deflate(content), "-1", " /Filter /FlateDecode"))
+	f.Add(buildPDF(content, "99999999999999999999", ""))
+	f.Add([]byte("%PDF-1.7\n1 0 obj << /Type /ObjStm /N 3 /First 1 /Length 3 >> stream\nabc\nendstream endobj"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pdfLoader{}.Load(context.Background(), data)
+	})
+}
+
+type panicLoader struct{}
+
+func (panicLoader) Load(ctx context.Context, data []byte) ([]*schema.Document, error) {
+	var s []byte
+	return nil, fmt.Errorf("unreachable %d", s[len(data)])
+}
+
+func TestLoadRecoversPanic(t *testing.T) {
+	if _, err := load(context.Background(), panicLoader{}, []byte("x")); err == nil || !strings.Contains(err.Error(), "malformed file") {
+		t.Fatalf("err = %v, want malformed file", err)
+	}
+}
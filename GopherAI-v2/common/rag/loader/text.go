@@ -0,0 +1,14 @@
+package loader
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// textLoader 纯文本和 Markdown，整个文件作为一个文档，Markdown 标题由切分器处理
+type textLoader struct{}
+
+func (textLoader) Load(ctx context.Context, data []byte) ([]*schema.Document, error) {
+	return appendDocument(nil, decodeText(data), nil), nil
+}
@@ -0,0 +1,173 @@
+package rag
+
+import (
+	redisPkg "GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// MigrateKnowledgeBaseIndex 使用新的参数重建知识库索引，重建期间查询不受影响
+// opts 中未设置的字段沿用知识库当前的参数。流程：
+//  1. 向量类型变化时，先把已有向量转换为新类型写入新的向量字段（迁移期间新写入的块同时写入新旧字段）
+//  2. 在同一 key 前缀上创建新版本的索引，等待 RediSearch 在后台完成对已有数据的索引
+//  3. 将别名切换到新索引并删除旧索引，查询始终通过别名访问，切换是原子的
+func MigrateKnowledgeBaseIndex(ctx context.Context, knowledgeBaseID uint, opts redisPkg.VectorIndexOptions) error {
+	if err := requireRedisStore(); err != nil {
+		return err
+	}
+	kb, err := knowledgeDao.GetKnowledgeBaseByID(knowledgeBaseID)
+	if err != nil {
+		return fmt.Errorf("failed to get knowledge base %d: %w", knowledgeBaseID, err)
+	}
+	indexName := KnowledgeBaseIndex(kb.UserName, kb.ID)
+	meta, err := redisPkg.GetIndexMeta(ctx, indexName)
+	if err != nil {
+		return fmt.Errorf("failed to get index meta: %w", err)
+	}
+
+	current := IndexOptions(kb.VectorIndex)
+	if meta != nil {
+		current = meta.Options.Normalize()
+	}
+	target := mergeIndexOptions(current, opts).Normalize()
+	if err := target.Validate(); err != nil {
+		return err
+	}
+
+	// 索引还没有创建，只需要更新知识库的参数，下次入库时按新参数创建
+	if meta == nil {
+		log.Printf("[rag] %s has no index yet, only update settings", indexName)
+		return knowledgeDao.UpdateKnowledgeBaseIndex(knowledgeBaseID, IndexSettings(target))
+	}
+	if target == current {
+		log.Printf("[rag] %s already uses the requested index options", indexName)
+		return knowledgeDao.UpdateKnowledgeBaseIndex(knowledgeBaseID, IndexSettings(target))
+	}
+
+	// 1. 转换向量类型
+	oldType := current.VectorType
+	typeChanged := target.VectorType != oldType
+	if typeChanged {
+		meta.MigratingVectorType = target.VectorType
+		if err := redisPkg.SetIndexMeta(ctx, indexName, meta); err != nil {
+			return fmt.Errorf("failed to save index meta: %w", err)
+		}
+		converted, err := convertVectors(ctx, indexName, oldType, target.VectorType)
+		if err != nil {
+			return err
+		}
+		log.Printf("[rag] %s: converted %d vectors from %s to %s", indexName, converted, oldType, target.VectorType)
+	}
+
+	// 2. 创建新版本的索引
+	newIndex := redisPkg.GenerateVersionedIndexName(indexName, meta.Version+1)
+	if err := redisPkg.CreateVectorIndex(ctx, indexName, newIndex, meta.Dimension, target); err != nil {
+		return err
+	}
+	log.Printf("[rag] %s: building %s (%s %s %s)", indexName, newIndex, target.Algorithm, target.Metric, target.VectorType)
+	if err := redisPkg.WaitIndexReady(ctx, newIndex); err != nil {
+		return err
+	}
+
+	// 3. 先保存新的元信息再切换别名：向量检索按元信息中的实际索引名和向量类型查询，
+	// 元信息更新后检索直接使用新索引，不会出现用旧的向量字段查询新索引的情况
+	oldMeta := *meta
+	meta.Index = newIndex
+	meta.Version++
+	meta.Options = target
+	meta.MigratingVectorType = ""
+	if err := redisPkg.SetIndexMeta(ctx, indexName, meta); err != nil {
+		return fmt.Errorf("failed to save index meta: %w", err)
+	}
+	if err := redisPkg.SwapIndexAlias(ctx, indexName, oldMeta.Index, newIndex); err != nil {
+		if err := redisPkg.SetIndexMeta(ctx, indexName, &oldMeta); err != nil {
+			log.Printf("[rag] %s: restore index meta failed: %v", indexName, err)
+		}
+		return err
+	}
+	if err := knowledgeDao.UpdateKnowledgeBaseIndex(knowledgeBaseID, IndexSettings(target)); err != nil {
+		return fmt.Errorf("failed to update knowledge base settings: %w", err)
+	}
+
+	// 旧的向量字段已不再被任何索引使用
+	if typeChanged {
+		if err := removeVectorField(ctx, indexName, oldType); err != nil {
+			log.Printf("[rag] %s: remove %s vectors failed: %v", indexName, oldType, err)
+		}
+	}
+	log.Printf("[rag] %s now served by %s", indexName, newIndex)
+	return nil
+}
+
+// requireRedisStore 索引迁移直接操作 RediSearch 索引和 key，只适用于 Redis 向量存储
+func requireRedisStore() error {
+	if _, ok := vectorstore.Default().(*vectorstore.RedisStore); !ok {
+		return errors.New("index migration is only supported by the redis vector store")
+	}
+	return nil
+}
+
+// mergeIndexOptions override 中设置了的字段覆盖 base
+func mergeIndexOptions(base, override redisPkg.VectorIndexOptions) redisPkg.VectorIndexOptions {
+	if override.Algorithm != "" {
+		base.Algorithm = override.Algorithm
+	}
+	if override.Metric != "" {
+		base.Metric = override.Metric
+	}
+	if override.VectorType != "" {
+		base.VectorType = override.VectorType
+	}
+	if override.M > 0 {
+		base.M = override.M
+	}
+	if override.EfConstruction > 0 {
+		base.EfConstruction = override.EfConstruction
+	}
+	if override.EfRuntime > 0 {
+		base.EfRuntime = override.EfRuntime
+	}
+	return base
+}
+
+// convertVectors 将知识库中所有块的向量从 fromType 转换为 toType，写入 toType 对应的字段
+func convertVectors(ctx context.Context, indexName, fromType, toType string) (int, error) {
+	keys, err := redisPkg.ScanKeys(ctx, redisPkg.GenerateIndexNamePrefix(indexName)+"*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan chunks: %w", err)
+	}
+	fromField, toField := redisPkg.VectorField(fromType), redisPkg.VectorField(toType)
+	converted := 0
+	for _, key := range keys {
+		data, err := redisPkg.Rdb.HGet(ctx, key, fromField).Bytes()
+		if err != nil {
+			// 没有旧向量字段（例如迁移开始后新写入的块已经带有新字段）
+			continue
+		}
+		vector := redisPkg.DecodeVector(data, fromType)
+		if err := redisPkg.Rdb.HSet(ctx, key, toField, redisPkg.EncodeVector(vector, toType)).Err(); err != nil {
+			return converted, fmt.Errorf("failed to convert vector of %s: %w", key, err)
+		}
+		converted++
+	}
+	return converted, nil
+}
+
+// removeVectorField 删除知识库中所有块的指定类型向量字段
+func removeVectorField(ctx context.Context, indexName, vectorType string) error {
+	keys, err := redisPkg.ScanKeys(ctx, redisPkg.GenerateIndexNamePrefix(indexName)+"*")
+	if err != nil {
+		return err
+	}
+	field := redisPkg.VectorField(vectorType)
+	pipe := redisPkg.Rdb.Pipeline()
+	for _, key := range keys {
+		pipe.HDel(ctx, key, field)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,100 @@
+package rag
+
+import (
+	redisPkg "GopherAI/common/redis"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LegacyKnowledgeBaseIndex 按用户隔离之前的索引标识，迁移前仍可能存在
+func LegacyKnowledgeBaseIndex(knowledgeBaseID uint) string {
+	return fmt.Sprintf("kb_%d", knowledgeBaseID)
+}
+
+// MigrateLegacyIndexes 将按用户隔离之前创建的索引迁移到带所有者的命名空间，返回迁移的知识库数量
+// 每个知识库：在新前缀上创建同样参数的索引和别名，将块 RENAME 到新前缀（新索引随之收录），
+// 再删除旧索引（保留数据）和旧元信息，并在 MySQL 中记录索引的所有者
+// dryRun 为 true 时只打印将要执行的操作
+func MigrateLegacyIndexes(ctx context.Context, dryRun bool) (int, error) {
+	if err := requireRedisStore(); err != nil {
+		return 0, err
+	}
+	kbs, err := knowledgeDao.GetAllKnowledgeBases()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list knowledge bases: %w", err)
+	}
+	migrated := 0
+	for i := range kbs {
+		ok, err := migrateLegacyIndex(ctx, &kbs[i], dryRun)
+		if err != nil {
+			return migrated, fmt.Errorf("knowledge base %d: %w", kbs[i].ID, err)
+		}
+		if ok {
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+func migrateLegacyIndex(ctx context.Context, kb *model.KnowledgeBase, dryRun bool) (bool, error) {
+	legacy := LegacyKnowledgeBaseIndex(kb.ID)
+	indexName := KnowledgeBaseIndex(kb.UserName, kb.ID)
+
+	meta, err := redisPkg.GetIndexMeta(ctx, legacy)
+	if err != nil {
+		return false, fmt.Errorf("failed to get index meta: %w", err)
+	}
+	legacyPrefix := redisPkg.GenerateIndexNamePrefix(legacy)
+	keys, err := redisPkg.ScanKeys(ctx, legacyPrefix+"*")
+	if err != nil {
+		return false, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	if meta == nil {
+		// 没有元信息的块无法确定向量模型，留给孤儿数据清理处理
+		if len(keys) > 0 {
+			log.Printf("[rag] %s has %d keys but no index meta, skipped", legacy, len(keys))
+		}
+		return false, nil
+	}
+
+	log.Printf("[rag] migrate %s -> %s (%d keys, owner %s)", legacy, indexName, len(keys), kb.UserName)
+	if dryRun {
+		return true, nil
+	}
+
+	// 1. 在新前缀上创建同样参数的索引
+	newMeta := *meta
+	newMeta.Version = max(meta.Version, 1)
+	newMeta.Index = redisPkg.GenerateVersionedIndexName(indexName, newMeta.Version)
+	newMeta.MigratingVectorType = ""
+	if err := redisPkg.SetIndexMeta(ctx, indexName, &newMeta); err != nil {
+		return false, fmt.Errorf("failed to save index meta: %w", err)
+	}
+	if err := redisPkg.InitRedisIndex(ctx, indexName, &newMeta); err != nil {
+		return false, err
+	}
+
+	// 2. 块移动到新前缀
+	for _, key := range keys {
+		chunkID := strings.TrimPrefix(strings.TrimPrefix(key, legacyPrefix), legacy+":")
+		newKey := redisPkg.GenerateChunkKey(indexName, chunkID)
+		if err := redisPkg.Rdb.Rename(ctx, key, newKey).Err(); err != nil {
+			return false, fmt.Errorf("failed to rename %s: %w", key, err)
+		}
+	}
+
+	// 3. 删除旧索引和旧元信息，数据已经移走
+	if err := redisPkg.DeleteRedisIndex(ctx, legacy); err != nil {
+		log.Printf("[rag] drop legacy index %s failed: %v", legacy, err)
+	}
+
+	// 4. 记录所有者
+	if _, err := claimIndex(kb.UserName, kb.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
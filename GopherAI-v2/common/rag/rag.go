@@ -1,273 +1,392 @@
 package rag
 
 import (
-	"GopherAI/common/redis"
+	"GopherAI/common/embedder"
+	"GopherAI/common/prompt"
+	"GopherAI/common/rag/loader"
+	"GopherAI/common/rag/splitter"
 	redisPkg "GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
 	"GopherAI/config"
+	knowledgeDao "GopherAI/dao/knowledge"
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"strings"
 
-	embeddingArk "github.com/cloudwego/eino-ext/components/embedding/ark"
-	redisIndexer "github.com/cloudwego/eino-ext/components/indexer/redis"
-	redisRetriever "github.com/cloudwego/eino-ext/components/retriever/redis"
 	"github.com/cloudwego/eino/components/embedding"
-	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/schema"
-	redisCli "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 type RAGIndexer struct {
 	embedding embedding.Embedder
-	indexer   *redisIndexer.Indexer
+	store     vectorstore.Store
+	name      string // 索引标识（知识库索引），即向量存储中的集合名
 }
 
 type RAGQuery struct {
 	embedding embedding.Embedder
-	retriever retriever.Retriever
+	store     vectorstore.Store
+	indexName string // 索引标识，即向量存储中的集合名
 }
 
 // 构建知识库索引
 // 专业说法：文本解析、文本切块、向量化、存储向量
 // 通俗理解：把“人能读的文档”，转换成“AI 能按语义搜索的格式”，并存起来
-func NewRAGIndexer(filename, embeddingModel string) (*RAGIndexer, error) {
+// embedderID 为空时使用配置中的默认向量模型，opts 为新建索引时使用的向量索引参数
+func NewRAGIndexer(filename, embedderID string, opts redisPkg.VectorIndexOptions) (*RAGIndexer, error) {
 
 	// 用于控制整个初始化流程（超时 / 取消等），这里先用默认背景即可
 	ctx := context.Background()
 
-	// 从环境变量中读取调用向量模型所需的 API Key
-	apiKey := os.Getenv("OPENAI_API_KEY")
-
-	// 向量的维度大小（等于向量模型输出的数字个数）
-	// Redis 在创建向量索引时必须提前知道这个值
-	dimension := config.GetConfig().RagModelConfig.RagDimension
-
 	// 1. 配置并创建“向量生成器”（Embedding）
 	// 可以理解为：找一个“翻译官”，
 	// 专门负责把文本翻译成 AI 能理解的“向量表示”
-	embedConfig := &embeddingArk.EmbeddingConfig{
-		BaseURL: config.GetConfig().RagModelConfig.RagBaseUrl, // 向量模型服务地址
-		APIKey:  apiKey,                                       // 鉴权信息
-		Model:   embeddingModel,                               // 使用哪个向量模型
-	}
-
-	// 创建向量生成器实例
-	// 后续所有文本的“向量化”都会通过它完成
-	embedder, err := embeddingArk.NewEmbedder(ctx, embedConfig)
+	// 具体使用哪个向量模型（Ark / OpenAI 兼容 / Ollama）由配置决定
+	spec, err := embedder.GetSpec(embedderID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder: %w", err)
+		return nil, err
 	}
-
-	// ===============================
-	// 2. 初始化 Redis 中的向量索引结构
-	// ===============================
-	// 可以理解为：先在 Redis 里建好“仓库”，
-	// 告诉它以后要存向量，并且每个向量的维度是多少
-	if err := redisPkg.InitRedisIndex(ctx, filename, dimension); err != nil {
-		return nil, fmt.Errorf("failed to init redis index: %w", err)
+	embedder_, err := embedder.New(ctx, spec)
+	if err != nil {
+		return nil, err
 	}
 
-	// 获取 Redis 客户端，用于后续数据写入
-	rdb := redisPkg.Rdb
-
-	// ===============================
-	// 3. 配置索引器（定义：文档如何被存进 Redis）
-	// ===============================
-	indexerConfig := &redisIndexer.IndexerConfig{
-		Client:    rdb,                                     // Redis 客户端
-		KeyPrefix: redis.GenerateIndexNamePrefix(filename), // 不同知识库使用不同前缀，避免冲突
-		BatchSize: 10,                                      // 批量处理文档，提高写入效率
-
-		// 定义：一段文档（Document）在 Redis 中该如何存储
-		DocumentToHashes: func(ctx context.Context, doc *schema.Document) (*redisIndexer.Hashes, error) {
-
-			// 从文档的元数据中取出来源信息（例如文件名、URL）
-			source := ""
-			if s, ok := doc.MetaData["source"].(string); ok {
-				source = s
-			}
-
-			// 构造 Redis 中实际存储的数据结构（Hash）
-			return &redisIndexer.Hashes{
-				// Redis Key，一般由“知识库名 + 文档块 ID”组成
-				Key: fmt.Sprintf("%s:%s", filename, doc.ID),
-
-				// Redis Hash 中的字段
-				Field2Value: map[string]redisIndexer.FieldValue{
-					// content：原始文本内容
-					// EmbedKey 表示：该字段需要先做向量化，
-					// 生成的向量会存入名为 "vector" 的字段中
-					"content": {Value: doc.Content, EmbedKey: "vector"},
-
-					// metadata：一些辅助信息，不参与向量计算
-					"metadata": {Value: source},
-				},
-			}, nil
-		},
+	// 向量的维度大小（等于向量模型输出的数字个数）
+	// 创建向量索引时必须提前知道这个值
+	dimension, err := embedder.Dimension(ctx, spec, embedder_)
+	if err != nil {
+		return nil, err
 	}
 
-	// 将“向量生成器”交给索引器
-	// 这样索引器在写入文本时，可以自动完成向量计算
-	indexerConfig.Embedding = embedder
-
 	// ===============================
-	// 4. 创建最终可用的索引器实例
+	// 2. 初始化向量存储中的集合
 	// ===============================
-	// 此时索引器已经具备：
-	// - 文本 → 向量 的能力
-	// - 向量写入 Redis 的能力
-	idx, err := redisIndexer.NewIndexer(ctx, indexerConfig)
+	// 如果索引之前是用其他向量模型构建的，旧向量与新模型不在同一个向量空间，
+	// 相似度没有意义，需要连同文档一起清除后重建；索引是原地重建的，清除后到重建完成前检索只能查到已重新写入的文档
+	// 知识库索引的清除和重建由调用方持有知识库锁（lockKnowledgeBase）
+	store := vectorstore.Default()
+	existing, err := store.GetCollection(ctx, filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create indexer: %w", err)
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	if existing != nil && (existing.EmbedderID != spec.ID || existing.Dimension != dimension) {
+		if err := store.DropCollection(ctx, filename); err != nil {
+			return nil, fmt.Errorf("failed to drop stale index: %w", err)
+		}
+	}
+	// 集合不存在时按 opts 创建，已存在时沿用原来的参数
+	if _, err := store.CreateCollection(ctx, &vectorstore.Collection{
+		Name:       filename,
+		EmbedderID: spec.ID,
+		Dimension:  dimension,
+		Options:    opts,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
 	}
 
 	// 返回一个封装好的 RAGIndexer，
 	// 后续只需要调用它，就可以把文档加入知识库
 	return &RAGIndexer{
-		embedding: embedder,
-		indexer:   idx,
+		embedding: embedder_,
+		store:     store,
+		name:      filename,
 	}, nil
 }
 
-// IndexFile 读取文件内容并创建向量索引
-func (r *RAGIndexer) IndexFile(ctx context.Context, filePath string) error {
-	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+// IndexFile 读取文件内容并创建向量索引，返回切分出的块数量
+func (r *RAGIndexer) IndexFile(ctx context.Context, filePath string) (int, error) {
+	docs, err := LoadChunks(ctx, filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return 0, err
 	}
 
-	// 将文件内容转换为文档
-	// TODO: 这里可以根据需要进行文本切块，目前简单处理为一个文档
-	doc := &schema.Document{
-		ID:      "doc_1", // 可以使用 UUID 或其他唯一标识
-		Content: string(content),
-		MetaData: map[string]any{
-			"source": filePath,
-		},
+	// 使用 indexer 存储文档（会自动进行向量化）
+	if err := r.StoreChunks(ctx, docs); err != nil {
+		return 0, err
 	}
 
-	// 使用 indexer 存储文档（会自动进行向量化）
-	_, err = r.indexer.Store(ctx, []*schema.Document{doc})
+	return len(docs), nil
+}
+
+// LoadChunks 解析文件并切分为文档块，块 ID 由文档标识和块内容的哈希组成（见 splitter.ChunkID），内容不变的块重新入库时 ID 不变
+func LoadChunks(ctx context.Context, filePath string) ([]*schema.Document, error) {
+	// 按文件类型解析为若干文档（PDF 按页、DOCX/HTML 按章节、CSV 按行）
+	loaded, err := loader.LoadFile(ctx, filePath)
 	if err != nil {
-		return fmt.Errorf("failed to store document: %w", err)
+		return nil, err
 	}
 
-	return nil
-}
+	// 将每个文档切分为多个文档块，每块单独向量化
+	textSplitter, err := splitter.FromConfig(filePath)
+	if err != nil {
+		return nil, err
+	}
 
-// DeleteIndex 删除指定文件的知识库索引（静态方法，不依赖实例）
-func DeleteIndex(ctx context.Context, filename string) error {
-	if err := redisPkg.DeleteRedisIndex(ctx, filename); err != nil {
-		return fmt.Errorf("failed to delete redis index: %w", err)
+	// 块 ID 由文件名和块内容哈希组成，文件修改后重新索引时内容未变的块 ID 保持不变
+	docID := DocumentKey(filePath)
+	occurrences := make(map[string]int)
+	docs := make([]*schema.Document, 0)
+	for _, doc := range loaded {
+		for _, chunk := range textSplitter.Split(doc.Content) {
+			hash := splitter.ContentHash(chunk.Content)
+			metadata := map[string]any{
+				"source":       filePath,
+				"heading_path": chunk.HeadingPath,
+				"offset":       chunk.Offset, // 在所属文档（页、章节）中的偏移
+				"chunk_index":  len(docs),
+				"content_hash": hash,
+			}
+			// 页码、章节等引用信息
+			for k, v := range doc.MetaData {
+				metadata[k] = v
+			}
+			docs = append(docs, &schema.Document{
+				ID:       splitter.ChunkID(docID, hash, occurrences[hash]),
+				Content:  chunk.Content,
+				MetaData: metadata,
+			})
+			occurrences[hash]++
+		}
 	}
-	return nil
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no content to index in %s", filePath)
+	}
+	return docs, nil
 }
 
-// NewRAGQuery 创建 RAG 查询器（用于向量检索和问答）
-func NewRAGQuery(ctx context.Context, username string) (*RAGQuery, error) {
-	cfg := config.GetConfig()
-	apiKey := os.Getenv("OPENAI_API_KEY")
+// DocumentKey 文档在索引中的标识（服务器上保存的文件名去掉扩展名），作为块 ID 前缀
+func DocumentKey(filePath string) string {
+	return strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+}
 
-	// 创建 embedding 模型
-	embedConfig := &embeddingArk.EmbeddingConfig{
-		BaseURL: cfg.RagModelConfig.RagBaseUrl,
-		APIKey:  apiKey,
-		Model:   cfg.RagModelConfig.RagEmbeddingModel,
-	}
-	embedder, err := embeddingArk.NewEmbedder(ctx, embedConfig)
+// ExistingChunks 获取文档已写入索引的块 ID
+func (r *RAGIndexer) ExistingChunks(ctx context.Context, docID string) (map[string]bool, error) {
+	chunkIDs, err := r.store.List(ctx, r.name, vectorstore.Filter{Documents: []string{docID}})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder: %w", err)
+		return nil, fmt.Errorf("failed to list chunks of %s: %w", docID, err)
+	}
+	ids := make(map[string]bool, len(chunkIDs))
+	for _, id := range chunkIDs {
+		ids[id] = true
 	}
+	return ids, nil
+}
+
+// UpdateChunkMetadata 只更新块的元数据（块序号、偏移等），不重新向量化
+func (r *RAGIndexer) UpdateChunkMetadata(ctx context.Context, docs []*schema.Document) error {
+	return r.store.UpdateMetadata(ctx, r.name, toStoreDocuments(docs))
+}
 
-	// 获取用户上传的文件名（假设每个用户只有一个文件）
-	// 这里需要从用户目录读取文件名
-	userDir := fmt.Sprintf("uploads/%s", username)
-	files, err := os.ReadDir(userDir)
-	if err != nil || len(files) == 0 {
-		return nil, fmt.Errorf("no uploaded file found for user %s", username)
+// DeleteChunks 从索引中删除指定的块
+func (r *RAGIndexer) DeleteChunks(ctx context.Context, chunkIDs []string) error {
+	if len(chunkIDs) == 0 {
+		return nil
 	}
+	_, err := r.store.Delete(ctx, r.name, vectorstore.Filter{IDs: chunkIDs})
+	return err
+}
+
+// storeBatchSize 单次向量化的文档块数量
+const storeBatchSize = 10
 
-	var filename string
-	for _, f := range files {
-		if !f.IsDir() {
-			filename = f.Name()
-			break
+// StoreChunks 将文档块向量化后写入索引
+func (r *RAGIndexer) StoreChunks(ctx context.Context, docs []*schema.Document) error {
+	for start := 0; start < len(docs); start += storeBatchSize {
+		batch := docs[start:min(start+storeBatchSize, len(docs))]
+		texts := make([]string, 0, len(batch))
+		for _, doc := range batch {
+			texts = append(texts, doc.Content)
+		}
+		vectors, err := r.embedding.EmbedStrings(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(batch))
+		}
+		storeDocs := toStoreDocuments(batch)
+		for i := range storeDocs {
+			storeDocs[i].Vector = vectors[i]
+		}
+		if err := r.store.Upsert(ctx, r.name, storeDocs); err != nil {
+			return fmt.Errorf("failed to store document: %w", err)
 		}
 	}
+	return nil
+}
 
-	if filename == "" {
-		return nil, fmt.Errorf("no valid file found for user %s", username)
+// toStoreDocuments 转换为向量存储中的文档块，所属文档由来源文件确定
+func toStoreDocuments(docs []*schema.Document) []*vectorstore.Document {
+	result := make([]*vectorstore.Document, 0, len(docs))
+	for _, doc := range docs {
+		source, _ := doc.MetaData["source"].(string)
+		result = append(result, &vectorstore.Document{
+			ID:       doc.ID,
+			Document: DocumentKey(source),
+			Content:  doc.Content,
+			Metadata: doc.MetaData,
+		})
 	}
+	return result
+}
 
-	// 创建 retriever
-	rdb := redisPkg.Rdb
-	indexName := redis.GenerateIndexName(filename)
+// NewRAGQuery 创建 RAG 查询器（用于向量检索和问答）
+// 在知识库内的所有文档中检索，knowledgeBaseID 为 0 时使用用户的默认知识库
+func NewRAGQuery(ctx context.Context, username string, knowledgeBaseID uint) (*RAGQuery, error) {
+	kb, err := resolveKnowledgeBase(username, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	// 知识库还没有入库过文档时没有索引，不触发重建
+	indexName := KnowledgeBaseIndex(kb.UserName, kb.ID)
+	if _, err := knowledgeDao.GetVectorIndex(indexName); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%s: %w", indexName, errIndexNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get index %s: %w", indexName, err)
+	}
 
-	retrieverConfig := &redisRetriever.RetrieverConfig{
-		Client:       rdb,
-		Index:        indexName,
-		Dialect:      2,
-		ReturnFields: []string{"content", "metadata", "distance"},
-		TopK:         5,
-		VectorField:  "vector",
-		DocumentConverter: func(ctx context.Context, doc redisCli.Document) (*schema.Document, error) {
-			resp := &schema.Document{
-				ID:       doc.ID,
-				Content:  "",
-				MetaData: map[string]any{},
-			}
-			for field, val := range doc.Fields {
-				if field == "content" {
-					resp.Content = val
-				} else {
-					resp.MetaData[field] = val
-				}
-			}
-			return resp, nil
-		},
+	// 查询必须使用构建索引时的向量模型，否则相似度结果没有意义
+	store := vectorstore.Default()
+	spec, err := resolveQueryEmbedder(ctx, store, indexName, kb.ID)
+	if err != nil {
+		return nil, err
+	}
+	embedder_, err := embedder.New(ctx, spec)
+	if err != nil {
+		return nil, err
 	}
-	retrieverConfig.Embedding = embedder
 
-	rtr, err := redisRetriever.NewRetriever(ctx, retrieverConfig)
+	return &RAGQuery{
+		embedding: embedder_,
+		store:     store,
+		indexName: indexName,
+	}, nil
+}
+
+// NewIndexQuery 直接在指定索引上创建查询器，使用构建索引时的向量模型
+// 不校验索引的所有者，只供离线评估等命令行工具使用
+func NewIndexQuery(ctx context.Context, indexName string) (*RAGQuery, error) {
+	store := vectorstore.Default()
+	collection, err := store.GetCollection(ctx, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	if collection == nil {
+		return nil, fmt.Errorf("index %s not found", indexName)
+	}
+	spec, err := embedder.GetSpec(collection.EmbedderID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create retriever: %w", err)
+		return nil, err
+	}
+	embedder_, err := embedder.New(ctx, spec)
+	if err != nil {
+		return nil, err
 	}
 
 	return &RAGQuery{
-		embedding: embedder,
-		retriever: rtr,
+		embedding: embedder_,
+		store:     store,
+		indexName: indexName,
 	}, nil
 }
 
-// RetrieveDocuments 检索相关文档
-func (r *RAGQuery) RetrieveDocuments(ctx context.Context, query string) ([]*schema.Document, error) {
-	docs, err := r.retriever.Retrieve(ctx, query)
+// convertDocument 将向量存储的检索结果转换为 schema.Document
+// 向量检索的距离记录在 MetaData["distance"] 中
+func convertDocument(result *vectorstore.SearchResult, withDistance bool) *schema.Document {
+	metadata := result.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	if withDistance {
+		metadata["distance"] = result.Distance
+	}
+	return &schema.Document{
+		ID:       result.ID,
+		Content:  result.Content,
+		MetaData: metadata,
+	}
+}
+
+// resolveQueryEmbedder 确定查询使用的向量模型
+// 索引与当前默认向量模型不一致时，会在后台用默认向量模型重建知识库索引；
+// 重建开始前，若原向量模型仍在配置中，则继续用原向量模型查询。重建会先删除旧索引再原地写入，
+// 之后的查询使用新向量模型，重建完成前结果只包含已重新写入的文档
+func resolveQueryEmbedder(ctx context.Context, store vectorstore.Store, indexName string, knowledgeBaseID uint) (*embedder.Spec, error) {
+	current, err := embedder.GetSpec("")
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+		return nil, err
 	}
-	return docs, nil
+
+	collection, err := store.GetCollection(ctx, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	if collection != nil && collection.EmbedderID == current.ID {
+		return current, nil
+	}
+
+	// 向量模型已切换（或旧索引没有记录向量模型），触发重建
+	triggerReindex(indexName, knowledgeBaseID)
+
+	if collection == nil {
+		return nil, fmt.Errorf("index %s has no embedder meta, reindexing", indexName)
+	}
+	spec, err := embedder.GetSpec(collection.EmbedderID)
+	if err != nil {
+		return nil, fmt.Errorf("index %s was built with %s which is no longer configured, reindexing", indexName, collection.EmbedderID)
+	}
+	return spec, nil
+}
+
+// promptDocument 模板中的一篇参考文档
+type promptDocument struct {
+	Index   int
+	Content string
+}
+
+// 知识库中没有相关内容时的处理方式
+const (
+	NoAnswerStrict   = "strict"   // 拒绝回答，有检索结果时也要求模型只根据参考文档回答
+	NoAnswerFallback = "fallback" // 告知用户未参考知识库，再由模型直接回答
+)
+
+// NoAnswerMode 读取 retrievalConfig.noAnswerMode，默认 fallback
+func NoAnswerMode() string {
+	if strings.EqualFold(config.GetConfig().RetrievalNoAnswerMode, NoAnswerStrict) {
+		return NoAnswerStrict
+	}
+	return NoAnswerFallback
 }
 
 // BuildRAGPrompt 构建包含检索文档的提示词
-func BuildRAGPrompt(query string, docs []*schema.Document) string {
+// 提示词模板来自模板库（rag_answer，严格模式为 rag_answer_strict），可按语言和模型覆盖
+// modelName 为回答问题的模型，为空时使用 RAG 对话模型
+func BuildRAGPrompt(query string, docs []*schema.Document, modelName string) string {
 	if len(docs) == 0 {
 		return query
 	}
 
+	documents := make([]promptDocument, 0, len(docs))
 	contextText := ""
 	for i, doc := range docs {
+		documents = append(documents, promptDocument{Index: i + 1, Content: doc.Content})
 		contextText += fmt.Sprintf("[文档 %d]: %s\n\n", i+1, doc.Content)
 	}
 
-	prompt := fmt.Sprintf(`基于以下参考文档回答用户的问题。如果文档中没有相关信息，请说明无法找到相关信息。
-
-参考文档：
-%s
-
-用户问题：%s
-
-请提供准确、完整的回答：`, contextText, query)
-
-	return prompt
+	if modelName == "" {
+		modelName = config.GetConfig().RagChatModelName
+	}
+	target := prompt.DefaultTarget(modelName)
+	name := prompt.RAGAnswer
+	if NoAnswerMode() == NoAnswerStrict {
+		name = prompt.RAGStrict
+	}
+	return prompt.Render(name, target, map[string]any{
+		"Query":     query,
+		"Documents": documents,
+		"Context":   contextText,
+	})
 }
@@ -0,0 +1,130 @@
+package rerank
+
+import (
+	"GopherAI/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// =================== 重排 API 实现 ===================
+// dashscope：阿里云百炼 text-rerank 接口（gte-rerank 等）
+// openai：Jina / Cohere / vLLM / Xinference 等兼容的 /rerank 接口
+
+type apiReranker struct {
+	model   string
+	baseURL string
+	format  string
+	apiKey  string
+	client  *http.Client
+}
+
+type rerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+func newAPIReranker(conf *config.Config) (Reranker, error) {
+	if conf.RerankBaseUrl == "" || conf.RerankModel == "" {
+		return nil, fmt.Errorf("rerank api requires baseUrl and model")
+	}
+	format := conf.RerankApiFormat
+	if format == "" {
+		format = "dashscope"
+	}
+	if format != "dashscope" && format != "openai" {
+		return nil, fmt.Errorf("unsupported rerank api format: %s", format)
+	}
+	apiKey := ""
+	if conf.RerankApiKeyEnv != "" {
+		apiKey = os.Getenv(conf.RerankApiKeyEnv)
+	}
+	return &apiReranker{
+		model:   conf.RerankModel,
+		baseURL: conf.RerankBaseUrl,
+		format:  format,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (r *apiReranker) Score(ctx context.Context, query string, docs []string) ([]float64, error) {
+	var payload any
+	if r.format == "dashscope" {
+		payload = map[string]any{
+			"model": r.model,
+			"input": map[string]any{"query": query, "documents": docs},
+			"parameters": map[string]any{
+				"top_n":            len(docs),
+				"return_documents": false,
+			},
+		}
+	} else {
+		payload = map[string]any{
+			"model":            r.model,
+			"query":            query,
+			"documents":        docs,
+			"top_n":            len(docs),
+			"return_documents": false,
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Results []rerankResult `json:"results"` // openai 兼容格式
+		Output  struct {
+			Results []rerankResult `json:"results"` // dashscope 格式
+		} `json:"output"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("parse rerank response failed: %w", err)
+	}
+	results := result.Results
+	if r.format == "dashscope" {
+		results = result.Output.Results
+	}
+
+	// 接口按分数排序返回，按 index 还原输入顺序；未返回的文档分数最低
+	scores := make([]float64, len(docs))
+	for i := range scores {
+		scores[i] = -1
+	}
+	for _, item := range results {
+		if item.Index < 0 || item.Index >= len(docs) {
+			return nil, fmt.Errorf("rerank returned invalid index %d", item.Index)
+		}
+		scores[item.Index] = item.RelevanceScore
+	}
+	return scores, nil
+}
@@ -0,0 +1,202 @@
+package rerank
+
+import (
+	"GopherAI/common/onnx"
+	"GopherAI/common/tokenizer"
+	"GopherAI/config"
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// =================== ONNX 交叉编码器实现 ===================
+// 在 CPU 上运行 BERT 系列交叉编码器（如 ms-marco-MiniLM、使用 WordPiece 词表的中文重排模型），
+// 将 [CLS] 问题 [SEP] 文档 [SEP] 一起输入模型，输出相关性 logit
+
+type onnxReranker struct {
+	session      *ort.DynamicAdvancedSession
+	tokenizer    *tokenizer.WordPiece
+	inputNames   []string
+	maxSeqLength int
+	batchSize    int
+	sem          chan struct{}
+}
+
+func newOnnxReranker(conf *config.Config) (Reranker, error) {
+	if conf.RerankModelPath == "" || conf.RerankVocabPath == "" {
+		return nil, fmt.Errorf("onnx reranker requires modelPath and vocabPath")
+	}
+	if err := onnx.InitEnvironment(); err != nil {
+		return nil, err
+	}
+
+	tk, err := tokenizer.LoadWordPiece(conf.RerankVocabPath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(conf.RerankModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("read onnx model info failed: %w", err)
+	}
+	inputNames := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		switch in.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			inputNames = append(inputNames, in.Name)
+		default:
+			return nil, fmt.Errorf("unsupported onnx model input %s", in.Name)
+		}
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("onnx model has no output")
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(conf.RerankModelPath, inputNames, []string{outputs[0].Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create onnx session failed: %w", err)
+	}
+
+	maxSeqLength := conf.RerankMaxSeqLength
+	if maxSeqLength <= 0 {
+		maxSeqLength = 512
+	}
+	batchSize := conf.RerankBatchSize
+	if batchSize <= 0 {
+		batchSize = 8
+	}
+	concurrency := conf.RerankConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	return &onnxReranker{
+		session:      session,
+		tokenizer:    tk,
+		inputNames:   inputNames,
+		maxSeqLength: maxSeqLength,
+		batchSize:    batchSize,
+		sem:          make(chan struct{}, concurrency),
+	}, nil
+}
+
+func (r *onnxReranker) Score(ctx context.Context, query string, docs []string) ([]float64, error) {
+	scores := make([]float64, 0, len(docs))
+	for start := 0; start < len(docs); start += r.batchSize {
+		end := min(start+r.batchSize, len(docs))
+
+		select {
+		case r.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		batch, err := r.scoreBatch(query, docs[start:end])
+		<-r.sem
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, batch...)
+	}
+	return scores, nil
+}
+
+func (r *onnxReranker) scoreBatch(query string, docs []string) ([]float64, error) {
+	encodings := make([]tokenizer.Encoding, len(docs))
+	seqLen := 0
+	for i, doc := range docs {
+		encodings[i] = r.tokenizer.EncodePair(query, doc, r.maxSeqLength)
+		seqLen = max(seqLen, len(encodings[i].InputIDs))
+	}
+
+	batch := len(docs)
+	inputIDs := make([]int64, batch*seqLen)
+	attentionMask := make([]int64, batch*seqLen)
+	typeIDs := make([]int64, batch*seqLen)
+	for i, enc := range encodings {
+		for j := 0; j < seqLen; j++ {
+			idx := i*seqLen + j
+			if j < len(enc.InputIDs) {
+				inputIDs[idx] = enc.InputIDs[j]
+				attentionMask[idx] = enc.AttentionMask[j]
+				typeIDs[idx] = enc.TypeIDs[j]
+			} else {
+				inputIDs[idx] = r.tokenizer.PadID()
+			}
+		}
+	}
+
+	shape := ort.NewShape(int64(batch), int64(seqLen))
+	inputs := make([]ort.Value, 0, len(r.inputNames))
+	defer func() {
+		for _, v := range inputs {
+			v.Destroy()
+		}
+	}()
+	for _, name := range r.inputNames {
+		var data []int64
+		switch name {
+		case "input_ids":
+			data = inputIDs
+		case "attention_mask":
+			data = attentionMask
+		case "token_type_ids":
+			data = typeIDs
+		}
+		tensor, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, fmt.Errorf("create input tensor failed: %w", err)
+		}
+		inputs = append(inputs, tensor)
+	}
+
+	outputs := []ort.Value{nil}
+	if err := r.session.Run(inputs, outputs); err != nil {
+		return nil, fmt.Errorf("onnx run error: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	out, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("unexpected onnx output type")
+	}
+	data := out.GetData()
+	labels := len(data) / batch
+	if labels == 0 || len(data) != labels*batch {
+		return nil, fmt.Errorf("unexpected onnx output size %d for batch %d", len(data), batch)
+	}
+
+	// 输出为 [batch, 1] 时对 logit 取 sigmoid；为 [batch, 2] 时取“相关”类别的 softmax 概率
+	scores := make([]float64, batch)
+	for i := range scores {
+		row := data[i*labels : (i+1)*labels]
+		if labels == 1 {
+			scores[i] = 1 / (1 + math.Exp(-float64(row[0])))
+		} else {
+			scores[i] = softmax(row)[labels-1]
+		}
+	}
+	return scores, nil
+}
+
+func softmax(logits []float32) []float64 {
+	if len(logits) == 0 {
+		return nil
+	}
+	maxLogit := float64(logits[0])
+	for _, l := range logits {
+		maxLogit = math.Max(maxLogit, float64(l))
+	}
+	sum := 0.0
+	probs := make([]float64, len(logits))
+	for i, l := range logits {
+		probs[i] = math.Exp(float64(l) - maxLogit)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+	return probs
+}
@@ -0,0 +1,118 @@
+package rerank
+
+import (
+	"GopherAI/config"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Reranker 重排模型：对检索召回的候选文档块按与问题的相关性重新打分
+// 返回每个候选文档的分数，顺序与 docs 一致
+type Reranker interface {
+	Score(ctx context.Context, query string, docs []string) ([]float64, error)
+}
+
+// Creator 根据配置创建重排模型
+type Creator func(conf *config.Config) (Reranker, error)
+
+var creators = map[string]Creator{
+	"api":  newAPIReranker,
+	"onnx": newOnnxReranker,
+}
+
+// Register 注册新的重排模型提供方
+func Register(provider string, creator Creator) {
+	creators[provider] = creator
+}
+
+var (
+	rerankers  = map[config.RerankConfig]Reranker{}
+	rerankerMu sync.Mutex
+)
+
+// GetGlobalReranker 获取配置的重排模型，未启用或创建失败时返回 nil（跳过重排）
+// 按重排配置缓存创建的模型，离线评估时各变体的配置不同，分别使用各自的模型
+func GetGlobalReranker() Reranker {
+	conf := config.GetConfig()
+	if !conf.RerankEnabled {
+		return nil
+	}
+	// 候选数量不影响模型本身
+	key := conf.RerankConfig
+	key.RerankDepth = 0
+
+	rerankerMu.Lock()
+	defer rerankerMu.Unlock()
+	if r, ok := rerankers[key]; ok {
+		return r
+	}
+	// 创建失败时同样缓存，避免每次检索都重试
+	rerankers[key] = nil
+	creator, ok := creators[conf.RerankProvider]
+	if !ok {
+		log.Printf("[rerank] unsupported provider %q, rerank disabled", conf.RerankProvider)
+		return nil
+	}
+	r, err := creator(conf)
+	if err != nil {
+		log.Printf("[rerank] create reranker failed, rerank disabled: %v", err)
+		return nil
+	}
+	rerankers[key] = r
+	return r
+}
+
+// Depth 重排的候选数量（检索阶段召回的文档块数量）
+func Depth(topK int) int {
+	depth := config.GetConfig().RerankDepth
+	if depth <= 0 {
+		depth = 20
+	}
+	return max(depth, topK)
+}
+
+// Rerank 对候选文档重新排序并保留前 topK 个，分数记录在 MetaData["rerank_score"] 中
+func Rerank(ctx context.Context, r Reranker, query string, docs []*schema.Document, topK int) ([]*schema.Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	contents := make([]string, len(docs))
+	for i, doc := range docs {
+		contents[i] = doc.Content
+	}
+	scores, err := r.Score(ctx, query, contents)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(docs) {
+		return nil, fmt.Errorf("reranker returned %d scores, expected %d", len(scores), len(docs))
+	}
+
+	indexes := make([]int, len(docs))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	if len(indexes) > topK {
+		indexes = indexes[:topK]
+	}
+
+	result := make([]*schema.Document, 0, len(indexes))
+	for _, i := range indexes {
+		doc := docs[i]
+		if doc.MetaData == nil {
+			doc.MetaData = map[string]any{}
+		}
+		doc.MetaData["rerank_score"] = scores[i]
+		result = append(result, doc)
+	}
+	return result, nil
+}
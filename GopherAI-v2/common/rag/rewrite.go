@@ -0,0 +1,219 @@
+package rag
+
+import (
+	"GopherAI/common/prompt"
+	"GopherAI/config"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Query 检索使用的查询
+// 对话中的追问（如“第二个呢？”）直接检索效果很差，检索前先结合对话历史改写为独立的查询，
+// 可选地再扩展出其他表述（多查询）和假设性回答（HyDE），各自检索后合并结果，再用 Standalone 重排
+type Query struct {
+	Original   string   `json:"original"`             // 用户原始问题
+	Standalone string   `json:"standalone"`           // 改写后的独立查询，用于检索和重排
+	Expansions []string `json:"expansions,omitempty"` // 多查询扩展出的其他表述
+	HyDE       string   `json:"hyde,omitempty"`       // 假设性回答，只用于向量检索
+}
+
+// NewQuery 不做改写的查询
+func NewQuery(text string) *Query {
+	return &Query{Original: text, Standalone: text}
+}
+
+// texts 需要分别检索的查询文本，去掉重复的表述
+func (q *Query) texts() []string {
+	texts := []string{q.Standalone}
+	for _, expansion := range q.Expansions {
+		duplicate := false
+		for _, text := range texts {
+			if strings.EqualFold(text, expansion) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			texts = append(texts, expansion)
+		}
+	}
+	return texts
+}
+
+type noRewriteKey struct{}
+
+// WithoutRewrite 在 context 中标记本次检索不改写查询
+// 改写模型使用 RAG 对话模型的服务，本地模型的会话没有脱敏时，对话内容不能发送给改写模型
+func WithoutRewrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRewriteKey{}, true)
+}
+
+// RewriteQuery 按 queryRewriteConfig 改写查询，history 为最新问题之前的对话消息
+// 改写失败时使用原始问题，不影响检索；结果记录在日志和 context 中的 Trace 里，便于调试
+func RewriteQuery(ctx context.Context, history []*schema.Message, query string) *Query {
+	q := NewQuery(query)
+	start := time.Now()
+	defer func() {
+		recordTiming(ctx, stageRewrite, start)
+		if trace := TraceFromContext(ctx); trace != nil {
+			trace.Query = q
+		}
+	}()
+
+	conf := config.GetConfig().QueryRewriteConfig
+	if !conf.QueryRewriteEnabled {
+		return q
+	}
+	if skip, _ := ctx.Value(noRewriteKey{}).(bool); skip {
+		return q
+	}
+	llm, err := rewriteModel(ctx)
+	if err != nil {
+		log.Printf("[rag] query rewrite unavailable: %v", err)
+		return q
+	}
+
+	// 1. 有对话历史时改写为独立查询
+	if historyText := formatHistory(history, conf.QueryRewriteHistory); historyText != "" {
+		rewritten, err := generate(ctx, llm, prompt.QueryRewrite, map[string]any{
+			"History": historyText,
+			"Query":   query,
+		})
+		if err != nil {
+			log.Printf("[rag] rewrite query failed, using original: %v", err)
+		} else if rewritten = strings.TrimSpace(rewritten); rewritten != "" {
+			q.Standalone = rewritten
+		}
+	}
+
+	// 2. 多查询扩展和 HyDE 互不依赖，并行生成
+	var wg sync.WaitGroup
+	if conf.QueryRewriteMultiQuery > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text, err := generate(ctx, llm, prompt.QueryExpand, map[string]any{
+				"Query": q.Standalone,
+				"Count": conf.QueryRewriteMultiQuery,
+			})
+			if err != nil {
+				log.Printf("[rag] expand query failed: %v", err)
+				return
+			}
+			q.Expansions = parseExpansions(text, conf.QueryRewriteMultiQuery)
+		}()
+	}
+	if conf.QueryRewriteHyDE {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text, err := generate(ctx, llm, prompt.HyDE, map[string]any{"Query": q.Standalone})
+			if err != nil {
+				log.Printf("[rag] generate hypothetical answer failed: %v", err)
+				return
+			}
+			q.HyDE = strings.TrimSpace(text)
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("[rag] query rewrite: %q -> %q, expansions=%q, hyde=%t", q.Original, q.Standalone, q.Expansions, q.HyDE != "")
+	return q
+}
+
+// formatHistory 取最近 limit 条消息，每条一行
+func formatHistory(history []*schema.Message, limit int) string {
+	if limit <= 0 {
+		limit = 6
+	}
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	var b strings.Builder
+	for _, msg := range history {
+		role := "用户"
+		if msg.Role == schema.Assistant {
+			role = "助手"
+		}
+		b.WriteString(fmt.Sprintf("%s：%s\n", role, strings.TrimSpace(msg.Content)))
+	}
+	return b.String()
+}
+
+// listMarker 模型可能添加的编号和列表符号，如 "1. "、"2、"、"- "
+var listMarker = regexp.MustCompile(`^(?:[-*•]|\d+[.、)）])\s*`)
+
+// parseExpansions 每行一种表述，去掉编号和列表符号
+func parseExpansions(text string, limit int) []string {
+	expansions := make([]string, 0, limit)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(listMarker.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line == "" {
+			continue
+		}
+		expansions = append(expansions, line)
+		if len(expansions) == limit {
+			break
+		}
+	}
+	return expansions
+}
+
+func generate(ctx context.Context, llm model.BaseChatModel, name string, vars map[string]any) (string, error) {
+	content := prompt.Render(name, prompt.DefaultTarget(rewriteModelName()), vars)
+	resp, err := llm.Generate(ctx, []*schema.Message{schema.UserMessage(content)})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// rewriteModelKey 改写模型的缓存键，离线评估时各变体可以使用不同的模型
+type rewriteModelKey struct {
+	baseURL string
+	model   string
+}
+
+var (
+	rewriteLLMs  = map[rewriteModelKey]model.BaseChatModel{}
+	rewriteLLMMu sync.Mutex
+)
+
+func rewriteModelName() string {
+	conf := config.GetConfig()
+	if conf.QueryRewriteModelName != "" {
+		return conf.QueryRewriteModelName
+	}
+	return conf.RagChatModelName
+}
+
+// rewriteModel 改写使用的模型，与 RAG 对话模型使用同一个服务，按服务地址和模型名缓存
+func rewriteModel(ctx context.Context) (model.BaseChatModel, error) {
+	key := rewriteModelKey{baseURL: config.GetConfig().RagBaseUrl, model: rewriteModelName()}
+
+	rewriteLLMMu.Lock()
+	defer rewriteLLMMu.Unlock()
+	if llm, ok := rewriteLLMs[key]; ok {
+		return llm, nil
+	}
+	llm, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
+		BaseURL: key.baseURL,
+		Model:   key.model,
+		APIKey:  os.Getenv("OPENAI_API_KEY"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	rewriteLLMs[key] = llm
+	return llm, nil
+}
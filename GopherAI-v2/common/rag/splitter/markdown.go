@@ -0,0 +1,93 @@
+package splitter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Markdown 按标题层级切分 Markdown 文档
+// 每个标题下的内容单独切分（不会跨标题合并），块的 HeadingPath 记录从一级标题到当前标题的路径；
+// 代码块中的 # 不会被识别为标题
+type Markdown struct {
+	opts      Options
+	recursive *Recursive
+}
+
+var headingRegex = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)[ \t]*#*[ \t]*$`)
+
+type heading struct {
+	level int
+	title string
+}
+
+type section struct {
+	span
+	path []string
+}
+
+func NewMarkdown(opts Options) *Markdown {
+	opts = opts.normalize()
+	return &Markdown{opts: opts, recursive: NewRecursive(opts)}
+}
+
+func (s *Markdown) Split(text string) []Chunk {
+	chunks := make([]Chunk, 0)
+	for _, sec := range s.sections(text) {
+		pieces := s.recursive.splitSpan(text, sec.span, s.recursive.separators)
+		for _, c := range merge(text, pieces, s.opts) {
+			c.HeadingPath = sec.path
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
+
+// sections 按标题将文档划分为若干段，标题行归属到它下面的内容
+func (s *Markdown) sections(text string) []section {
+	result := make([]section, 0)
+	stack := make([]heading, 0)
+	start := 0
+	inFence := false
+
+	closeSection := func(end int) {
+		if end > start {
+			result = append(result, section{span: span{start: start, end: end}, path: headingPath(stack)})
+		}
+		start = end
+	}
+
+	for lineStart := 0; lineStart < len(text); {
+		lineEnd := strings.IndexByte(text[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += lineStart + 1
+		}
+		line := strings.TrimRight(text[lineStart:lineEnd], "\r\n")
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			if m := headingRegex.FindStringSubmatch(line); m != nil {
+				closeSection(lineStart)
+				level := len(m[1])
+				for len(stack) > 0 && stack[len(stack)-1].level >= level {
+					stack = stack[:len(stack)-1]
+				}
+				stack = append(stack, heading{level: level, title: m[2]})
+			}
+		}
+		lineStart = lineEnd
+	}
+	closeSection(len(text))
+	return result
+}
+
+func headingPath(stack []heading) []string {
+	path := make([]string, len(stack))
+	for i, h := range stack {
+		path[i] = h.title
+	}
+	return path
+}
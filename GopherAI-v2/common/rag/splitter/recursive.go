@@ -0,0 +1,85 @@
+package splitter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 默认分隔符，按优先级从高到低：段落 -> 行 -> 句子 -> 分句 -> 词，最后按字符硬切
+var defaultSeparators = []string{"\n\n", "\n", "。", "！", "？", "；", ". ", "! ", "? ", "; ", "，", ", ", " ", ""}
+
+// Recursive 递归字符切分
+// 优先使用粒度大的分隔符切分，切出的片段仍然过长时再用下一级分隔符继续切分，
+// 最后将相邻片段合并到不超过 ChunkSize
+type Recursive struct {
+	opts       Options
+	separators []string
+}
+
+func NewRecursive(opts Options) *Recursive {
+	return &Recursive{opts: opts.normalize(), separators: defaultSeparators}
+}
+
+func (s *Recursive) Split(text string) []Chunk {
+	return merge(text, s.splitSpan(text, span{start: 0, end: len(text)}, s.separators), s.opts)
+}
+
+// splitSpan 将一段文本切成不超过 ChunkSize 的片段
+func (s *Recursive) splitSpan(text string, sp span, separators []string) []span {
+	if runeLen(text, sp) <= s.opts.ChunkSize {
+		return []span{sp}
+	}
+
+	for i, sep := range separators {
+		if sep == "" {
+			return s.hardCut(text, sp)
+		}
+		if !strings.Contains(text[sp.start:sp.end], sep) {
+			continue
+		}
+		pieces := make([]span, 0)
+		for _, part := range splitKeep(text, sp, sep) {
+			pieces = append(pieces, s.splitSpan(text, part, separators[i+1:])...)
+		}
+		return pieces
+	}
+	return s.hardCut(text, sp)
+}
+
+// hardCut 找不到分隔符时按字符数硬切
+func (s *Recursive) hardCut(text string, sp span) []span {
+	pieces := make([]span, 0)
+	start, count := sp.start, 0
+	for i := sp.start; i < sp.end; {
+		_, width := utf8.DecodeRuneInString(text[i:sp.end])
+		i += width
+		count++
+		if count == s.opts.ChunkSize {
+			pieces = append(pieces, span{start: start, end: i})
+			start, count = i, 0
+		}
+	}
+	if start < sp.end {
+		pieces = append(pieces, span{start: start, end: sp.end})
+	}
+	return pieces
+}
+
+// splitKeep 按分隔符切分，分隔符保留在前一段的末尾（例如句号留在句子中）
+func splitKeep(text string, sp span, sep string) []span {
+	parts := make([]span, 0)
+	start := sp.start
+	for start < sp.end {
+		i := strings.Index(text[start:sp.end], sep)
+		if i < 0 {
+			break
+		}
+		end := start + i + len(sep)
+		parts = append(parts, span{start: start, end: end})
+		start = end
+	}
+	if start < sp.end {
+		parts = append(parts, span{start: start, end: sp.end})
+	}
+	return parts
+}
@@ -0,0 +1,83 @@
+package splitter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentence 按句子切分，适合中文文档
+// 先按句末标点（。！？；…以及英文句号等）切成句子，再把相邻句子合并到不超过 ChunkSize，
+// 重叠部分以整句为单位，避免把一句话拆到两个块中
+type Sentence struct {
+	opts      Options
+	recursive *Recursive
+}
+
+// 单个句子超过 ChunkSize 时使用的分隔符
+var clauseSeparators = []string{"，", "、", ", ", " ", ""}
+
+func NewSentence(opts Options) *Sentence {
+	opts = opts.normalize()
+	return &Sentence{opts: opts, recursive: NewRecursive(opts)}
+}
+
+func (s *Sentence) Split(text string) []Chunk {
+	pieces := make([]span, 0)
+	for _, sentence := range sentences(text) {
+		pieces = append(pieces, s.recursive.splitSpan(text, sentence, clauseSeparators)...)
+	}
+	return merge(text, pieces, s.opts)
+}
+
+// 句末标点
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '。', '！', '？', '；', '…', '!', '?', '\n':
+		return true
+	}
+	return false
+}
+
+// 句末标点之后的收尾符号（引号、括号），归属到前一个句子
+func isCloser(r rune) bool {
+	return strings.ContainsRune("”’」』）】》\"')]", r)
+}
+
+// sentences 将文本切分为句子
+func sentences(text string) []span {
+	result := make([]span, 0)
+	start := 0
+	i := 0
+	for i < len(text) {
+		r, width := utf8.DecodeRuneInString(text[i:])
+		i += width
+
+		end := false
+		switch {
+		case isSentenceEnd(r):
+			end = true
+		case r == '.':
+			// 英文句号后需要跟空白或位于结尾，避免切开小数和缩写
+			next, _ := utf8.DecodeRuneInString(text[i:])
+			end = i >= len(text) || next == ' ' || next == '\n' || next == '\t'
+		}
+		if !end {
+			continue
+		}
+
+		// 连续的句末标点和收尾符号归属到当前句子
+		for i < len(text) {
+			next, w := utf8.DecodeRuneInString(text[i:])
+			if !isSentenceEnd(next) && !isCloser(next) {
+				break
+			}
+			i += w
+		}
+		result = append(result, span{start: start, end: i})
+		start = i
+	}
+	if start < len(text) {
+		result = append(result, span{start: start, end: len(text)})
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+package splitter
+
+import (
+	"GopherAI/config"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk 切分后的文本块
+type Chunk struct {
+	Content     string
+	Offset      int      // 在原文中的字节偏移
+	HeadingPath []string // 所在的标题路径（仅 Markdown 切分器）
+}
+
+// Splitter 文本切分器
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// Options 切分参数，长度按字符（rune）计算，中英文一致
+type Options struct {
+	ChunkSize    int // 每块最大字符数
+	ChunkOverlap int // 相邻块之间重叠的字符数
+}
+
+const (
+	defaultChunkSize    = 500
+	defaultChunkOverlap = 50
+)
+
+func (o Options) normalize() Options {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = defaultChunkSize
+	}
+	if o.ChunkOverlap < 0 {
+		o.ChunkOverlap = 0
+	}
+	// 重叠不能超过块大小，否则无法向前推进
+	if o.ChunkOverlap >= o.ChunkSize {
+		o.ChunkOverlap = o.ChunkSize / 5
+	}
+	return o
+}
+
+// New 按名称创建切分器：recursive | markdown | sentence
+// name 为空或 auto 时根据文件扩展名选择：Markdown 文件按标题切分，其他文件按句子切分
+func New(name, filename string, opts Options) (Splitter, error) {
+	opts = opts.normalize()
+	if name == "" || name == "auto" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".md", ".markdown":
+			name = "markdown"
+		default:
+			name = "sentence"
+		}
+	}
+
+	switch name {
+	case "recursive":
+		return NewRecursive(opts), nil
+	case "markdown":
+		return NewMarkdown(opts), nil
+	case "sentence":
+		return NewSentence(opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported splitter: %s", name)
+	}
+}
+
+// FromConfig 根据配置文件 chunkConfig 创建切分器
+func FromConfig(filename string) (Splitter, error) {
+	conf := config.GetConfig()
+	return New(conf.ChunkSplitter, filename, Options{
+		ChunkSize:    conf.ChunkSize,
+		ChunkOverlap: conf.ChunkOverlap,
+	})
+}
+
+// ContentHash 块内容的哈希，用于判断块内容是否变化
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChunkID 生成块的稳定 ID：由文档 ID 和内容哈希组成，文档修改后内容不变的块 ID 不变
+// occurrence 为同一文档中相同内容出现的次序（从 0 开始），保证重复段落的 ID 不冲突
+func ChunkID(docID, contentHash string, occurrence int) string {
+	if occurrence == 0 {
+		return fmt.Sprintf("%s_%s", docID, contentHash[:16])
+	}
+	return fmt.Sprintf("%s_%s_%d", docID, contentHash[:16], occurrence)
+}
+
+// DocumentOfChunk 从块 ID 中取出文档 ID，是 ChunkID 的逆操作，不是这种格式的 ID 返回 false
+func DocumentOfChunk(chunkID string) (string, bool) {
+	m := chunkIDPattern.FindStringSubmatch(chunkID)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+var chunkIDPattern = regexp.MustCompile(`^(.+)_[0-9a-f]{16}(?:_\d+)?$`)
+
+// span 原文中的一段 [start, end)，使用字节偏移，便于回溯原文位置
+type span struct {
+	start, end int
+}
+
+func runeLen(text string, s span) int {
+	return utf8.RuneCountInString(text[s.start:s.end])
+}
+
+// merge 将连续的小片段合并为不超过 ChunkSize 的块，相邻块之间保留不超过 ChunkOverlap 的重叠片段
+// pieces 必须按原文顺序排列且每段都不超过 ChunkSize
+func merge(text string, pieces []span, opts Options) []Chunk {
+	chunks := make([]Chunk, 0)
+	window := make([]span, 0)
+	size := 0
+
+	emit := func() {
+		if len(window) == 0 {
+			return
+		}
+		if c, ok := makeChunk(text, span{start: window[0].start, end: window[len(window)-1].end}); ok {
+			chunks = append(chunks, c)
+		}
+	}
+
+	for _, p := range pieces {
+		l := runeLen(text, p)
+		if size+l > opts.ChunkSize && len(window) > 0 {
+			emit()
+			// 只保留末尾不超过 overlap 的片段作为下一块的开头
+			for len(window) > 0 && (size > opts.ChunkOverlap || size+l > opts.ChunkSize) {
+				size -= runeLen(text, window[0])
+				window = window[1:]
+			}
+		}
+		window = append(window, p)
+		size += l
+	}
+	emit()
+	return chunks
+}
+
+// makeChunk 去掉首尾空白，空白块直接丢弃
+func makeChunk(text string, s span) (Chunk, bool) {
+	raw := text[s.start:s.end]
+	left := strings.TrimLeftFunc(raw, unicode.IsSpace)
+	content := strings.TrimRightFunc(left, unicode.IsSpace)
+	if content == "" {
+		return Chunk{}, false
+	}
+	return Chunk{
+		Content: content,
+		Offset:  s.start + len(raw) - len(left),
+	}, true
+}
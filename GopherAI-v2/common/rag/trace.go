@@ -0,0 +1,104 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// 检索各阶段，用于记录耗时
+const (
+	stageRewrite   = "rewrite"
+	stageEmbedding = "embedding"
+	stageSearch    = "search"
+	stageRerank    = "rerank"
+)
+
+type traceKey struct{}
+
+// Trace 一次检索的调试信息
+// 同一阶段可能并行执行多次（多路检索、多个知识库），耗时为各次之和，可能大于实际经过的时间
+type Trace struct {
+	Query             *Query  `json:"query"`               // 实际用于检索的查询（改写、扩展和 HyDE 的结果）
+	RewriteMs         float64 `json:"rewrite_ms"`          // 查询改写、扩展和 HyDE 生成
+	EmbeddingMs       float64 `json:"embedding_ms"`        // 查询向量化
+	SearchMs          float64 `json:"search_ms"`           // 向量检索和全文检索
+	RerankMs          float64 `json:"rerank_ms"`           // 重排
+	Candidates        int     `json:"candidates"`          // 合并后的候选块数
+	DroppedByDistance int     `json:"dropped_by_distance"` // 因向量距离超过阈值被去掉的候选块数
+	Reranked          bool    `json:"reranked"`            // 是否经过重排
+
+	mu sync.Mutex
+}
+
+// WithTrace 在 context 中记录检索的调试信息，检索完成后从返回的 Trace 中读取
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+// TraceFromContext 取出 context 中的 Trace，没有时返回 nil
+func TraceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// recordTiming 累加某个阶段从 start 开始的耗时，context 中没有 Trace 时不记录
+func recordTiming(ctx context.Context, stage string, start time.Time) {
+	trace := TraceFromContext(ctx)
+	if trace == nil {
+		return
+	}
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	switch stage {
+	case stageRewrite:
+		trace.RewriteMs += elapsed
+	case stageEmbedding:
+		trace.EmbeddingMs += elapsed
+	case stageSearch:
+		trace.SearchMs += elapsed
+	case stageRerank:
+		trace.RerankMs += elapsed
+	}
+}
+
+// recordCandidates 记录候选块数和被距离阈值去掉的块数
+func recordCandidates(ctx context.Context, candidates, dropped int, reranked bool) {
+	trace := TraceFromContext(ctx)
+	if trace == nil {
+		return
+	}
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.Candidates += candidates
+	trace.DroppedByDistance += dropped
+	trace.Reranked = trace.Reranked || reranked
+}
+
+// logDocuments 记录检索结果的块 ID 和分数，便于排查回答质量问题
+func logDocuments(indexName string, query *Query, docs []*schema.Document) {
+	items := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		items = append(items, fmt.Sprintf("%s(%s)", doc.ID, formatScores(doc.MetaData)))
+	}
+	log.Printf("[rag] retrieved index=%s query=%q docs=%d [%s]", indexName, query.Standalone, len(docs), strings.Join(items, " "))
+}
+
+func formatScores(metadata map[string]any) string {
+	scores := make([]string, 0, 3)
+	for _, key := range []string{"score", "distance", "rerank_score"} {
+		if value, ok := metadata[key].(float64); ok {
+			scores = append(scores, fmt.Sprintf("%s=%.4f", key, value))
+		}
+	}
+	return strings.Join(scores, ",")
+}
@@ -0,0 +1,208 @@
+package redact
+
+import (
+	"GopherAI/config"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Rule 一条脱敏规则：Name 会作为占位符前缀，例如 PHONE -> [PHONE_1]
+type Rule struct {
+	Name    string
+	Locale  string
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// 内置规则，按地区划分
+// 注意顺序：较长的规则（如身份证号）需要排在较短的规则（如手机号）前面，避免被截断匹配
+var builtinRules = map[string][]Rule{
+	"common": {
+		{Name: "EMAIL", Pattern: `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`},
+		{Name: "API_KEY", Pattern: `\b(?:sk|ak|pk|rk)-[A-Za-z0-9_\-]{16,}\b`},
+		{Name: "BEARER_TOKEN", Pattern: `\bBearer\s+[A-Za-z0-9._\-]{20,}`},
+	},
+	"zh-CN": {
+		{Name: "ID_CARD", Pattern: `\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`},
+		{Name: "PHONE", Pattern: `(?:\+?86[\s\-]?)?\b1[3-9]\d{9}\b`},
+		{Name: "BANK_CARD", Pattern: `\b(?:62|4\d|5[1-5])\d{14,17}\b`},
+	},
+	"en-US": {
+		{Name: "SSN", Pattern: `\b\d{3}-\d{2}-\d{4}\b`},
+		{Name: "PHONE", Pattern: `\b\(?\d{3}\)?[\s.\-]\d{3}[\s.\-]\d{4}\b`},
+	},
+}
+
+// 占位符格式，用于流式输出时判断是否需要暂存
+var placeholderRe = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_\d+\]`)
+
+// 占位符的最大长度，流式输出时超过该长度的未闭合片段直接输出
+const maxPlaceholderLen = 40
+
+// Redactor 脱敏器，持有编译好的规则，可被多个会话共享
+type Redactor struct {
+	rules []*Rule
+}
+
+// NewRedactor 根据地区加载内置规则，并追加（或覆盖同名的）自定义规则
+func NewRedactor(locales []string, custom []config.PIIRule) (*Redactor, error) {
+	rules := make([]*Rule, 0)
+	index := make(map[string]int)
+
+	add := func(r Rule) error {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pii rule %s: %w", r.Name, err)
+		}
+		r.Name = strings.ToUpper(r.Name)
+		r.re = re
+		key := r.Locale + "/" + r.Name
+		if i, ok := index[key]; ok {
+			rules[i] = &r
+			return nil
+		}
+		index[key] = len(rules)
+		rules = append(rules, &r)
+		return nil
+	}
+
+	for _, locale := range locales {
+		for _, r := range builtinRules[locale] {
+			r.Locale = locale
+			if err := add(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, r := range custom {
+		if err := add(Rule{Name: r.Name, Locale: r.Locale, Pattern: r.Pattern}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Redactor{rules: rules}, nil
+}
+
+// NewSession 创建一个脱敏会话，同一会话内相同的敏感值始终映射为同一个占位符
+func (r *Redactor) NewSession() *Session {
+	return &Session{
+		redactor: r,
+		forward:  make(map[string]string),
+		reverse:  make(map[string]string),
+		counters: make(map[string]int),
+	}
+}
+
+// Session 保存敏感值与占位符之间的双向映射
+type Session struct {
+	redactor *Redactor
+	mu       sync.Mutex
+	forward  map[string]string // 敏感值 -> 占位符
+	reverse  map[string]string // 占位符 -> 敏感值
+	counters map[string]int
+}
+
+// Redact 将文本中的敏感信息替换为占位符
+func (s *Session) Redact(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rule := range s.redactor.rules {
+		text = rule.re.ReplaceAllStringFunc(text, func(value string) string {
+			if placeholderRe.MatchString(value) {
+				return value
+			}
+			if p, ok := s.forward[value]; ok {
+				return p
+			}
+			s.counters[rule.Name]++
+			p := fmt.Sprintf("[%s_%d]", rule.Name, s.counters[rule.Name])
+			s.forward[value] = p
+			s.reverse[p] = value
+			return p
+		})
+	}
+	return text
+}
+
+// Restore 将文本中的占位符还原为原始值，未知的占位符保持不变
+func (s *Session) Restore(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.reverse) == 0 {
+		return text
+	}
+	return placeholderRe.ReplaceAllStringFunc(text, func(p string) string {
+		if v, ok := s.reverse[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+// StreamRestorer 流式还原器
+// 占位符可能被拆分到多个流式片段中，这里会暂存可能属于占位符的尾部，等下一个片段到来再还原
+type StreamRestorer struct {
+	session *Session
+	cb      func(string)
+	buf     strings.Builder
+}
+
+// NewStreamRestorer 创建流式还原器，还原后的内容通过 cb 输出
+func (s *Session) NewStreamRestorer(cb func(string)) *StreamRestorer {
+	return &StreamRestorer{session: s, cb: cb}
+}
+
+// Write 写入一个流式片段
+func (w *StreamRestorer) Write(chunk string) {
+	w.buf.WriteString(chunk)
+	text := w.buf.String()
+
+	// 找到最后一个未闭合的 '['，其后的内容可能是占位符的一部分
+	cut := len(text)
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLen {
+		cut = i
+	}
+
+	w.buf.Reset()
+	w.buf.WriteString(text[cut:])
+	if cut > 0 {
+		w.cb(w.session.Restore(text[:cut]))
+	}
+}
+
+// Flush 输出剩余暂存的内容
+func (w *StreamRestorer) Flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	w.cb(w.session.Restore(w.buf.String()))
+	w.buf.Reset()
+}
+
+var (
+	globalRedactor *Redactor
+	redactorOnce   sync.Once
+)
+
+// GetGlobalRedactor 获取全局脱敏器，未开启脱敏时返回 nil
+// 开启了脱敏但规则加载失败时直接退出进程：不能在不脱敏的情况下把原始内容发送给外部模型，
+// 服务启动时会先调用一次，配置错误在启动阶段就会暴露
+func GetGlobalRedactor() *Redactor {
+	redactorOnce.Do(func() {
+		conf := config.GetConfig().PIIConfig
+		if !conf.PIIEnabled {
+			return
+		}
+		r, err := NewRedactor(conf.PIILocales, conf.PIIRules)
+		if err != nil {
+			log.Fatalf("[redact] load pii rules failed: %v", err)
+		}
+		globalRedactor = r
+	})
+	return globalRedactor
+}
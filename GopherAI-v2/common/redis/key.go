@@ -15,7 +15,22 @@ func GenerateIndexName(filename string) string {
 	return indexName
 }
 
+// 带版本号的实际索引名，查询时通过别名 GenerateIndexName 访问
+func GenerateVersionedIndexName(filename string, version int) string {
+	return fmt.Sprintf("%s:v%d", GenerateIndexName(filename), version)
+}
+
 func GenerateIndexNamePrefix(filename string) string {
 	prefix := fmt.Sprintf(config.DefaultRedisKeyConfig.IndexNamePrefix, filename)
 	return prefix
 }
+
+// 块在 Redis 中的 key：索引前缀 + 索引标识 + 块 ID
+func GenerateChunkKey(filename, chunkID string) string {
+	return GenerateIndexNamePrefix(filename) + fmt.Sprintf("%s:%s", filename, chunkID)
+}
+
+// 索引元信息（构建索引时使用的向量模型及维度）
+func GenerateIndexMeta(filename string) string {
+	return fmt.Sprintf(config.DefaultRedisKeyConfig.IndexMeta, filename)
+}
@@ -4,6 +4,7 @@ import (
 	"GopherAI/config"
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -66,7 +67,9 @@ func CheckCaptchaForEmail(email, userInput string) (bool, error) {
 }
 
 // InitRedisIndex 初始化 Redis 索引，支持按文件名区分
-func InitRedisIndex(ctx context.Context, filename string, dimension int) error {
+// 实际的索引名带有版本号（见 meta.Index），查询统一使用别名（GenerateIndexName），
+// 迁移索引参数时新建索引后切换别名，查询不受影响
+func InitRedisIndex(ctx context.Context, filename string, meta *IndexMeta) error {
 	indexName := GenerateIndexName(filename)
 
 	// 检查索引是否存在
@@ -77,46 +80,328 @@ func InitRedisIndex(ctx context.Context, filename string, dimension int) error {
 	}
 
 	// 如果索引不存在，创建新索引
-	if !strings.Contains(err.Error(), "Unknown index name") {
+	if !isUnknownIndex(err) {
 		return fmt.Errorf("检查索引失败: %w", err)
 	}
 
 	fmt.Println("正在创建 Redis 索引...")
 
+	if err := CreateVectorIndex(ctx, filename, meta.Index, meta.Dimension, meta.Options); err != nil {
+		return err
+	}
+	if err := Rdb.Do(ctx, "FT.ALIASADD", indexName, meta.Index).Err(); err != nil {
+		return fmt.Errorf("创建索引别名失败: %w", err)
+	}
+
+	fmt.Println("索引创建成功！")
+	return nil
+}
+
+// CreateVectorIndex 在知识库的 key 前缀上创建一个索引
+// 同一前缀上可以同时存在多个索引，RediSearch 会在后台为已有的 Hash 建立新索引
+func CreateVectorIndex(ctx context.Context, filename, physicalName string, dimension int, opts VectorIndexOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 	prefix := GenerateIndexNamePrefix(filename)
 
 	// 创建索引
+	// LANGUAGE 决定全文检索的分词方式，chinese 使用中文分词（否则整段中文会被当作一个词）
 	createArgs := []interface{}{
-		"FT.CREATE", indexName,
+		"FT.CREATE", physicalName,
 		"ON", "HASH",
 		"PREFIX", "1", prefix,
+	}
+	if language := config.GetConfig().RetrievalLanguage; language != "" {
+		createArgs = append(createArgs, "LANGUAGE", language)
+	}
+	createArgs = append(createArgs,
 		"SCHEMA",
 		"content", "TEXT",
 		"metadata", "TEXT",
-		"vector", "VECTOR", "FLAT",
-		"6",
-		"TYPE", "FLOAT32",
-		"DIM", dimension,
-		"DISTANCE_METRIC", "COSINE",
+		"document", "TAG", // 所属文档，用于按文档过滤
+	)
+	for _, field := range MetadataFields {
+		createArgs = append(createArgs, field.Name, field.Type)
 	}
+	createArgs = append(createArgs, opts.schemaArgs(dimension)...)
 
 	if err := Rdb.Do(ctx, createArgs...).Err(); err != nil {
 		return fmt.Errorf("创建索引失败: %w", err)
 	}
+	return nil
+}
+
+// AddIndexField 为已有索引添加字段，字段已存在时不做任何事
+// 旧版本创建的索引缺少后来新增的字段，写入数据前调用以补全
+func AddIndexField(ctx context.Context, filename string, field ...interface{}) error {
+	args := append([]interface{}{"FT.ALTER", GenerateIndexName(filename), "SCHEMA", "ADD"}, field...)
+	err := Rdb.Do(ctx, args...).Err()
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "duplicate") {
+		return fmt.Errorf("添加索引字段失败: %w", err)
+	}
+	return nil
+}
 
-	fmt.Println("索引创建成功！")
+// WaitIndexReady 等待索引完成对已有数据的后台索引
+func WaitIndexReady(ctx context.Context, physicalName string) error {
+	for {
+		info, err := Rdb.FTInfo(ctx, physicalName).Result()
+		if err != nil {
+			return fmt.Errorf("查询索引状态失败: %w", err)
+		}
+		if info.Indexing == 0 && info.PercentIndexed >= 1 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// SwapIndexAlias 将别名切换到新索引并删除旧索引（保留数据）
+// 旧索引没有使用别名（直接以别名的名字创建）时，在同一个事务中删除旧索引并添加别名
+func SwapIndexAlias(ctx context.Context, filename, oldPhysical, newPhysical string) error {
+	alias := GenerateIndexName(filename)
+	if oldPhysical == alias {
+		pipe := Rdb.TxPipeline()
+		pipe.Do(ctx, "FT.DROPINDEX", oldPhysical)
+		pipe.Do(ctx, "FT.ALIASADD", alias, newPhysical)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("切换索引别名失败: %w", err)
+		}
+		return nil
+	}
+	if err := Rdb.Do(ctx, "FT.ALIASUPDATE", alias, newPhysical).Err(); err != nil {
+		return fmt.Errorf("切换索引别名失败: %w", err)
+	}
+	if err := Rdb.Do(ctx, "FT.DROPINDEX", oldPhysical).Err(); err != nil && !isUnknownIndex(err) {
+		return fmt.Errorf("删除旧索引失败: %w", err)
+	}
 	return nil
 }
 
-// DeleteRedisIndex 删除 Redis 索引，支持按文件名区分
+// DeleteRedisIndex 删除 Redis 索引、索引下的所有块和元信息
+// FT.DROPINDEX DD 只会删除索引收录的 key，之前不带 DD 删除索引后留下的块按前缀一并清除
 func DeleteRedisIndex(ctx context.Context, filename string) error {
-	indexName := GenerateIndexName(filename)
+	if err := DropRedisIndexWithDocs(ctx, filename); err != nil {
+		return err
+	}
+	if _, err := DeleteIndexKeys(ctx, filename); err != nil {
+		return fmt.Errorf("删除索引数据失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteIndexKeys 按前缀删除索引下的所有块，不要求索引存在，返回删除的数量
+func DeleteIndexKeys(ctx context.Context, filename string) (int, error) {
+	keys, err := ScanKeys(ctx, GenerateChunkKey(filename, "*"))
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for start := 0; start < len(keys); start += 1000 {
+		n, err := Rdb.Del(ctx, keys[start:min(start+1000, len(keys))]...).Result()
+		deleted += int(n)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// ListIndexNames 列出 Redis 中所有索引标识，包括只剩元信息、只剩 RediSearch 索引或只剩块数据的索引
+func ListIndexNames(ctx context.Context) ([]string, error) {
+	names := make(map[string]bool)
+
+	metaPrefix := keyPrefix(GenerateIndexMeta)
+	metaKeys, err := ScanKeys(ctx, metaPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range metaKeys {
+		names[strings.TrimPrefix(key, metaPrefix)] = true
+	}
 
-	// 删除索引
-	if err := Rdb.Do(ctx, "FT.DROPINDEX", indexName).Err(); err != nil {
+	indexes, err := Rdb.Do(ctx, "FT._LIST").StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("列出索引失败: %w", err)
+	}
+	for _, index := range indexes {
+		if name, ok := parseIndexName(index); ok {
+			names[name] = true
+		}
+	}
+
+	chunkKeys, err := ScanKeys(ctx, keyPrefix(GenerateIndexNamePrefix)+"*")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range chunkKeys {
+		if name, ok := parseChunkKey(key); ok {
+			names[name] = true
+		}
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// parseIndexName 从 RediSearch 索引名（rag_docs:<标识>:idx 或 rag_docs:<标识>:idx:v<版本>）中取出索引标识
+func parseIndexName(index string) (string, bool) {
+	prefix, suffix, _ := strings.Cut(GenerateIndexName("\x00"), "\x00")
+	if !strings.HasPrefix(index, prefix) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(index, prefix)
+	if i := strings.LastIndex(rest, suffix+":v"); i > 0 {
+		if _, err := strconv.Atoi(rest[i+len(suffix)+2:]); err == nil {
+			return rest[:i], true
+		}
+	}
+	if strings.HasSuffix(rest, suffix) && len(rest) > len(suffix) {
+		return strings.TrimSuffix(rest, suffix), true
+	}
+	return "", false
+}
+
+// parseChunkKey 从块的 key（rag_docs:<标识>:<标识>:<块ID>）中取出索引标识
+// 标识中可能含有冒号，依次尝试每个冒号位置，直到前后两段标识相同
+func parseChunkKey(key string) (string, bool) {
+	prefix := keyPrefix(GenerateIndexNamePrefix)
+	if !strings.HasPrefix(key, prefix) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(key, prefix)
+	for i := strings.Index(rest, ":"); i > 0; {
+		name := rest[:i]
+		if strings.HasPrefix(rest[i+1:], name+":") {
+			return name, true
+		}
+		next := strings.Index(rest[i+1:], ":")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", false
+}
+
+// keyPrefix key 格式中索引标识之前的固定部分，如 rag_docs:
+func keyPrefix(generate func(string) string) string {
+	prefix, _, _ := strings.Cut(generate("\x00"), "\x00")
+	return prefix
+}
+
+// IndexMeta 索引元信息，记录构建索引时使用的向量模型和索引参数，保证查询时使用同一个向量模型和向量编码
+type IndexMeta struct {
+	EmbedderID string
+	Dimension  int
+	Index      string // 实际的索引名，旧版本直接以别名的名字创建索引
+	Version    int    // 索引版本号，每次迁移加一
+	Options    VectorIndexOptions
+	// 正在迁移到的向量类型，迁移期间新写入的块同时写入新旧两个向量字段
+	MigratingVectorType string
+}
+
+// SetIndexMeta 保存索引元信息
+func SetIndexMeta(ctx context.Context, filename string, meta *IndexMeta) error {
+	key := GenerateIndexMeta(filename)
+	return Rdb.HSet(ctx, key,
+		"embedder", meta.EmbedderID,
+		"dimension", meta.Dimension,
+		"index", meta.Index,
+		"version", meta.Version,
+		"algorithm", meta.Options.Algorithm,
+		"metric", meta.Options.Metric,
+		"vector_type", meta.Options.VectorType,
+		"m", meta.Options.M,
+		"ef_construction", meta.Options.EfConstruction,
+		"ef_runtime", meta.Options.EfRuntime,
+		"migrating_vector_type", meta.MigratingVectorType,
+	).Err()
+}
+
+// GetIndexMeta 获取索引元信息，不存在时返回 nil
+// 旧版本的元信息没有索引参数，按原来固定的 FLAT / COSINE / FLOAT32 补全
+func GetIndexMeta(ctx context.Context, filename string) (*IndexMeta, error) {
+	key := GenerateIndexMeta(filename)
+	values, err := Rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	dimension, _ := strconv.Atoi(values["dimension"])
+	version, _ := strconv.Atoi(values["version"])
+	m, _ := strconv.Atoi(values["m"])
+	efConstruction, _ := strconv.Atoi(values["ef_construction"])
+	efRuntime, _ := strconv.Atoi(values["ef_runtime"])
+	meta := &IndexMeta{
+		EmbedderID: values["embedder"],
+		Dimension:  dimension,
+		Index:      values["index"],
+		Version:    version,
+		Options: VectorIndexOptions{
+			Algorithm:      firstNonEmpty(values["algorithm"], VectorAlgorithmFlat),
+			Metric:         firstNonEmpty(values["metric"], "COSINE"),
+			VectorType:     firstNonEmpty(values["vector_type"], VectorTypeFloat32),
+			M:              m,
+			EfConstruction: efConstruction,
+			EfRuntime:      efRuntime,
+		},
+		MigratingVectorType: values["migrating_vector_type"],
+	}
+	if meta.Index == "" {
+		meta.Index = GenerateIndexName(filename)
+	}
+	return meta, nil
+}
+
+// physicalIndexName 获取实际的索引名
+func physicalIndexName(ctx context.Context, filename string) (string, error) {
+	meta, err := GetIndexMeta(ctx, filename)
+	if err != nil {
+		return "", err
+	}
+	if meta == nil {
+		return GenerateIndexName(filename), nil
+	}
+	return meta.Index, nil
+}
+
+func isUnknownIndex(err error) bool {
+	return strings.Contains(err.Error(), "Unknown index name") || strings.Contains(err.Error(), "no such index")
+}
+
+// DropRedisIndexWithDocs 删除索引以及索引下的所有文档数据
+// 用于向量模型变更后重建索引：旧向量与新模型不兼容，必须一并清除
+func DropRedisIndexWithDocs(ctx context.Context, filename string) error {
+	physical, err := physicalIndexName(ctx, filename)
+	if err != nil {
+		return err
+	}
+
+	Rdb.Do(ctx, "FT.ALIASDEL", GenerateIndexName(filename))
+	if err := Rdb.Do(ctx, "FT.DROPINDEX", physical, "DD").Err(); err != nil && !isUnknownIndex(err) {
 		return fmt.Errorf("删除索引失败: %w", err)
 	}
+	return Rdb.Del(ctx, GenerateIndexMeta(filename)).Err()
+}
 
-	fmt.Println("索引删除成功！")
-	return nil
+// ScanKeys 查找匹配模式的所有 key
+func ScanKeys(ctx context.Context, pattern string) ([]string, error) {
+	keys := make([]string, 0)
+	iter := Rdb.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
 }
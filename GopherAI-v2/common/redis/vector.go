@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"GopherAI/config"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	VectorAlgorithmFlat = "FLAT" // 精确检索，耗时随数据量线性增长
+	VectorAlgorithmHNSW = "HNSW" // 近似检索，数据量大时远快于 FLAT
+
+	VectorTypeFloat32 = "FLOAT32"
+	VectorTypeFloat64 = "FLOAT64"
+)
+
+var vectorMetrics = map[string]bool{"COSINE": true, "L2": true, "IP": true}
+
+// 可过滤元数据字段的类型
+const (
+	MetadataTag     = "TAG"     // 取值集合，按取值之一过滤
+	MetadataNumeric = "NUMERIC" // 数值，按范围过滤
+)
+
+// MetadataField 可过滤的元数据字段，写入块时从块的元数据中取同名字段的值单独存储并建立索引
+type MetadataField struct {
+	Name string
+	Type string
+}
+
+// MetadataFields 所有可过滤的元数据字段
+var MetadataFields = []MetadataField{
+	{Name: "doc_id", Type: MetadataTag},         // 文档记录 ID
+	{Name: "tags", Type: MetadataTag},           // 文档标签
+	{Name: "language", Type: MetadataTag},       // 文档语言
+	{Name: "updated_at", Type: MetadataNumeric}, // 文档更新时间（Unix 时间戳）
+}
+
+// LookupMetadataField 查找可过滤的元数据字段
+func LookupMetadataField(name string) (MetadataField, bool) {
+	for _, field := range MetadataFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return MetadataField{}, false
+}
+
+// VectorIndexOptions 向量索引参数
+type VectorIndexOptions struct {
+	Algorithm      string // FLAT | HNSW
+	Metric         string // COSINE | L2 | IP
+	VectorType     string // FLOAT32 | FLOAT64
+	M              int    // HNSW：每个节点的最大邻居数
+	EfConstruction int    // HNSW：构建图时的候选数量，越大召回越好、构建越慢
+	EfRuntime      int    // HNSW：查询时的候选数量，越大召回越好、查询越慢
+}
+
+// Normalize 未设置的参数使用 vectorIndexConfig 中的默认值
+func (o VectorIndexOptions) Normalize() VectorIndexOptions {
+	conf := config.GetConfig().VectorIndexConfig
+	o.Algorithm = strings.ToUpper(firstNonEmpty(o.Algorithm, conf.VectorIndexAlgorithm, VectorAlgorithmFlat))
+	o.Metric = strings.ToUpper(firstNonEmpty(o.Metric, conf.VectorIndexMetric, "COSINE"))
+	o.VectorType = strings.ToUpper(firstNonEmpty(o.VectorType, conf.VectorIndexType, VectorTypeFloat32))
+	o.M = firstPositive(o.M, conf.VectorIndexM, 16)
+	o.EfConstruction = firstPositive(o.EfConstruction, conf.VectorIndexEfConstruction, 200)
+	o.EfRuntime = firstPositive(o.EfRuntime, conf.VectorIndexEfRuntime, 10)
+	return o
+}
+
+// Validate 校验参数是否合法，应在 Normalize 之后调用
+func (o VectorIndexOptions) Validate() error {
+	if o.Algorithm != VectorAlgorithmFlat && o.Algorithm != VectorAlgorithmHNSW {
+		return fmt.Errorf("unsupported vector index algorithm %q", o.Algorithm)
+	}
+	if !vectorMetrics[o.Metric] {
+		return fmt.Errorf("unsupported distance metric %q", o.Metric)
+	}
+	if o.VectorType != VectorTypeFloat32 && o.VectorType != VectorTypeFloat64 {
+		return fmt.Errorf("unsupported vector type %q", o.VectorType)
+	}
+	return nil
+}
+
+// schemaArgs FT.CREATE 中向量字段的定义
+func (o VectorIndexOptions) schemaArgs(dimension int) []interface{} {
+	attrs := []interface{}{
+		"TYPE", o.VectorType,
+		"DIM", dimension,
+		"DISTANCE_METRIC", o.Metric,
+	}
+	if o.Algorithm == VectorAlgorithmHNSW {
+		attrs = append(attrs,
+			"M", o.M,
+			"EF_CONSTRUCTION", o.EfConstruction,
+			"EF_RUNTIME", o.EfRuntime,
+		)
+	}
+	args := []interface{}{VectorField(o.VectorType), "VECTOR", o.Algorithm, len(attrs)}
+	return append(args, attrs...)
+}
+
+// VectorField 向量在 Hash 中的字段名
+// FLOAT32 沿用原来的 "vector" 字段，其他类型使用单独的字段，迁移向量类型时新旧字段可以并存
+func VectorField(vectorType string) string {
+	if vectorType == "" || vectorType == VectorTypeFloat32 {
+		return "vector"
+	}
+	return "vector_" + strings.ToLower(vectorType)
+}
+
+// EncodeVector 按向量类型编码为 RediSearch 使用的小端字节序
+func EncodeVector(vector []float64, vectorType string) []byte {
+	if vectorType == VectorTypeFloat64 {
+		buf := make([]byte, 8*len(vector))
+		for i, v := range vector {
+			binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(v))
+		}
+		return buf
+	}
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v)))
+	}
+	return buf
+}
+
+// DecodeVector EncodeVector 的逆过程
+func DecodeVector(data []byte, vectorType string) []float64 {
+	if vectorType == VectorTypeFloat64 {
+		vector := make([]float64, len(data)/8)
+		for i := range vector {
+			vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+		}
+		return vector
+	}
+	vector := make([]float64, len(data)/4)
+	for i := range vector {
+		vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+	}
+	return vector
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,318 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WordPiece BERT 系列模型（bge、m3e、text2vec 等）使用的分词器
+// 分词流程与 HuggingFace BertTokenizer 保持一致：
+// 1. 基础分词：清洗文本、中文按字切分、按空白和标点切分、转小写并去除重音
+// 2. WordPiece：对每个词做最长前缀匹配，子词以 "##" 开头
+type WordPiece struct {
+	vocab     map[string]int64
+	lowercase bool
+	unkID     int64
+	clsID     int64
+	sepID     int64
+	padID     int64
+}
+
+// 单个词超过该长度时直接视为 [UNK]，与 HuggingFace 默认值一致
+const maxInputCharsPerWord = 100
+
+// Encoding 模型输入
+type Encoding struct {
+	InputIDs      []int64
+	AttentionMask []int64
+	TypeIDs       []int64
+}
+
+// LoadWordPiece 加载词表，支持 vocab.txt（每行一个 token）和 tokenizer.json（WordPiece 模型）
+func LoadWordPiece(path string, lowercase bool) (*WordPiece, error) {
+	var (
+		vocab map[string]int64
+		err   error
+	)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		vocab, err = loadTokenizerJSON(path)
+	} else {
+		vocab, err = loadVocabTxt(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &WordPiece{vocab: vocab, lowercase: lowercase}
+	for token, dst := range map[string]*int64{
+		"[UNK]": &t.unkID,
+		"[CLS]": &t.clsID,
+		"[SEP]": &t.sepID,
+		"[PAD]": &t.padID,
+	} {
+		id, ok := vocab[token]
+		if !ok {
+			return nil, fmt.Errorf("special token %s not found in %s", token, path)
+		}
+		*dst = id
+	}
+	return t, nil
+}
+
+func loadVocabTxt(path string) (map[string]int64, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("open vocab file failed: %w", err)
+	}
+	defer f.Close()
+
+	vocab := make(map[string]int64)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var id int64
+	for sc.Scan() {
+		token := strings.TrimRight(sc.Text(), "\r")
+		vocab[token] = id
+		id++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read vocab file failed: %w", err)
+	}
+	if len(vocab) == 0 {
+		return nil, fmt.Errorf("empty vocab file %s", path)
+	}
+	return vocab, nil
+}
+
+func loadTokenizerJSON(path string) (map[string]int64, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("open tokenizer file failed: %w", err)
+	}
+	var tj struct {
+		Model struct {
+			Type  string           `json:"type"`
+			Vocab map[string]int64 `json:"vocab"`
+		} `json:"model"`
+	}
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return nil, fmt.Errorf("parse tokenizer file failed: %w", err)
+	}
+	if tj.Model.Type != "" && tj.Model.Type != "WordPiece" {
+		return nil, fmt.Errorf("unsupported tokenizer model type %s, only WordPiece is supported", tj.Model.Type)
+	}
+	if len(tj.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("empty vocab in %s", path)
+	}
+	return tj.Model.Vocab, nil
+}
+
+// Tokenize 将文本切分为 WordPiece token
+func (t *WordPiece) Tokenize(text string) []string {
+	tokens := make([]string, 0)
+	for _, word := range t.basicTokenize(text) {
+		tokens = append(tokens, t.wordPiece(word)...)
+	}
+	return tokens
+}
+
+// Encode 编码单个句子：[CLS] text [SEP]，超过 maxLen 时截断
+func (t *WordPiece) Encode(text string, maxLen int) Encoding {
+	ids := t.tokenIDs(text)
+	if maxLen > 2 && len(ids) > maxLen-2 {
+		ids = ids[:maxLen-2]
+	}
+
+	enc := newEncoding(len(ids) + 2)
+	enc.InputIDs = append(enc.InputIDs, t.clsID)
+	enc.InputIDs = append(enc.InputIDs, ids...)
+	enc.InputIDs = append(enc.InputIDs, t.sepID)
+	for range enc.InputIDs {
+		enc.AttentionMask = append(enc.AttentionMask, 1)
+		enc.TypeIDs = append(enc.TypeIDs, 0)
+	}
+	return enc
+}
+
+// EncodePair 编码句子对：[CLS] a [SEP] b [SEP]，用于交叉编码器（重排模型）
+// 超过 maxLen 时优先截断较长的一方
+func (t *WordPiece) EncodePair(a, b string, maxLen int) Encoding {
+	idsA := t.tokenIDs(a)
+	idsB := t.tokenIDs(b)
+	if maxLen > 3 {
+		for len(idsA)+len(idsB) > maxLen-3 {
+			if len(idsA) > len(idsB) {
+				idsA = idsA[:len(idsA)-1]
+			} else {
+				idsB = idsB[:len(idsB)-1]
+			}
+		}
+	}
+
+	enc := newEncoding(len(idsA) + len(idsB) + 3)
+	enc.InputIDs = append(enc.InputIDs, t.clsID)
+	enc.InputIDs = append(enc.InputIDs, idsA...)
+	enc.InputIDs = append(enc.InputIDs, t.sepID)
+	firstLen := len(enc.InputIDs)
+	enc.InputIDs = append(enc.InputIDs, idsB...)
+	enc.InputIDs = append(enc.InputIDs, t.sepID)
+	for i := range enc.InputIDs {
+		enc.AttentionMask = append(enc.AttentionMask, 1)
+		if i < firstLen {
+			enc.TypeIDs = append(enc.TypeIDs, 0)
+		} else {
+			enc.TypeIDs = append(enc.TypeIDs, 1)
+		}
+	}
+	return enc
+}
+
+// PadID 填充 token 的 ID
+func (t *WordPiece) PadID() int64 {
+	return t.padID
+}
+
+func newEncoding(n int) Encoding {
+	return Encoding{
+		InputIDs:      make([]int64, 0, n),
+		AttentionMask: make([]int64, 0, n),
+		TypeIDs:       make([]int64, 0, n),
+	}
+}
+
+func (t *WordPiece) tokenIDs(text string) []int64 {
+	tokens := t.Tokenize(text)
+	ids := make([]int64, len(tokens))
+	for i, token := range tokens {
+		ids[i] = t.vocab[token]
+	}
+	return ids
+}
+
+// wordPiece 贪心最长匹配，无法切分时返回 [UNK]
+func (t *WordPiece) wordPiece(word string) []string {
+	runes := []rune(word)
+	if len(runes) > maxInputCharsPerWord {
+		return []string{"[UNK]"}
+	}
+
+	tokens := make([]string, 0, 2)
+	start := 0
+	for start < len(runes) {
+		end := len(runes)
+		found := ""
+		for start < end {
+			sub := string(runes[start:end])
+			if start > 0 {
+				sub = "##" + sub
+			}
+			if _, ok := t.vocab[sub]; ok {
+				found = sub
+				break
+			}
+			end--
+		}
+		if found == "" {
+			return []string{"[UNK]"}
+		}
+		tokens = append(tokens, found)
+		start = end
+	}
+	return tokens
+}
+
+// basicTokenize 基础分词
+func (t *WordPiece) basicTokenize(text string) []string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == 0 || r == 0xFFFD || isControl(r):
+			continue
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		case isChineseChar(r):
+			// 中文按字切分
+			sb.WriteRune(' ')
+			sb.WriteRune(r)
+			sb.WriteRune(' ')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	words := make([]string, 0)
+	for _, word := range strings.Fields(sb.String()) {
+		if t.lowercase {
+			word = stripAccents(strings.ToLower(word))
+		}
+		words = append(words, splitOnPunc(word)...)
+	}
+	return words
+}
+
+// splitOnPunc 标点单独成词
+func splitOnPunc(word string) []string {
+	words := make([]string, 0, 1)
+	current := make([]rune, 0, len(word))
+	for _, r := range word {
+		if isPunctuation(r) {
+			if len(current) > 0 {
+				words = append(words, string(current))
+				current = current[:0]
+			}
+			words = append(words, string(r))
+			continue
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	return words
+}
+
+// stripAccents 去除重音符号（NFD 分解后去掉组合字符）
+func stripAccents(s string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isControl(r rune) bool {
+	if r == '\t' || r == '\n' || r == '\r' {
+		return false
+	}
+	return unicode.IsControl(r) || unicode.In(r, unicode.Cf)
+}
+
+// isPunctuation 与 BERT 保持一致：ASCII 中所有非字母数字的可见字符都视为标点
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isChineseChar 判断是否为 CJK 统一表意字符
+func isChineseChar(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
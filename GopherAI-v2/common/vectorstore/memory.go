@@ -0,0 +1,487 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MemoryStore 进程内的向量存储，KNN 为暴力计算，全文检索为简单的 BM25
+// snapshotPath 不为空时启动时从快照恢复，并按 interval 定期把有变化的数据保存到快照，interval 不大于 0 时每次写入后保存；
+// 进程退出前需要调用 Close 保存最后的变化
+// 数据只存在于当前进程，多实例部署或独立的命令行工具无法共享，只适合小规模部署和本地调试
+type MemoryStore struct {
+	mu           sync.RWMutex
+	collections  map[string]*memoryCollection
+	snapshotPath string
+	interval     time.Duration
+	version      uint64 // 每次写入加一，与 saved 不同时说明有未保存的变化
+
+	snapshotMu sync.Mutex // 保证同一时间只有一次保存
+	saved      uint64     // 最近一次保存的快照对应的 version
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type memoryCollection struct {
+	Collection Collection           `json:"collection"`
+	Docs       map[string]*Document `json:"docs"`
+}
+
+func NewMemoryStore(snapshotPath string, interval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		collections:  make(map[string]*memoryCollection),
+		snapshotPath: snapshotPath,
+		interval:     interval,
+		done:         make(chan struct{}),
+	}
+	if snapshotPath == "" {
+		return s
+	}
+	if err := s.load(); err != nil {
+		log.Printf("[vectorstore] load snapshot %s failed: %v", snapshotPath, err)
+	}
+	if interval > 0 {
+		go s.snapshotLoop()
+	}
+	return s
+}
+
+func (s *MemoryStore) snapshotLoop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				log.Printf("[vectorstore] save snapshot failed: %v", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close 停止定期保存并把未保存的变化写入快照
+func (s *MemoryStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.Snapshot()
+}
+
+// saveOnWrite 没有设置保存间隔时，写入后立即保存快照
+// 在写操作释放锁之后调用（defer 在加锁之前注册）
+func (s *MemoryStore) saveOnWrite() {
+	if s.snapshotPath == "" || s.interval > 0 {
+		return
+	}
+	if err := s.Snapshot(); err != nil {
+		log.Printf("[vectorstore] save snapshot failed: %v", err)
+	}
+}
+
+// Snapshot 将数据保存到快照文件，先写临时文件再重命名，避免写到一半时留下损坏的快照
+// 编码期间只持有读锁，检索不受影响；编码期间的写入会在 version 中留下记录，下次保存时写入
+func (s *MemoryStore) Snapshot() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.snapshotPath), 0755); err != nil {
+		return err
+	}
+	tmp := s.snapshotPath + ".tmp"
+	version, changed, err := s.encode(tmp)
+	if err != nil || !changed {
+		return err
+	}
+	if err := os.Rename(tmp, s.snapshotPath); err != nil {
+		return err
+	}
+	s.saved = version
+	return nil
+}
+
+// encode 在读锁下把数据写入 path，没有未保存的变化时不写入
+func (s *MemoryStore) encode(path string) (uint64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.version == s.saved {
+		return s.version, false, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := json.NewEncoder(f).Encode(s.collections); err != nil {
+		f.Close()
+		os.Remove(path)
+		return 0, false, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return 0, false, err
+	}
+	return s.version, true, nil
+}
+
+func (s *MemoryStore) load() error {
+	f, err := os.Open(s.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	collections := make(map[string]*memoryCollection)
+	if err := json.NewDecoder(f).Decode(&collections); err != nil {
+		return err
+	}
+	s.collections = collections
+	return nil
+}
+
+func (s *MemoryStore) CreateCollection(ctx context.Context, c *Collection) (*Collection, error) {
+	defer s.saveOnWrite()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.collections[c.Name]; ok {
+		copied := existing.Collection
+		return &copied, nil
+	}
+	opts := c.Options.Normalize()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	created := *c
+	created.Options = opts
+	s.collections[c.Name] = &memoryCollection{Collection: created, Docs: make(map[string]*Document)}
+	s.version++
+	return &created, nil
+}
+
+func (s *MemoryStore) GetCollection(ctx context.Context, name string) (*Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if existing, ok := s.collections[name]; ok {
+		copied := existing.Collection
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) DropCollection(ctx context.Context, name string) error {
+	defer s.saveOnWrite()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.collections[name]; ok {
+		delete(s.collections, name)
+		s.version++
+	}
+	return nil
+}
+
+func (s *MemoryStore) Collections(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.collections))
+	for name := range s.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemoryStore) Upsert(ctx context.Context, collection string, docs []*Document) error {
+	defer s.saveOnWrite()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return fmt.Errorf("collection %s not found", collection)
+	}
+	for _, doc := range docs {
+		if len(doc.Vector) != c.Collection.Dimension {
+			return fmt.Errorf("chunk %s has dimension %d, collection expects %d", doc.ID, len(doc.Vector), c.Collection.Dimension)
+		}
+	}
+	for _, doc := range docs {
+		copied := *doc
+		c.Docs[doc.ID] = &copied
+	}
+	s.version++
+	return nil
+}
+
+func (s *MemoryStore) UpdateMetadata(ctx context.Context, collection string, docs []*Document) error {
+	defer s.saveOnWrite()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return fmt.Errorf("collection %s not found", collection)
+	}
+	for _, doc := range docs {
+		if existing, ok := c.Docs[doc.ID]; ok {
+			existing.Metadata = doc.Metadata
+			existing.Document = doc.Document
+		}
+	}
+	s.version++
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, collection string, filter Filter) (int, error) {
+	if filter.IsEmpty() {
+		return 0, nil
+	}
+	defer s.saveOnWrite()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return 0, nil
+	}
+	deleted := 0
+	for id, doc := range c.Docs {
+		if matchFilter(doc, filter) {
+			delete(c.Docs, id)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		s.version++
+	}
+	return deleted, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, collection string, filter Filter) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, nil
+	}
+	ids := make([]string, 0)
+	for id, doc := range c.Docs {
+		if matchFilter(doc, filter) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Search 暴力计算与所有文档块的距离，距离的定义与 RediSearch 一致：
+// COSINE 为 1 - 余弦相似度，IP 为 1 - 内积，L2 为欧氏距离的平方
+func (s *MemoryStore) Search(ctx context.Context, collection string, vector []float64, topK int, filter Filter) ([]*SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, fmt.Errorf("collection %s not found", collection)
+	}
+	if len(vector) != c.Collection.Dimension {
+		return nil, fmt.Errorf("query has dimension %d, collection expects %d", len(vector), c.Collection.Dimension)
+	}
+
+	results := make([]*SearchResult, 0)
+	for _, doc := range c.Docs {
+		if !matchFilter(doc, filter) {
+			continue
+		}
+		results = append(results, &SearchResult{
+			Document: copyDocument(doc),
+			Distance: distance(c.Collection.Options.Metric, vector, doc.Vector),
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results[:min(topK, len(results))], nil
+}
+
+// BM25 参数，与 RediSearch 默认值一致
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// TextSearch 简单的 BM25 全文检索
+// 分词规则与 RediSearch 默认规则一致，中文没有分词器，按单字和相邻两字切分
+func (s *MemoryStore) TextSearch(ctx context.Context, collection, query string, topK int, filter Filter) ([]*SearchResult, error) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, fmt.Errorf("collection %s not found", collection)
+	}
+
+	type candidate struct {
+		doc    *Document
+		freqs  map[string]int
+		length int
+	}
+	candidates := make([]candidate, 0)
+	docFreq := make(map[string]int)
+	totalLength := 0
+	for _, doc := range c.Docs {
+		if !matchFilter(doc, filter) {
+			continue
+		}
+		tokens := tokenize(doc.Content)
+		freqs := make(map[string]int)
+		for _, token := range tokens {
+			freqs[token]++
+		}
+		for term := range freqs {
+			docFreq[term]++
+		}
+		candidates = append(candidates, candidate{doc: doc, freqs: freqs, length: len(tokens)})
+		totalLength += len(tokens)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	n := float64(len(candidates))
+	avgLength := float64(totalLength) / n
+	results := make([]*SearchResult, 0)
+	for _, cand := range candidates {
+		score := 0.0
+		for _, term := range uniqueStrings(terms) {
+			tf := float64(cand.freqs[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(cand.length)/avgLength))
+		}
+		if score > 0 {
+			results = append(results, &SearchResult{Document: copyDocument(cand.doc), Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results[:min(topK, len(results))], nil
+}
+
+func matchFilter(doc *Document, filter Filter) bool {
+	if len(filter.IDs) > 0 && !containsString(filter.IDs, doc.ID) {
+		return false
+	}
+	return matchDocuments(doc.ID, filter.Documents) && matchMetadata(doc.Metadata, filter)
+}
+
+// copyDocument 检索结果不返回向量，调用方修改元数据也不影响存储中的数据
+func copyDocument(doc *Document) *Document {
+	metadata := make(map[string]any, len(doc.Metadata))
+	for k, v := range doc.Metadata {
+		metadata[k] = v
+	}
+	return &Document{ID: doc.ID, Document: doc.Document, Content: doc.Content, Metadata: metadata}
+}
+
+func distance(metric string, a, b []float64) float64 {
+	switch metric {
+	case "L2":
+		sum := 0.0
+		for i := range a {
+			d := a[i] - b[i]
+			sum += d * d
+		}
+		return sum
+	case "IP":
+		return 1 - dot(a, b)
+	default:
+		normA, normB := math.Sqrt(dot(a, a)), math.Sqrt(dot(b, b))
+		if normA == 0 || normB == 0 {
+			return 1
+		}
+		return 1 - dot(a, b)/(normA*normB)
+	}
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// tokenize 按 RediSearch 默认规则拆词并转为小写，连续的中文按单字和相邻两字切分
+func tokenize(text string) []string {
+	tokens := make([]string, 0)
+	for _, term := range strings.FieldsFunc(strings.ToLower(text), isTextSeparator) {
+		runes := []rune(term)
+		start := 0
+		for start < len(runes) {
+			end := start
+			han := unicode.Is(unicode.Han, runes[start])
+			for end < len(runes) && unicode.Is(unicode.Han, runes[end]) == han {
+				end++
+			}
+			if !han {
+				tokens = append(tokens, string(runes[start:end]))
+			} else {
+				for i := start; i < end; i++ {
+					tokens = append(tokens, string(runes[i]))
+					if i+1 < end {
+						tokens = append(tokens, string(runes[i:i+2]))
+					}
+				}
+			}
+			start = end
+		}
+	}
+	return tokens
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
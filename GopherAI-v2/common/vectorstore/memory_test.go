@@ -0,0 +1,139 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 创建集合时读取 vectorIndexConfig 中的默认参数，配置文件路径相对于 GopherAI-v2 目录
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestCollection(t *testing.T, s *MemoryStore) {
+	t.Helper()
+	_, err := s.CreateCollection(context.Background(), &Collection{Name: "kb", EmbedderID: "test", Dimension: 2})
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+}
+
+func upsertTestDocument(t *testing.T, s *MemoryStore, id string) {
+	t.Helper()
+	err := s.Upsert(context.Background(), "kb", []*Document{{ID: id, Document: "doc", Content: id, Vector: []float64{1, 0}}})
+	if err != nil {
+		t.Fatalf("upsert %s: %v", id, err)
+	}
+}
+
+func listTestDocuments(t *testing.T, s *MemoryStore) []string {
+	t.Helper()
+	ids, err := s.List(context.Background(), "kb", Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	return ids
+}
+
+func TestMemoryStoreCloseSavesSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	s := NewMemoryStore(path, time.Hour)
+	newTestCollection(t, s)
+	upsertTestDocument(t, s, "doc_1")
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("snapshot written before interval elapsed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	restored := NewMemoryStore(path, time.Hour)
+	defer restored.Close()
+	if ids := listTestDocuments(t, restored); len(ids) != 1 || ids[0] != "doc_1" {
+		t.Fatalf("restored documents = %v, want [doc_1]", ids)
+	}
+}
+
+func TestMemoryStoreSavesOnWriteWithoutInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	s := NewMemoryStore(path, 0)
+	newTestCollection(t, s)
+	upsertTestDocument(t, s, "doc_1")
+
+	restored := NewMemoryStore(path, 0)
+	if ids := listTestDocuments(t, restored); len(ids) != 1 {
+		t.Fatalf("restored documents = %v, want [doc_1]", ids)
+	}
+
+	if _, err := s.Delete(context.Background(), "kb", Filter{Documents: []string{"doc"}}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	restored = NewMemoryStore(path, 0)
+	if ids := listTestDocuments(t, restored); len(ids) != 0 {
+		t.Fatalf("restored documents after delete = %v, want none", ids)
+	}
+}
+
+func TestMemoryStoreSnapshotSkipsUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	s := NewMemoryStore(path, time.Hour)
+	defer s.Close()
+	newTestCollection(t, s)
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove snapshot: %v", err)
+	}
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged store rewrote snapshot: %v", err)
+	}
+}
+
+// 保存快照的同时写入，写入的数据不能因为保存而被标记为已保存
+func TestMemoryStoreConcurrentWritesAndSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	s := NewMemoryStore(path, time.Hour)
+	newTestCollection(t, s)
+
+	const writes = 50
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			upsertTestDocument(t, s, fmt.Sprintf("doc_%d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			if err := s.Snapshot(); err != nil {
+				t.Errorf("snapshot: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	restored := NewMemoryStore(path, time.Hour)
+	defer restored.Close()
+	if ids := listTestDocuments(t, restored); len(ids) != writes {
+		t.Fatalf("restored %d documents, want %d", len(ids), writes)
+	}
+}
@@ -0,0 +1,447 @@
+package vectorstore
+
+import (
+	redisPkg "GopherAI/common/redis"
+	"GopherAI/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	redisCli "github.com/redis/go-redis/v9"
+)
+
+// RedisStore 基于 RediSearch 的向量存储
+// 每个集合对应一个索引别名和一个 key 前缀，文档块存储为 Hash：content、metadata、document 和向量字段
+type RedisStore struct{}
+
+func NewRedisStore() *RedisStore {
+	return &RedisStore{}
+}
+
+func (s *RedisStore) CreateCollection(ctx context.Context, c *Collection) (*Collection, error) {
+	meta, err := redisPkg.GetIndexMeta(ctx, c.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index meta: %w", err)
+	}
+	existed := meta != nil
+	if !existed {
+		// 记录索引使用的向量模型和索引参数，查询时据此选择同一个向量模型和向量编码
+		meta = &redisPkg.IndexMeta{
+			EmbedderID: c.EmbedderID,
+			Dimension:  c.Dimension,
+			Index:      redisPkg.GenerateVersionedIndexName(c.Name, 1),
+			Version:    1,
+			Options:    c.Options.Normalize(),
+		}
+		if err := redisPkg.SetIndexMeta(ctx, c.Name, meta); err != nil {
+			return nil, fmt.Errorf("failed to save index meta: %w", err)
+		}
+	}
+
+	// 可以理解为：先在 Redis 里建好“仓库”，
+	// 告诉它以后要存向量，并且每个向量的维度是多少
+	if err := redisPkg.InitRedisIndex(ctx, c.Name, meta); err != nil {
+		return nil, fmt.Errorf("failed to init redis index: %w", err)
+	}
+	if existed {
+		if err := redisPkg.AddIndexField(ctx, c.Name, "document", "TAG"); err != nil {
+			return nil, err
+		}
+		for _, field := range redisPkg.MetadataFields {
+			if err := redisPkg.AddIndexField(ctx, c.Name, field.Name, field.Type); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return collectionOf(c.Name, meta), nil
+}
+
+func (s *RedisStore) GetCollection(ctx context.Context, name string) (*Collection, error) {
+	meta, err := redisPkg.GetIndexMeta(ctx, name)
+	if err != nil || meta == nil {
+		return nil, err
+	}
+	return collectionOf(name, meta), nil
+}
+
+func (s *RedisStore) DropCollection(ctx context.Context, name string) error {
+	return redisPkg.DeleteRedisIndex(ctx, name)
+}
+
+func (s *RedisStore) Collections(ctx context.Context) ([]string, error) {
+	return redisPkg.ListIndexNames(ctx)
+}
+
+func (s *RedisStore) Upsert(ctx context.Context, collection string, docs []*Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	meta, err := redisPkg.GetIndexMeta(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to get index meta: %w", err)
+	}
+	if meta == nil {
+		return fmt.Errorf("collection %s not found", collection)
+	}
+	// 向量按索引实际的向量类型编码，迁移向量类型期间同时写入新旧两个字段
+	vectorTypes := []string{meta.Options.VectorType}
+	if meta.MigratingVectorType != "" && meta.MigratingVectorType != meta.Options.VectorType {
+		vectorTypes = append(vectorTypes, meta.MigratingVectorType)
+	}
+
+	pipe := redisPkg.Rdb.Pipeline()
+	for _, doc := range docs {
+		metadata, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		fields := []interface{}{
+			"content", doc.Content,
+			"metadata", string(metadata),
+			"document", doc.Document,
+		}
+		fields = append(fields, metadataFields(doc.Metadata)...)
+		for _, vectorType := range vectorTypes {
+			fields = append(fields, redisPkg.VectorField(vectorType), redisPkg.EncodeVector(doc.Vector, vectorType))
+		}
+		pipe.HSet(ctx, redisPkg.GenerateChunkKey(collection, doc.ID), fields...)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) UpdateMetadata(ctx context.Context, collection string, docs []*Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	pipe := redisPkg.Rdb.Pipeline()
+	for _, doc := range docs {
+		metadata, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		fields := []interface{}{"metadata", string(metadata), "document", doc.Document}
+		fields = append(fields, metadataFields(doc.Metadata)...)
+		pipe.HSet(ctx, redisPkg.GenerateChunkKey(collection, doc.ID), fields...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Delete(ctx context.Context, collection string, filter Filter) (int, error) {
+	if filter.IsEmpty() {
+		return 0, nil
+	}
+	keys, err := s.keys(ctx, collection, filter)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	deleted, err := redisPkg.Rdb.Del(ctx, keys...).Result()
+	return int(deleted), err
+}
+
+func (s *RedisStore) List(ctx context.Context, collection string, filter Filter) ([]string, error) {
+	keys, err := s.keys(ctx, collection, filter)
+	if err != nil {
+		return nil, err
+	}
+	// 按 ID 过滤时 key 是拼出来的，需要确认是否存在
+	if len(filter.IDs) > 0 && len(keys) > 0 {
+		pipe := redisPkg.Rdb.Pipeline()
+		cmds := make([]*redisCli.IntCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Exists(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+		existing := keys[:0]
+		for i, key := range keys {
+			if cmds[i].Val() > 0 {
+				existing = append(existing, key)
+			}
+		}
+		keys = existing
+	}
+	prefix := redisPkg.GenerateChunkKey(collection, "")
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, strings.TrimPrefix(key, prefix))
+	}
+	return ids, nil
+}
+
+// keys 匹配过滤条件的块 key，按文档过滤时按 key 前缀扫描，旧数据没有 document 字段也能匹配
+func (s *RedisStore) keys(ctx context.Context, collection string, filter Filter) ([]string, error) {
+	if filter.hasMetadata() {
+		return nil, errors.New("redis store only supports filtering by metadata in search")
+	}
+	prefix := redisPkg.GenerateChunkKey(collection, "")
+	if len(filter.IDs) > 0 {
+		keys := make([]string, 0, len(filter.IDs))
+		for _, id := range filter.IDs {
+			if matchDocuments(id, filter.Documents) {
+				keys = append(keys, prefix+id)
+			}
+		}
+		return keys, nil
+	}
+	if len(filter.Documents) == 0 {
+		return redisPkg.ScanKeys(ctx, prefix+"*")
+	}
+	keys := make([]string, 0)
+	for _, document := range filter.Documents {
+		matched, err := redisPkg.ScanKeys(ctx, prefix+document+"_*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chunks of %s: %w", document, err)
+		}
+		keys = append(keys, matched...)
+	}
+	return keys, nil
+}
+
+// Search KNN 向量检索，HNSW 索引使用创建索引时设置的 EF_RUNTIME
+// 查询元信息中记录的实际索引而不是别名，迁移索引时向量字段与索引总是一致
+func (s *RedisStore) Search(ctx context.Context, collection string, vector []float64, topK int, filter Filter) ([]*SearchResult, error) {
+	if len(filter.IDs) > 0 {
+		return nil, errors.New("redis store does not support filtering search by chunk ids")
+	}
+	meta, err := redisPkg.GetIndexMeta(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index meta: %w", err)
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("collection %s not found", collection)
+	}
+
+	knn := fmt.Sprintf("(%s)=>[KNN %d @%s $vector AS distance]",
+		filterQuery(filter), topK, redisPkg.VectorField(meta.Options.VectorType))
+	result, err := redisPkg.Rdb.FTSearchWithArgs(ctx, meta.Index, knn, &redisCli.FTSearchOptions{
+		Return:         []redisCli.FTSearchReturn{{FieldName: "content"}, {FieldName: "metadata"}, {FieldName: "document"}, {FieldName: "distance"}},
+		SortBy:         []redisCli.FTSearchSortBy{{FieldName: "distance", Asc: true}},
+		Limit:          topK,
+		Params:         map[string]any{"vector": redisPkg.EncodeVector(vector, meta.Options.VectorType)},
+		DialectVersion: 2,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return convertResults(collection, result.Docs), nil
+}
+
+// TextSearch RediSearch 全文检索，使用 BM25 打分
+func (s *RedisStore) TextSearch(ctx context.Context, collection, query string, topK int, filter Filter) ([]*SearchResult, error) {
+	if len(filter.IDs) > 0 {
+		return nil, errors.New("redis store does not support filtering search by chunk ids")
+	}
+	textQuery := buildFullTextQuery(query)
+	if textQuery == "" {
+		return nil, nil
+	}
+	if !filter.IsEmpty() {
+		textQuery = filterQuery(filter) + " " + textQuery
+	}
+	// 与 Search 一样查询元信息中记录的实际索引，迁移索引期间混合检索的两路结果来自同一个索引
+	meta, err := redisPkg.GetIndexMeta(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index meta: %w", err)
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("collection %s not found", collection)
+	}
+
+	result, err := redisPkg.Rdb.FTSearchWithArgs(ctx, meta.Index, textQuery, &redisCli.FTSearchOptions{
+		Return:         []redisCli.FTSearchReturn{{FieldName: "content"}, {FieldName: "metadata"}, {FieldName: "document"}},
+		Language:       config.GetConfig().RetrievalLanguage,
+		Scorer:         "BM25",
+		WithScores:     true,
+		Limit:          topK,
+		DialectVersion: 2,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return convertResults(collection, result.Docs), nil
+}
+
+func collectionOf(name string, meta *redisPkg.IndexMeta) *Collection {
+	return &Collection{
+		Name:       name,
+		EmbedderID: meta.EmbedderID,
+		Dimension:  meta.Dimension,
+		Options:    meta.Options,
+	}
+}
+
+// metadataFields 从块的元数据中取出可过滤字段，单独写入 Hash 以便建立索引
+// TAG 字段没有取值时写入空值，覆盖之前的取值；NUMERIC 字段没有取值时不写入，写入非数值会导致整个块无法被索引
+func metadataFields(metadata map[string]any) []interface{} {
+	fields := make([]interface{}, 0, 2*len(redisPkg.MetadataFields))
+	for _, field := range redisPkg.MetadataFields {
+		switch field.Type {
+		case redisPkg.MetadataTag:
+			fields = append(fields, field.Name, strings.Join(MetadataTagValues(metadata[field.Name]), ","))
+		case redisPkg.MetadataNumeric:
+			if value, ok := MetadataNumericValue(metadata[field.Name]); ok {
+				fields = append(fields, field.Name, strconv.FormatFloat(value, 'f', -1, 64))
+			}
+		}
+	}
+	return fields
+}
+
+// filterQuery 将过滤条件转换为 RediSearch 查询，多个条件之间为 AND
+func filterQuery(filter Filter) string {
+	clauses := make([]string, 0)
+	if len(filter.Documents) > 0 {
+		clauses = append(clauses, tagClause("document", filter.Documents))
+	}
+	for _, field := range sortedKeys(filter.Tags) {
+		if len(filter.Tags[field]) > 0 {
+			clauses = append(clauses, tagClause(field, filter.Tags[field]))
+		}
+	}
+	for _, field := range sortedKeys(filter.Ranges) {
+		r := filter.Ranges[field]
+		clauses = append(clauses, fmt.Sprintf("@%s:[%s %s]", field,
+			rangeBound(r.Min, r.ExclusiveMin), rangeBound(r.Max, r.ExclusiveMax)))
+	}
+	if len(clauses) == 0 {
+		return "*"
+	}
+	return strings.Join(clauses, " ")
+}
+
+func tagClause(field string, values []string) string {
+	escaped := make([]string, 0, len(values))
+	for _, value := range values {
+		escaped = append(escaped, escapeTag(value))
+	}
+	return "@" + field + ":{" + strings.Join(escaped, " | ") + "}"
+}
+
+// rangeBound NUMERIC 范围的边界，不限制时为 -inf / +inf，开区间在前面加 "("
+func rangeBound(v float64, exclusive bool) string {
+	var bound string
+	switch {
+	case math.IsInf(v, -1):
+		bound = "-inf"
+	case math.IsInf(v, 1):
+		bound = "+inf"
+	default:
+		bound = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if exclusive {
+		return "(" + bound
+	}
+	return bound
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeTag TAG 查询中标点和空白需要转义
+func escapeTag(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r != '_' && (unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// convertResults 将 RediSearch 的结果转换为检索结果，ID 去掉 key 前缀还原为块 ID
+func convertResults(collection string, docs []redisCli.Document) []*SearchResult {
+	prefix := redisPkg.GenerateChunkKey(collection, "")
+	results := make([]*SearchResult, 0, len(docs))
+	for _, doc := range docs {
+		result := &SearchResult{
+			Document: &Document{
+				ID:       strings.TrimPrefix(doc.ID, prefix),
+				Metadata: map[string]any{},
+			},
+		}
+		if doc.Score != nil {
+			result.Score = *doc.Score
+		}
+		for field, val := range doc.Fields {
+			switch field {
+			case "content":
+				result.Content = val
+			case "document":
+				result.Document.Document = val
+			case "distance":
+				result.Distance, _ = strconv.ParseFloat(val, 64)
+			case "metadata":
+				// 元数据为 JSON，解析失败（旧索引）时保留原始字符串
+				if err := json.Unmarshal([]byte(val), &result.Metadata); err != nil {
+					result.Metadata[field] = val
+				}
+			default:
+				result.Metadata[field] = val
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func matchDocuments(id string, documents []string) bool {
+	if len(documents) == 0 {
+		return true
+	}
+	for _, document := range documents {
+		if belongsTo(id, document) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTextSeparator 与 RediSearch 默认分词规则保持一致：标点和空白都是分隔符，下划线不是
+// 因此 ERR_NOT_FOUND、get_user_info 这类标识符会作为一个整体检索
+func isTextSeparator(r rune) bool {
+	if r == '_' {
+		return false
+	}
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// splitTerms 按分隔符拆成小写的词，去重并保持顺序
+func splitTerms(text string) []string {
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, term := range strings.FieldsFunc(text, isTextSeparator) {
+		term = strings.ToLower(term)
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// buildFullTextQuery 将用户问题转换为全文检索查询：按分隔符拆成词，任意一个词命中即可（OR），由 BM25 排序
+// 中文片段交给 RediSearch 的中文分词处理
+func buildFullTextQuery(query string) string {
+	terms := splitTerms(query)
+	if len(terms) == 0 {
+		return ""
+	}
+	return "@content:(" + strings.Join(terms, " | ") + ")"
+}
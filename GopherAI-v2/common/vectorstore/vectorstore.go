@@ -0,0 +1,214 @@
+package vectorstore
+
+import (
+	redisPkg "GopherAI/common/redis"
+	"GopherAI/config"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 向量存储后端
+const (
+	BackendRedis  = "redis"  // RediSearch，需要 Redis Stack
+	BackendMemory = "memory" // 进程内存储，可选快照到磁盘，适合小规模部署和本地调试
+)
+
+// Collection 向量集合，一个知识库索引对应一个集合
+type Collection struct {
+	Name       string
+	EmbedderID string // 构建集合时使用的向量模型，查询时必须使用同一个向量模型
+	Dimension  int
+	Options    redisPkg.VectorIndexOptions
+}
+
+// Document 集合中的一个文档块
+type Document struct {
+	ID       string
+	Document string // 所属文档标识，块 ID 以 "<文档标识>_" 开头
+	Content  string
+	Metadata map[string]any
+	Vector   []float64
+}
+
+// Filter 过滤条件，各字段之间为 AND，为空的字段不做限制
+type Filter struct {
+	IDs       []string // 块 ID
+	Documents []string // 文档标识
+	// 以下按块元数据过滤，字段必须是 redis.MetadataFields 中定义的字段
+	Tags   map[string][]string     // TAG 字段，匹配任意一个取值即可
+	Ranges map[string]NumericRange // NUMERIC 字段的取值范围
+}
+
+// NumericRange 数值范围，默认为闭区间
+type NumericRange struct {
+	Min, Max                   float64 // 不限制时为 -Inf / +Inf
+	ExclusiveMin, ExclusiveMax bool
+}
+
+// Contains 数值是否在范围内
+func (r NumericRange) Contains(v float64) bool {
+	if v < r.Min || (r.ExclusiveMin && v == r.Min) {
+		return false
+	}
+	if v > r.Max || (r.ExclusiveMax && v == r.Max) {
+		return false
+	}
+	return true
+}
+
+// IsEmpty 没有任何过滤条件
+func (f Filter) IsEmpty() bool {
+	return len(f.IDs) == 0 && len(f.Documents) == 0 && !f.hasMetadata()
+}
+
+// hasMetadata 是否有按元数据过滤的条件
+func (f Filter) hasMetadata() bool {
+	return len(f.Tags) > 0 || len(f.Ranges) > 0
+}
+
+// SearchResult 检索结果
+type SearchResult struct {
+	*Document
+	Distance float64 // KNN 检索时与查询向量的距离，越小越相似
+	Score    float64 // 全文检索时的 BM25 分数，越大越相关
+}
+
+// Store 向量存储
+type Store interface {
+	// CreateCollection 创建集合，集合已存在时沿用已有的定义并返回
+	CreateCollection(ctx context.Context, c *Collection) (*Collection, error)
+	// GetCollection 获取集合的定义，不存在时返回 nil
+	GetCollection(ctx context.Context, name string) (*Collection, error)
+	// DropCollection 删除集合及其中的所有文档块
+	DropCollection(ctx context.Context, name string) error
+	// Collections 列出所有集合的名称，包括定义已删除但还留有文档块的集合
+	Collections(ctx context.Context) ([]string, error)
+
+	// Upsert 写入文档块，ID 已存在时覆盖
+	Upsert(ctx context.Context, collection string, docs []*Document) error
+	// UpdateMetadata 只更新文档块的元数据，不重写向量
+	UpdateMetadata(ctx context.Context, collection string, docs []*Document) error
+	// Delete 删除匹配过滤条件的文档块，返回删除的数量；过滤条件为空时不删除任何数据
+	Delete(ctx context.Context, collection string, filter Filter) (int, error)
+	// List 列出匹配过滤条件的文档块 ID
+	List(ctx context.Context, collection string, filter Filter) ([]string, error)
+
+	// Search KNN 向量检索，结果按距离从小到大排列
+	Search(ctx context.Context, collection string, vector []float64, topK int, filter Filter) ([]*SearchResult, error)
+	// TextSearch 全文检索，使用 BM25 打分，结果按分数从大到小排列
+	TextSearch(ctx context.Context, collection, query string, topK int, filter Filter) ([]*SearchResult, error)
+}
+
+var (
+	defaultStore Store
+	defaultOnce  sync.Once
+)
+
+// Default 按 vectorStoreConfig 创建的全局向量存储
+func Default() Store {
+	defaultOnce.Do(func() {
+		conf := config.GetConfig().VectorStoreConfig
+		switch strings.ToLower(conf.VectorStoreBackend) {
+		case BackendMemory:
+			interval := time.Duration(conf.VectorStoreSnapshotInterval) * time.Second
+			defaultStore = NewMemoryStore(conf.VectorStoreSnapshotPath, interval)
+			log.Printf("[vectorstore] using in-memory store, snapshot: %q", conf.VectorStoreSnapshotPath)
+		default:
+			defaultStore = NewRedisStore()
+		}
+	})
+	return defaultStore
+}
+
+// Close 关闭全局向量存储，进程退出前调用；内存存储会把未保存的变化写入快照
+func Close() error {
+	if closer, ok := Default().(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// belongsTo 块 ID 是否属于指定文档
+func belongsTo(id, document string) bool {
+	return strings.HasPrefix(id, document+"_")
+}
+
+// MetadataTagValues 元数据中 TAG 字段的取值，兼容写入前的原始类型和从 JSON 解析出的类型
+func MetadataTagValues(v any) []string {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if val == "" {
+			return nil
+		}
+		return []string{val}
+	case []string:
+		return val
+	case []any:
+		values := make([]string, 0, len(val))
+		for _, item := range val {
+			values = append(values, MetadataTagValues(item)...)
+		}
+		return values
+	case float64:
+		return []string{strconv.FormatFloat(val, 'f', -1, 64)}
+	default:
+		return []string{fmt.Sprint(val)}
+	}
+}
+
+// MetadataNumericValue 元数据中 NUMERIC 字段的取值
+func MetadataNumericValue(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// matchMetadata 块的元数据是否满足过滤条件，TAG 不区分大小写，与 RediSearch 一致
+func matchMetadata(metadata map[string]any, filter Filter) bool {
+	for field, wanted := range filter.Tags {
+		if len(wanted) == 0 {
+			continue
+		}
+		matched := false
+		for _, value := range MetadataTagValues(metadata[field]) {
+			for _, w := range wanted {
+				if strings.EqualFold(value, w) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for field, r := range filter.Ranges {
+		value, ok := MetadataNumericValue(metadata[field])
+		if !ok || !r.Contains(value) {
+			return false
+		}
+	}
+	return true
+}
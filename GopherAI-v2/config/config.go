@@ -49,11 +49,36 @@ type Rabbitmq struct {
 }
 
 type RagModelConfig struct {
-	RagEmbeddingModel string `toml:"embeddingModel"`
-	RagChatModelName  string `toml:"chatModelName"`
-	RagDocDir         string `toml:"docDir"`
-	RagBaseUrl        string `toml:"baseUrl"`
-	RagDimension      int    `toml:"dimension"`
+	RagEmbeddingModel   string `toml:"embeddingModel"`
+	RagChatModelName    string `toml:"chatModelName"`
+	RagDocDir           string `toml:"docDir"` // 团队文档目录，启动时导入为所有用户可检索的共享知识库，为空时不导入
+	RagBaseUrl          string `toml:"baseUrl"`
+	RagDimension        int    `toml:"dimension"`
+	RagDocWatchInterval int    `toml:"docWatchInterval"` // 检查 docDir 变化的间隔（秒），0 表示只在启动时导入
+}
+
+// EmbeddingProvider 一个可用的向量模型
+type EmbeddingProvider struct {
+	ID        string `toml:"id"`       // 唯一标识，会记录在索引元信息中
+	Provider  string `toml:"provider"` // ark | openai | ollama | onnx
+	Model     string `toml:"model"`
+	BaseUrl   string `toml:"baseUrl"`
+	ApiKeyEnv string `toml:"apiKeyEnv"` // 从哪个环境变量读取 API Key
+	Dimension int    `toml:"dimension"` // 0 表示首次使用时自动探测；openai 只在指定时随请求发送 dimensions
+	BatchSize int    `toml:"batchSize"` // 单次请求最多向量化的文本条数
+
+	// 以下仅 onnx 本地向量模型使用
+	ModelPath    string `toml:"modelPath"`    // onnx 模型文件
+	VocabPath    string `toml:"vocabPath"`    // WordPiece 词表（vocab.txt 或 tokenizer.json）
+	MaxSeqLength int    `toml:"maxSeqLength"` // 最大 token 数，默认 512
+	Pooling      string `toml:"pooling"`      // mean | cls，默认 mean
+	Concurrency  int    `toml:"concurrency"`  // 同时推理的请求数，默认 CPU 核数
+}
+
+// EmbeddingConfig 向量模型配置，未配置 providers 时沿用 ragModelConfig 中的向量模型
+type EmbeddingConfig struct {
+	EmbeddingDefault   string              `toml:"default"` // 新建索引使用的向量模型 ID
+	EmbeddingProviders []EmbeddingProvider `toml:"providers"`
 }
 
 type VoiceServiceConfig struct {
@@ -61,6 +86,126 @@ type VoiceServiceConfig struct {
 	VoiceServiceSecretKey string `toml:"voiceServiceSecretKey"`
 }
 
+// OllamaOptions 对应 Ollama 的 options 参数，零值表示使用模型默认值
+type OllamaOptions struct {
+	Temperature   float32  `toml:"temperature"`
+	TopP          float32  `toml:"topP"`
+	TopK          int      `toml:"topK"`
+	NumCtx        int      `toml:"numCtx"`
+	NumPredict    int      `toml:"numPredict"`
+	RepeatPenalty float32  `toml:"repeatPenalty"`
+	Seed          int      `toml:"seed"`
+	Stop          []string `toml:"stop"`
+}
+
+type OllamaConfig struct {
+	OllamaBaseUrl   string        `toml:"baseUrl"`
+	OllamaModelName string        `toml:"modelName"` // 默认模型，请求中可以指定其他已安装的模型
+	OllamaKeepAlive string        `toml:"keepAlive"` // 模型在内存中保留的时长，如 "10m"、"-1"（常驻）
+	OllamaTimeout   int           `toml:"timeout"`   // 请求超时时间（秒），0 表示不超时
+	OllamaOptions   OllamaOptions `toml:"options"`
+}
+
+// ChunkConfig 文档切块配置
+type ChunkConfig struct {
+	ChunkSplitter string `toml:"splitter"`     // auto | recursive | markdown | sentence，auto 根据文件类型选择
+	ChunkSize     int    `toml:"chunkSize"`    // 每块最大字符数
+	ChunkOverlap  int    `toml:"chunkOverlap"` // 相邻块重叠的字符数
+}
+
+// RetrievalConfig 检索配置
+type RetrievalConfig struct {
+	RetrievalMode         string  `toml:"mode"`         // hybrid | vector | fulltext，默认 hybrid
+	RetrievalTopK         int     `toml:"topK"`         // 最终返回的文档块数量
+	RetrievalCandidates   int     `toml:"candidates"`   // 每一路召回的候选数量
+	RetrievalVectorWeight float64 `toml:"vectorWeight"` // 向量检索在 RRF 融合中的权重
+	RetrievalTextWeight   float64 `toml:"textWeight"`   // 全文检索（BM25）在 RRF 融合中的权重
+	RetrievalRRFK         int     `toml:"rrfK"`         // RRF 平滑常数，越大排名靠后的结果影响越大
+	RetrievalLanguage     string  `toml:"language"`     // 全文索引分词语言，chinese 使用中文分词
+	RetrievalMaxDistance  float64 `toml:"maxDistance"`  // 向量距离阈值，距离更大的块视为不相关（COSINE 距离范围 0~2），0 表示不过滤
+	RetrievalNoAnswerMode string  `toml:"noAnswerMode"` // 知识库中没有相关内容时的处理：strict 拒绝回答 | fallback 提示后直接由模型回答，默认 fallback
+}
+
+// RerankConfig 重排配置，检索得到 depth 个候选后重新打分，保留 retrievalConfig.topK 个
+type RerankConfig struct {
+	RerankEnabled   bool   `toml:"enabled"`
+	RerankProvider  string `toml:"provider"`  // api | onnx
+	RerankDepth     int    `toml:"depth"`     // 参与重排的候选数量
+	RerankModel     string `toml:"model"`     // api 使用的模型名
+	RerankBaseUrl   string `toml:"baseUrl"`   // api 完整地址
+	RerankApiFormat string `toml:"apiFormat"` // dashscope | openai（Jina / Cohere 兼容的 /rerank 接口）
+	RerankApiKeyEnv string `toml:"apiKeyEnv"` // 从哪个环境变量读取 API Key
+
+	// 以下仅 onnx 本地交叉编码器使用
+	RerankModelPath    string `toml:"modelPath"`
+	RerankVocabPath    string `toml:"vocabPath"` // WordPiece 词表（vocab.txt 或 tokenizer.json）
+	RerankMaxSeqLength int    `toml:"maxSeqLength"`
+	RerankBatchSize    int    `toml:"batchSize"`
+	RerankConcurrency  int    `toml:"concurrency"`
+}
+
+// QueryRewriteConfig 检索前的查询改写配置，改写、扩展和 HyDE 都需要额外调用一次模型
+type QueryRewriteConfig struct {
+	QueryRewriteEnabled    bool   `toml:"enabled"`    // 结合对话历史把追问改写为独立的检索查询
+	QueryRewriteHistory    int    `toml:"history"`    // 改写时参考的最近消息条数
+	QueryRewriteMultiQuery int    `toml:"multiQuery"` // 额外生成的查询表述数量，0 表示不扩展
+	QueryRewriteHyDE       bool   `toml:"hyde"`       // 生成假设性回答，用其向量检索
+	QueryRewriteModelName  string `toml:"modelName"`  // 使用的模型，为空时使用 ragModelConfig.chatModelName
+}
+
+// VectorIndexConfig 新建知识库索引时的默认向量索引参数，知识库可以单独指定
+type VectorIndexConfig struct {
+	VectorIndexAlgorithm      string `toml:"algorithm"`      // FLAT | HNSW
+	VectorIndexMetric         string `toml:"metric"`         // COSINE | L2 | IP
+	VectorIndexType           string `toml:"vectorType"`     // FLOAT32 | FLOAT64
+	VectorIndexM              int    `toml:"m"`              // HNSW 每个节点的最大邻居数
+	VectorIndexEfConstruction int    `toml:"efConstruction"` // HNSW 构建时的候选数量
+	VectorIndexEfRuntime      int    `toml:"efRuntime"`      // HNSW 查询时的候选数量
+}
+
+// VectorStoreConfig 向量存储配置
+type VectorStoreConfig struct {
+	VectorStoreBackend          string `toml:"backend"`          // redis | memory，默认 redis
+	VectorStoreSnapshotPath     string `toml:"snapshotPath"`     // memory：快照文件路径，为空时不持久化
+	VectorStoreSnapshotInterval int    `toml:"snapshotInterval"` // memory：保存快照的间隔（秒），0 表示每次写入后立即保存
+}
+
+// StorageConfig 上传文件的存储配置
+type StorageConfig struct {
+	StorageQuotaMB           int64 `toml:"quotaMB"`           // 每个用户上传文档的总大小上限（MB），0 表示不限制
+	StorageReconcileInterval int   `toml:"reconcileInterval"` // 对账任务的间隔（分钟），0 表示不定期执行，仍可通过 cmd/reconcile 手动执行
+	StorageReconcileFix      bool  `toml:"reconcileFix"`      // 定期对账时修复差异，默认只报告
+	StorageReconcileGrace    int   `toml:"reconcileGrace"`    // 最近修改过的文件和文档在这段时间（分钟）内不计入差异，避免误删正在上传或入库的数据
+}
+
+type PromptConfig struct {
+	PromptDir           string `toml:"dir"`           // 模板目录，结构为 <dir>/<模板名>/<语言>[@<模型名>].tmpl
+	PromptDefaultLocale string `toml:"defaultLocale"` // 默认语言
+	PromptCacheSeconds  int    `toml:"cacheSeconds"`  // 模板缓存时间，多实例部署时其他实例最多延迟该时间生效
+}
+
+type AdminConfig struct {
+	AdminUsernames []string `toml:"usernames"` // 管理员账号
+}
+
+type OnnxConfig struct {
+	OnnxLibraryPath string `toml:"libraryPath"` // onnxruntime 动态库路径，为空时使用默认路径
+}
+
+type PIIRule struct {
+	Name    string `toml:"name"`
+	Locale  string `toml:"locale"`
+	Pattern string `toml:"pattern"`
+}
+
+// PIIConfig 发送给外部模型前的敏感信息脱敏配置
+type PIIConfig struct {
+	PIIEnabled         bool      `toml:"enabled"`
+	PIISkipLocalModels bool      `toml:"skipLocalModels"` // 本地模型（如 Ollama）不做脱敏
+	PIILocales         []string  `toml:"locales"`         // 启用哪些地区的内置规则
+	PIIRules           []PIIRule `toml:"rules"`           // 自定义规则，同地区同名时覆盖内置规则
+}
+
 type Config struct {
 	EmailConfig        `toml:"emailConfig"`
 	RedisConfig        `toml:"redisConfig"`
@@ -70,18 +215,33 @@ type Config struct {
 	Rabbitmq           `toml:"rabbitmqConfig"`
 	RagModelConfig     `toml:"ragModelConfig"`
 	VoiceServiceConfig `toml:"voiceServiceConfig"`
+	PIIConfig          `toml:"piiConfig"`
+	OllamaConfig       `toml:"ollamaConfig"`
+	EmbeddingConfig    `toml:"embeddingConfig"`
+	ChunkConfig        `toml:"chunkConfig"`
+	RetrievalConfig    `toml:"retrievalConfig"`
+	RerankConfig       `toml:"rerankConfig"`
+	QueryRewriteConfig `toml:"queryRewriteConfig"`
+	VectorIndexConfig  `toml:"vectorIndexConfig"`
+	VectorStoreConfig  `toml:"vectorStoreConfig"`
+	StorageConfig      `toml:"storageConfig"`
+	OnnxConfig         `toml:"onnxConfig"`
+	PromptConfig       `toml:"promptConfig"`
+	AdminConfig        `toml:"adminConfig"`
 }
 
 type RedisKeyConfig struct {
 	CaptchaPrefix   string
 	IndexName       string
 	IndexNamePrefix string
+	IndexMeta       string
 }
 
 var DefaultRedisKeyConfig = RedisKeyConfig{
 	CaptchaPrefix:   "captcha:%s",
 	IndexName:       "rag_docs:%s:idx",
 	IndexNamePrefix: "rag_docs:%s:",
+	IndexMeta:       "rag_meta:%s",
 }
 
 var config *Config
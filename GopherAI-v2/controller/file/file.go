@@ -3,18 +3,62 @@ package file
 import (
 	"GopherAI/common/code"
 	"GopherAI/controller"
+	"GopherAI/model"
 	"GopherAI/service/file"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type (
 	UploadFileResponse struct {
-		FilePath string `json:"file_path,omitempty"`
+		FilePath  string           `json:"file_path,omitempty"`
+		Document  *model.Document  `json:"document,omitempty"`
+		Job       *model.IngestJob `json:"job,omitempty"` // 入库任务，通过 /file/jobs/:id 查询进度
+		Duplicate bool             `json:"duplicate"`     // 知识库中已有内容相同的文档，Document 为已有文档；它没有入库成功时重新入库，Job 为对应的任务
 		controller.Response
 	}
+
+	IngestJobResponse struct {
+		Job *model.IngestJob `json:"job,omitempty"`
+		controller.Response
+	}
+
+	ListDocumentsResponse struct {
+		Documents []model.Document   `json:"documents"`
+		Usage     *file.StorageUsage `json:"usage,omitempty"`
+		controller.Response
+	}
+
+	DocumentResponse struct {
+		Document *model.Document `json:"document,omitempty"`
+		controller.Response
+	}
+
+	RenameDocumentRequest struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	SearchRequest struct {
+		Query            string `json:"query" binding:"required"`
+		KnowledgeBaseIDs []uint `json:"kb_ids"` // 在哪些知识库中检索，不传时使用默认知识库
+		Filter           string `json:"filter"` // 过滤条件，如 tags:faq lang:zh，语法同对话接口
+	}
+
+	SearchResponse struct {
+		*file.SearchResult
+		controller.Response
+	}
+
+	UpdateDocumentMetadataRequest struct {
+		Tags     []string `json:"tags"`     // 文档标签，检索时可通过 tags:<标签> 过滤
+		Language string   `json:"language"` // 文档语言，如 zh、en
+	}
 )
 
 func UploadRagFile(c *gin.Context) {
@@ -33,8 +77,31 @@ func UploadRagFile(c *gin.Context) {
 		return
 	}
 
-	//indexer 会在 service 层根据实际文件名创建
-	filePath, err := file.UploadRagFile(username, uploadedFile)
+	// 上传到哪个知识库，不传时上传到默认知识库
+	var knowledgeBaseID uint64
+	if kbID := c.PostForm("kb_id"); kbID != "" {
+		if knowledgeBaseID, err = strconv.ParseUint(kbID, 10, 64); err != nil {
+			c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+			return
+		}
+	}
+
+	// 文档标签（逗号分隔）和语言，写入块的元数据用于检索过滤
+	var tags []string
+	if raw := c.PostForm("tags"); raw != "" {
+		tags = strings.Split(raw, ",")
+	}
+
+	// 文件保存后立即返回，切块和向量化由入库任务异步完成
+	doc, job, duplicate, err := file.UploadRagFile(username, uint(knowledgeBaseID), uploadedFile, tags, c.PostForm("language"))
+	if errors.Is(err, file.ErrQuotaExceeded) {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeQuotaExceeded))
+		return
+	}
+	if errors.Is(err, file.ErrInvalidMetadata) {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
 	if err != nil {
 		log.Println("UploadFile fail ", err)
 		c.JSON(http.StatusOK, res.CodeOf(code.CodeServerBusy))
@@ -42,6 +109,188 @@ func UploadRagFile(c *gin.Context) {
 	}
 
 	res.Success()
-	res.FilePath = filePath
+	res.FilePath = doc.FilePath
+	res.Document = doc
+	res.Job = job
+	res.Duplicate = duplicate
+	c.JSON(http.StatusOK, res)
+}
+
+func GetIngestJob(c *gin.Context) {
+	res := new(IngestJobResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	job, code_ := file.GetIngestJob(c.GetString("userName"), id)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Job = job
+	c.JSON(http.StatusOK, res)
+}
+
+func RetryIngestJob(c *gin.Context) {
+	res := new(IngestJobResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	job, code_ := file.RetryIngestJob(c.GetString("userName"), id)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Job = job
+	c.JSON(http.StatusOK, res)
+}
+
+// ListDocuments 列出用户上传的文档及存储空间使用情况，可通过 kb_id 只列出某个知识库的文档
+func ListDocuments(c *gin.Context) {
+	res := new(ListDocumentsResponse)
+	var knowledgeBaseID uint64
+	if kbID := c.Query("kb_id"); kbID != "" {
+		var err error
+		if knowledgeBaseID, err = strconv.ParseUint(kbID, 10, 64); err != nil {
+			c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+			return
+		}
+	}
+
+	docs, usage, code_ := file.ListDocuments(c.GetString("userName"), uint(knowledgeBaseID))
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Documents = docs
+	res.Usage = usage
+	c.JSON(http.StatusOK, res)
+}
+
+// DownloadDocument 下载文档的原始文件
+func DownloadDocument(c *gin.Context) {
+	res := new(controller.Response)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	doc, code_ := file.GetDocument(c.GetString("userName"), id)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+	if _, err := os.Stat(doc.FilePath); err != nil {
+		log.Println("DownloadDocument stat fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeRecordNotFound))
+		return
+	}
+
+	c.FileAttachment(doc.FilePath, doc.FileName)
+}
+
+// DeleteDocument 删除文档及其在索引中的所有块
+func DeleteDocument(c *gin.Context) {
+	res := new(controller.Response)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	c.JSON(http.StatusOK, res.CodeOf(file.DeleteDocument(c.GetString("userName"), id)))
+}
+
+// RenameDocument 修改文档的显示名称
+func RenameDocument(c *gin.Context) {
+	req := new(RenameDocumentRequest)
+	res := new(DocumentResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("RenameDocument bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	doc, code_ := file.RenameDocument(c.GetString("userName"), id, req.Name)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Document = doc
 	c.JSON(http.StatusOK, res)
 }
+
+// UpdateDocumentMetadata 修改文档的标签和语言，返回重新入库的任务
+func UpdateDocumentMetadata(c *gin.Context) {
+	req := new(UpdateDocumentMetadataRequest)
+	res := new(UploadFileResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("UpdateDocumentMetadata bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	doc, job, code_ := file.UpdateDocumentMetadata(c.GetString("userName"), id, req.Tags, req.Language)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Document = doc
+	res.Job = job
+	c.JSON(http.StatusOK, res)
+}
+
+// SearchDocuments 按对话使用的检索流程检索知识库，返回带分数的块和各阶段耗时，不调用模型
+func SearchDocuments(c *gin.Context) {
+	req := new(SearchRequest)
+	res := new(SearchResponse)
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("SearchDocuments bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	result, code_ := file.SearchDocuments(c.Request.Context(), c.GetString("userName"), req.KnowledgeBaseIDs, req.Query, req.Filter)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.SearchResult = result
+	c.JSON(http.StatusOK, res)
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
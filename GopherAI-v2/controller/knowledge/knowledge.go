@@ -0,0 +1,119 @@
+package knowledge
+
+import (
+	"GopherAI/common/code"
+	"GopherAI/controller"
+	"GopherAI/model"
+	"GopherAI/service/knowledge"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	CreateKnowledgeBaseRequest struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description,omitempty"`
+		// 向量索引参数，不传时使用配置文件中的默认值，创建后可通过 cmd/migrate-index 修改
+		VectorIndex model.VectorIndexSettings `json:"vector_index,omitempty"`
+	}
+
+	KnowledgeBaseResponse struct {
+		KnowledgeBase *model.KnowledgeBase `json:"knowledge_base,omitempty"`
+		controller.Response
+	}
+	ListKnowledgeBasesResponse struct {
+		KnowledgeBases []model.KnowledgeBase `json:"knowledge_bases"`
+		controller.Response
+	}
+	ListDocumentsResponse struct {
+		Documents []model.Document `json:"documents"`
+		controller.Response
+	}
+)
+
+func CreateKnowledgeBase(c *gin.Context) {
+	req := new(CreateKnowledgeBaseRequest)
+	res := new(KnowledgeBaseResponse)
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("CreateKnowledgeBase bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	kb, code_ := knowledge.CreateKnowledgeBase(c.GetString("userName"), req.Name, req.Description, req.VectorIndex)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.KnowledgeBase = kb
+	c.JSON(http.StatusOK, res)
+}
+
+func ListKnowledgeBases(c *gin.Context) {
+	res := new(ListKnowledgeBasesResponse)
+	kbs, code_ := knowledge.ListKnowledgeBases(c.GetString("userName"))
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.KnowledgeBases = kbs
+	c.JSON(http.StatusOK, res)
+}
+
+// SetDefaultKnowledgeBase 设置对话时默认检索的知识库
+func SetDefaultKnowledgeBase(c *gin.Context) {
+	res := new(controller.Response)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	c.JSON(http.StatusOK, res.CodeOf(knowledge.SetDefaultKnowledgeBase(c.GetString("userName"), id)))
+}
+
+func DeleteKnowledgeBase(c *gin.Context) {
+	res := new(controller.Response)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	c.JSON(http.StatusOK, res.CodeOf(knowledge.DeleteKnowledgeBase(c.GetString("userName"), id)))
+}
+
+// ListDocuments 列出知识库中的文档
+func ListDocuments(c *gin.Context) {
+	res := new(ListDocumentsResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	docs, code_ := knowledge.ListDocuments(c.GetString("userName"), id)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Documents = docs
+	c.JSON(http.StatusOK, res)
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,36 @@
+package ollama
+
+import (
+	"GopherAI/common/code"
+	"GopherAI/common/ollama"
+	"GopherAI/controller"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ListModelsResponse struct {
+		Models       []ollama.Model `json:"models"`
+		DefaultModel string         `json:"default_model,omitempty"` // 配置中的默认模型
+		controller.Response
+	}
+)
+
+// ListModels 列出 Ollama 主机上已安装的模型
+func ListModels(c *gin.Context) {
+	res := new(ListModelsResponse)
+
+	models, err := ollama.ListModels(c)
+	if err != nil {
+		log.Println("ListModels fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.AIModelCannotOpen))
+		return
+	}
+
+	res.Success()
+	res.Models = models
+	res.DefaultModel = ollama.GetDefaultModelName()
+	c.JSON(http.StatusOK, res)
+}
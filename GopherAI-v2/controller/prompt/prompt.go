@@ -0,0 +1,201 @@
+package prompt
+
+import (
+	"GopherAI/common/code"
+	promptTpl "GopherAI/common/prompt"
+	"GopherAI/controller"
+	"GopherAI/model"
+	"GopherAI/service/prompt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	CreatePromptRequest struct {
+		Name        string `json:"name" binding:"required"`
+		Locale      string `json:"locale,omitempty"`
+		ModelName   string `json:"model_name,omitempty"` // 为空表示对所有模型生效
+		Content     string `json:"content" binding:"required"`
+		Description string `json:"description,omitempty"`
+	}
+	UpdatePromptRequest struct {
+		Content     string `json:"content" binding:"required"`
+		Description string `json:"description,omitempty"`
+	}
+	PreviewPromptRequest struct {
+		Name      string         `json:"name" binding:"required"`
+		Content   string         `json:"content,omitempty"` // 为空时预览当前生效的模板
+		Locale    string         `json:"locale,omitempty"`
+		ModelName string         `json:"model_name,omitempty"`
+		Variables map[string]any `json:"variables,omitempty"`
+	}
+
+	ListPromptsResponse struct {
+		Templates []model.PromptTemplate       `json:"templates"`
+		Variables map[string]map[string]string `json:"variables"` // 各内置模板可用的变量说明
+		controller.Response
+	}
+	PromptResponse struct {
+		Template *model.PromptTemplate `json:"template,omitempty"`
+		controller.Response
+	}
+	PreviewPromptResponse struct {
+		Text  string `json:"text,omitempty"`
+		Error string `json:"error,omitempty"` // 模板语法或变量错误
+		controller.Response
+	}
+)
+
+func ListPrompts(c *gin.Context) {
+	res := new(ListPromptsResponse)
+	templates, code_ := prompt.ListPromptTemplates(c.Query("name"))
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Templates = templates
+	res.Variables = promptTpl.Variables
+	c.JSON(http.StatusOK, res)
+}
+
+func GetPrompt(c *gin.Context) {
+	res := new(PromptResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	template, code_ := prompt.GetPromptTemplate(id)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Template = template
+	c.JSON(http.StatusOK, res)
+}
+
+func CreatePrompt(c *gin.Context) {
+	req := new(CreatePromptRequest)
+	res := new(PromptResponse)
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("CreatePrompt bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	template, code_ := prompt.CreatePromptTemplate(c.GetString("userName"), &model.PromptTemplate{
+		Name:        req.Name,
+		Locale:      req.Locale,
+		ModelName:   req.ModelName,
+		Content:     req.Content,
+		Description: req.Description,
+	})
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Template = template
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdatePrompt 修改模板，会生成一个新版本
+func UpdatePrompt(c *gin.Context) {
+	req := new(UpdatePromptRequest)
+	res := new(PromptResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("UpdatePrompt bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	template, code_ := prompt.UpdatePromptTemplate(c.GetString("userName"), id, req.Content, req.Description)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Template = template
+	c.JSON(http.StatusOK, res)
+}
+
+func DeletePrompt(c *gin.Context) {
+	res := new(controller.Response)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	if code_ := prompt.DeletePromptTemplate(id); code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+	c.JSON(http.StatusOK, res.CodeOf(code.CodeSuccess))
+}
+
+// ActivatePrompt 将指定版本设为生效
+func ActivatePrompt(c *gin.Context) {
+	res := new(PromptResponse)
+	id, ok := parseID(c)
+	if !ok {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	template, code_ := prompt.ActivatePromptTemplate(id)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.Template = template
+	c.JSON(http.StatusOK, res)
+}
+
+// PreviewPrompt 使用示例变量渲染模板
+func PreviewPrompt(c *gin.Context) {
+	req := new(PreviewPromptRequest)
+	res := new(PreviewPromptResponse)
+	if err := c.ShouldBindJSON(req); err != nil {
+		log.Println("PreviewPrompt bind fail ", err)
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	text, err := prompt.PreviewPromptTemplate(req.Name, req.Content, req.Locale, req.ModelName, req.Variables)
+	if err != nil {
+		res.CodeOf(code.CodeInvalidParams)
+		res.Error = err.Error()
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	res.Success()
+	res.Text = text
+	c.JSON(http.StatusOK, res)
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
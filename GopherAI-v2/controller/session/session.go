@@ -17,24 +17,39 @@ type (
 		Sessions []model.SessionInfo `json:"sessions,omitempty"`
 	}
 	CreateSessionAndSendMessageRequest struct {
-		UserQuestion string `json:"question" binding:"required"`  // 用户问题;
-		ModelType    string `json:"modelType" binding:"required"` // 模型类型;
+		UserQuestion     string `json:"question" binding:"required"`  // 用户问题;
+		ModelType        string `json:"modelType" binding:"required"` // 模型类型;
+		ModelName        string `json:"modelName,omitempty"`          // 模型名称（可选，本地 Ollama 模型使用）;
+		Filter           string `json:"filter,omitempty"`             // 检索过滤条件（可选），如 "tags:release-notes updated_at>=2025-01-01";
+		KnowledgeBaseIDs []uint `json:"knowledgeBaseIds,omitempty"`   // 会话关联的知识库（可选），0 表示默认知识库，之后可以修改;
 	}
 
 	CreateSessionAndSendMessageResponse struct {
-		AiInformation string `json:"Information,omitempty"` // AI回答
-		SessionID     string `json:"sessionId,omitempty"`   // 当前会话ID
+		AiInformation string           `json:"Information,omitempty"` // AI回答
+		SessionID     string           `json:"sessionId,omitempty"`   // 当前会话ID
+		Citations     []model.Citation `json:"citations,omitempty"`   // 回答引用的文档
 		controller.Response
 	}
 
 	ChatSendRequest struct {
 		UserQuestion string `json:"question" binding:"required"`            // 用户问题;
 		ModelType    string `json:"modelType" binding:"required"`           // 模型类型;
+		ModelName    string `json:"modelName,omitempty"`                    // 模型名称（可选，本地 Ollama 模型使用）;
+		Filter       string `json:"filter,omitempty"`                       // 检索过滤条件（可选），如 "doc_id:12|15";
 		SessionID    string `json:"sessionId,omitempty" binding:"required"` // 当前会话ID
 	}
 
 	ChatSendResponse struct {
-		AiInformation string `json:"Information,omitempty"` // AI回答
+		AiInformation string           `json:"Information,omitempty"` // AI回答
+		Citations     []model.Citation `json:"citations,omitempty"`   // 回答引用的文档
+		controller.Response
+	}
+
+	SessionKnowledgeBasesRequest struct {
+		KnowledgeBaseIDs []uint `json:"knowledgeBaseIds"` // 会话关联的知识库，为空表示不再检索
+	}
+	SessionKnowledgeBasesResponse struct {
+		KnowledgeBaseIDs []uint `json:"knowledgeBaseIds"`
 		controller.Response
 	}
 
@@ -71,7 +86,7 @@ func CreateSessionAndSendMessage(c *gin.Context) {
 		return
 	}
 	//内部会创建会话并发送消息，并会将AI回答、当前会话返回
-	session_id, aiInformation, code_ := session.CreateSessionAndSendMessage(userName, req.UserQuestion, req.ModelType)
+	session_id, aiInformation, citations, code_ := session.CreateSessionAndSendMessage(userName, req.UserQuestion, req.ModelType, req.ModelName, req.Filter, req.KnowledgeBaseIDs)
 
 	if code_ != code.CodeSuccess {
 		c.JSON(http.StatusOK, res.CodeOf(code_))
@@ -81,6 +96,7 @@ func CreateSessionAndSendMessage(c *gin.Context) {
 	res.Success()
 	res.AiInformation = aiInformation
 	res.SessionID = session_id
+	res.Citations = citations
 	c.JSON(http.StatusOK, res)
 }
 
@@ -100,7 +116,7 @@ func CreateStreamSessionAndSendMessage(c *gin.Context) {
 	c.Header("X-Accel-Buffering", "no") // 禁止代理缓存
 
 	// 先创建会话并立即把 sessionId 下发给前端，随后再开始流式输出
-	sessionID, code_ := session.CreateStreamSessionOnly(userName, req.UserQuestion)
+	sessionID, code_ := session.CreateStreamSessionOnly(userName, req.UserQuestion, req.KnowledgeBaseIDs)
 	if code_ != code.CodeSuccess {
 		c.SSEvent("error", gin.H{"message": "Failed to create session"})
 		return
@@ -111,7 +127,7 @@ func CreateStreamSessionAndSendMessage(c *gin.Context) {
 	c.Writer.Flush()
 
 	// 然后开始把本次回答进行流式发送（包含最后的 [DONE]）
-	code_ = session.StreamMessageToExistingSession(userName, sessionID, req.UserQuestion, req.ModelType, http.ResponseWriter(c.Writer))
+	code_ = session.StreamMessageToExistingSession(userName, sessionID, req.UserQuestion, req.ModelType, req.ModelName, req.Filter, http.ResponseWriter(c.Writer))
 	if code_ != code.CodeSuccess {
 		c.SSEvent("error", gin.H{"message": "Failed to send message"})
 		return
@@ -127,7 +143,7 @@ func ChatSend(c *gin.Context) {
 		return
 	}
 	// 发送消息，并会将AI回答返回
-	aiInformation, code_ := session.ChatSend(userName, req.SessionID, req.UserQuestion, req.ModelType)
+	aiInformation, citations, code_ := session.ChatSend(userName, req.SessionID, req.UserQuestion, req.ModelType, req.ModelName, req.Filter)
 
 	if code_ != code.CodeSuccess {
 		c.JSON(http.StatusOK, res.CodeOf(code_))
@@ -136,6 +152,7 @@ func ChatSend(c *gin.Context) {
 
 	res.Success()
 	res.AiInformation = aiInformation
+	res.Citations = citations
 	c.JSON(http.StatusOK, res)
 }
 
@@ -155,7 +172,7 @@ func ChatStreamSend(c *gin.Context) {
 	c.Header("X-Accel-Buffering", "no") // 禁止代理缓存
 
 
-	code_ := session.ChatStreamSend(userName, req.SessionID, req.UserQuestion, req.ModelType, http.ResponseWriter(c.Writer))
+	code_ := session.ChatStreamSend(userName, req.SessionID, req.UserQuestion, req.ModelType, req.ModelName, req.Filter, http.ResponseWriter(c.Writer))
 	if code_ != code.CodeSuccess {
 		c.SSEvent("error", gin.H{"message": "Failed to send message"})
 		return
@@ -181,3 +198,37 @@ func ChatHistory(c *gin.Context) {
 	res.History = history
 	c.JSON(http.StatusOK, res)
 }
+
+// GetSessionKnowledgeBases 获取会话关联的知识库
+func GetSessionKnowledgeBases(c *gin.Context) {
+	res := new(SessionKnowledgeBasesResponse)
+	knowledgeBaseIDs, code_ := session.GetSessionKnowledgeBases(c.GetString("userName"), c.Param("id"))
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.KnowledgeBaseIDs = knowledgeBaseIDs
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdateSessionKnowledgeBases 修改会话关联的知识库，可以与任意模型组合使用
+func UpdateSessionKnowledgeBases(c *gin.Context) {
+	req := new(SessionKnowledgeBasesRequest)
+	res := new(SessionKnowledgeBasesResponse)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusOK, res.CodeOf(code.CodeInvalidParams))
+		return
+	}
+
+	knowledgeBaseIDs, code_ := session.UpdateSessionKnowledgeBases(c.GetString("userName"), c.Param("id"), req.KnowledgeBaseIDs)
+	if code_ != code.CodeSuccess {
+		c.JSON(http.StatusOK, res.CodeOf(code_))
+		return
+	}
+
+	res.Success()
+	res.KnowledgeBaseIDs = knowledgeBaseIDs
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,252 @@
+package knowledge
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func CreateKnowledgeBase(kb *model.KnowledgeBase) (*model.KnowledgeBase, error) {
+	err := mysql.DB.Create(kb).Error
+	return kb, err
+}
+
+func GetKnowledgeBasesByUserName(userName string) ([]model.KnowledgeBase, error) {
+	var kbs []model.KnowledgeBase
+	err := mysql.DB.Where("user_name = ?", userName).Order("id asc").Find(&kbs).Error
+	return kbs, err
+}
+
+// GetKnowledgeBase 获取用户的知识库，不属于该用户时返回 gorm.ErrRecordNotFound
+func GetKnowledgeBase(userName string, id uint) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	err := mysql.DB.Where("id = ? AND user_name = ?", id, userName).First(&kb).Error
+	return &kb, err
+}
+
+// GetReadableKnowledgeBase 获取用户可以检索的知识库：用户自己的知识库或共享知识库
+func GetReadableKnowledgeBase(userName string, id uint) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	err := mysql.DB.Where("id = ? AND user_name IN ?", id, []string{userName, model.SharedKnowledgeBaseOwner}).First(&kb).Error
+	return &kb, err
+}
+
+// GetSharedKnowledgeBase 获取共享知识库
+func GetSharedKnowledgeBase() (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	err := mysql.DB.Where("user_name = ?", model.SharedKnowledgeBaseOwner).Order("id asc").First(&kb).Error
+	return &kb, err
+}
+
+// GetAllKnowledgeBases 获取所有用户的知识库，用于索引迁移等后台任务
+func GetAllKnowledgeBases() ([]model.KnowledgeBase, error) {
+	var kbs []model.KnowledgeBase
+	err := mysql.DB.Order("id asc").Find(&kbs).Error
+	return kbs, err
+}
+
+func GetKnowledgeBaseByID(id uint) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	err := mysql.DB.Where("id = ?", id).First(&kb).Error
+	return &kb, err
+}
+
+// UpdateKnowledgeBaseIndex 更新知识库的向量索引参数
+func UpdateKnowledgeBaseIndex(id uint, settings model.VectorIndexSettings) error {
+	return mysql.DB.Model(&model.KnowledgeBase{}).Where("id = ?", id).
+		Select("index_algorithm", "index_metric", "index_vector_type", "index_m", "index_ef_construction", "index_ef_runtime").
+		Updates(&model.KnowledgeBase{VectorIndex: settings}).Error
+}
+
+// GetDefaultKnowledgeBase 获取用户的默认知识库
+func GetDefaultKnowledgeBase(userName string) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	err := mysql.DB.Where("user_name = ? AND is_default = ?", userName, true).First(&kb).Error
+	return &kb, err
+}
+
+// SetDefaultKnowledgeBase 将指定知识库设为默认，同一用户只有一个默认知识库
+func SetDefaultKnowledgeBase(userName string, id uint) error {
+	return mysql.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.KnowledgeBase{}).Where("user_name = ?", userName).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.KnowledgeBase{}).Where("id = ? AND user_name = ?", id, userName).
+			Update("is_default", true).Error
+	})
+}
+
+// DeleteKnowledgeBase 删除知识库及其下的所有文档记录和入库任务
+func DeleteKnowledgeBase(id uint) error {
+	return mysql.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("knowledge_base_id = ?", id).Delete(&model.IngestJob{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("knowledge_base_id = ?", id).Delete(&model.Document{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.KnowledgeBase{}).Error
+	})
+}
+
+func CreateDocument(doc *model.Document) (*model.Document, error) {
+	err := mysql.DB.Create(doc).Error
+	return doc, err
+}
+
+func GetDocumentsByKnowledgeBase(kbID uint) ([]model.Document, error) {
+	var docs []model.Document
+	err := mysql.DB.Where("knowledge_base_id = ?", kbID).Order("id asc").Find(&docs).Error
+	return docs, err
+}
+
+// UpdateDocumentStatus 更新文档的索引状态和块数量
+func UpdateDocumentStatus(id uint, status string, chunkCount int) error {
+	return mysql.DB.Model(&model.Document{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "chunk_count": chunkCount}).Error
+}
+
+func DeleteDocument(id uint) error {
+	return mysql.DB.Where("id = ?", id).Delete(&model.Document{}).Error
+}
+
+// DeleteDocumentWithJobs 删除文档记录及其入库任务
+func DeleteDocumentWithJobs(id uint) error {
+	return mysql.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", id).Delete(&model.IngestJob{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.Document{}).Error
+	})
+}
+
+// GetDocumentsByUserName 获取用户的所有文档，kbID 不为 0 时只获取该知识库的文档
+func GetDocumentsByUserName(userName string, kbID uint) ([]model.Document, error) {
+	var docs []model.Document
+	query := mysql.DB.Where("user_name = ?", userName)
+	if kbID != 0 {
+		query = query.Where("knowledge_base_id = ?", kbID)
+	}
+	err := query.Order("id desc").Find(&docs).Error
+	return docs, err
+}
+
+// GetUserDocument 获取用户的文档，不属于该用户时返回 gorm.ErrRecordNotFound
+func GetUserDocument(userName string, id uint) (*model.Document, error) {
+	doc := new(model.Document)
+	err := mysql.DB.Where("id = ? AND user_name = ?", id, userName).First(doc).Error
+	return doc, err
+}
+
+// SumDocumentSize 用户所有文档占用的存储空间（字节）
+func SumDocumentSize(userName string) (int64, error) {
+	var total int64
+	err := mysql.DB.Model(&model.Document{}).Where("user_name = ?", userName).
+		Select("COALESCE(SUM(size), 0)").Scan(&total).Error
+	return total, err
+}
+
+// GetAllDocuments 获取所有用户的文档，用于数据对账
+func GetAllDocuments() ([]model.Document, error) {
+	var docs []model.Document
+	err := mysql.DB.Order("id asc").Find(&docs).Error
+	return docs, err
+}
+
+// GetDocumentsByFilePaths 根据文件路径批量查询文档，用于将检索结果还原为原始文件名
+func GetDocumentsByFilePaths(paths []string) ([]model.Document, error) {
+	var docs []model.Document
+	if len(paths) == 0 {
+		return docs, nil
+	}
+	err := mysql.DB.Where("file_path IN ?", paths).Find(&docs).Error
+	return docs, err
+}
+
+func GetDocument(id uint) (*model.Document, error) {
+	doc := new(model.Document)
+	err := mysql.DB.Where("id = ?", id).First(doc).Error
+	return doc, err
+}
+
+func CreateIngestJob(job *model.IngestJob) (*model.IngestJob, error) {
+	err := mysql.DB.Create(job).Error
+	return job, err
+}
+
+// GetIngestJob 获取用户的入库任务
+func GetIngestJob(userName string, id uint) (*model.IngestJob, error) {
+	job := new(model.IngestJob)
+	err := mysql.DB.Where("id = ? AND user_name = ?", id, userName).First(job).Error
+	return job, err
+}
+
+func GetIngestJobByID(id uint) (*model.IngestJob, error) {
+	job := new(model.IngestJob)
+	err := mysql.DB.Where("id = ?", id).First(job).Error
+	return job, err
+}
+
+// GetLatestIngestJob 获取文档最近创建的入库任务
+func GetLatestIngestJob(documentID uint) (*model.IngestJob, error) {
+	job := new(model.IngestJob)
+	err := mysql.DB.Where("document_id = ?", documentID).Order("id desc").First(job).Error
+	return job, err
+}
+
+// GetStaleIngestJobs 获取 before 之后没有更新过、仍处于排队或处理中的入库任务
+// 处理中的任务会定期更新 updated_at（心跳），长时间没有更新说明处理任务的进程已经退出或消息已丢失
+func GetStaleIngestJobs(before time.Time) ([]model.IngestJob, error) {
+	var jobs []model.IngestJob
+	err := mysql.DB.Where("status IN ? AND updated_at < ?",
+		[]string{model.IngestJobQueued, model.IngestJobParsing, model.IngestJobEmbedding}, before).
+		Order("id asc").Find(&jobs).Error
+	return jobs, err
+}
+
+// TouchIngestJob 只更新入库任务的 updated_at，用作执行中的心跳
+func TouchIngestJob(id uint) error {
+	return mysql.DB.Model(&model.IngestJob{}).Where("id = ?", id).UpdateColumn("updated_at", time.Now()).Error
+}
+
+// SaveIngestJob 保存入库任务的状态和进度
+func SaveIngestJob(job *model.IngestJob) error {
+	return mysql.DB.Save(job).Error
+}
+
+// GetDocumentByContentHash 查找知识库中内容相同的文档
+func GetDocumentByContentHash(kbID uint, contentHash string) (*model.Document, error) {
+	doc := new(model.Document)
+	err := mysql.DB.Where("knowledge_base_id = ? AND content_hash = ?", kbID, contentHash).First(doc).Error
+	return doc, err
+}
+
+// GetDocumentByFileName 查找知识库中同名的文档
+func GetDocumentByFileName(kbID uint, fileName string) (*model.Document, error) {
+	doc := new(model.Document)
+	err := mysql.DB.Where("knowledge_base_id = ? AND file_name = ?", kbID, fileName).First(doc).Error
+	return doc, err
+}
+
+func SaveDocument(doc *model.Document) error {
+	return mysql.DB.Save(doc).Error
+}
+
+func GetVectorIndex(name string) (*model.VectorIndex, error) {
+	var index model.VectorIndex
+	err := mysql.DB.Where("name = ?", name).First(&index).Error
+	return &index, err
+}
+
+// ClaimVectorIndex 记录索引的所有者，记录已存在时返回已有记录
+func ClaimVectorIndex(index *model.VectorIndex) (*model.VectorIndex, error) {
+	err := mysql.DB.Where("name = ?", index.Name).FirstOrCreate(index).Error
+	return index, err
+}
+
+func DeleteVectorIndex(name string) error {
+	return mysql.DB.Where("name = ?", name).Delete(&model.VectorIndex{}).Error
+}
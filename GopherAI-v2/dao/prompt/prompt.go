@@ -0,0 +1,79 @@
+package prompt
+
+import (
+	"GopherAI/common/mysql"
+	"GopherAI/model"
+
+	"gorm.io/gorm"
+)
+
+// GetActivePromptTemplates 获取所有生效中的模板
+func GetActivePromptTemplates() ([]model.PromptTemplate, error) {
+	var templates []model.PromptTemplate
+	err := mysql.DB.Where("is_active = ?", true).Find(&templates).Error
+	return templates, err
+}
+
+// ListPromptTemplates 列出模板，name 为空时列出全部
+func ListPromptTemplates(name string) ([]model.PromptTemplate, error) {
+	var templates []model.PromptTemplate
+	db := mysql.DB.Order("name asc, locale asc, model_name asc, version desc")
+	if name != "" {
+		db = db.Where("name = ?", name)
+	}
+	err := db.Find(&templates).Error
+	return templates, err
+}
+
+func GetPromptTemplateByID(id uint) (*model.PromptTemplate, error) {
+	var template model.PromptTemplate
+	err := mysql.DB.Where("id = ?", id).First(&template).Error
+	return &template, err
+}
+
+// CreatePromptTemplateVersion 创建一个新版本并设为生效，同一 名称+语言+模型 下的其他版本失效
+func CreatePromptTemplateVersion(template *model.PromptTemplate) (*model.PromptTemplate, error) {
+	err := mysql.DB.Transaction(func(tx *gorm.DB) error {
+		var maxVersion int
+		if err := tx.Model(&model.PromptTemplate{}).
+			Where("name = ? AND locale = ? AND model_name = ?", template.Name, template.Locale, template.ModelName).
+			Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		if err := deactivate(tx, template); err != nil {
+			return err
+		}
+		template.ID = 0
+		template.Version = maxVersion + 1
+		template.IsActive = true
+		return tx.Create(template).Error
+	})
+	return template, err
+}
+
+// ActivatePromptTemplate 将指定版本设为生效（用于回滚）
+func ActivatePromptTemplate(id uint) (*model.PromptTemplate, error) {
+	var template model.PromptTemplate
+	err := mysql.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&template).Error; err != nil {
+			return err
+		}
+		if err := deactivate(tx, &template); err != nil {
+			return err
+		}
+		template.IsActive = true
+		return tx.Model(&template).Update("is_active", true).Error
+	})
+	return &template, err
+}
+
+// DeletePromptTemplate 删除指定版本
+func DeletePromptTemplate(id uint) error {
+	return mysql.DB.Where("id = ?", id).Delete(&model.PromptTemplate{}).Error
+}
+
+func deactivate(tx *gorm.DB, template *model.PromptTemplate) error {
+	return tx.Model(&model.PromptTemplate{}).
+		Where("name = ? AND locale = ? AND model_name = ?", template.Name, template.Locale, template.ModelName).
+		Update("is_active", false).Error
+}
@@ -21,3 +21,9 @@ func GetSessionByID(sessionID string) (*model.Session, error) {
 	err := mysql.DB.Where("id = ?", sessionID).First(&session).Error
 	return &session, err
 }
+
+// UpdateSessionKnowledgeBases 更新会话关联的知识库
+func UpdateSessionKnowledgeBases(sessionID string, knowledgeBaseIDs []uint) error {
+	return mysql.DB.Model(&model.Session{ID: sessionID}).Select("KnowledgeBaseIDs").
+		Updates(&model.Session{KnowledgeBaseIDs: knowledgeBaseIDs}).Error
+}
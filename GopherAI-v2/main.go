@@ -4,19 +4,42 @@ import (
 	"GopherAI/common/aihelper"
 	"GopherAI/common/mysql"
 	"GopherAI/common/rabbitmq"
+	"GopherAI/common/redact"
 	"GopherAI/common/redis"
+	"GopherAI/common/vectorstore"
 	"GopherAI/config"
 	"GopherAI/dao/message"
 	"GopherAI/router"
+	"GopherAI/service/file"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func StartServer(addr string, port int) error {
+// StartServer 启动 HTTP 服务，ctx 取消后停止接收新请求，等待处理中的请求完成后返回
+func StartServer(ctx context.Context, addr string, port int) error {
 	r := router.InitRouter()
 	//服务器静态资源路径映射关系，这里目前不需要
 	// r.Static(config.GetConfig().HttpFilePath, config.GetConfig().MusicFilePath)
-	return r.Run(fmt.Sprintf("%s:%d", addr, port))
+	srv := &http.Server{Addr: fmt.Sprintf("%s:%d", addr, port), Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown server error: %v", err)
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // 从数据库加载消息并初始化 AIHelperManager
@@ -42,7 +65,7 @@ func readDataFromDB() error {
 		}
 		log.Println("readDataFromDB init:  ", helper.SessionID)
 		// 添加消息到内存中(不开启存储功能)
-		helper.AddMessage(m.Content, m.UserName, m.IsUser, false)
+		helper.AddMessageWithCitations(m.Content, m.UserName, m.IsUser, m.Citations, false)
 	}
 
 	log.Println("AIHelperManager init success ")
@@ -53,6 +76,8 @@ func main() {
 	conf := config.GetConfig()
 	host := conf.MainConfig.Host
 	port := conf.MainConfig.Port
+	//开启了脱敏时先加载脱敏规则，规则有误时拒绝启动
+	redact.GetGlobalRedactor()
 	//初始化mysql
 	if err := mysql.InitMysql(); err != nil {
 		log.Println("InitMysql error , " + err.Error())
@@ -66,8 +91,26 @@ func main() {
 	log.Println("redis init success  ")
 	rabbitmq.InitRabbitMQ()
 	log.Println("rabbitmq init success  ")
+	// 将旧版本直接保存在用户目录下的文件导入默认知识库，并删除按文件建立的旧索引
+	go func() {
+		if _, _, err := file.MigrateLegacyUploads(context.Background(), false, false); err != nil {
+			log.Printf("MigrateLegacyUploads error: %v", err)
+		}
+	}()
+	// 恢复进程退出时中断的入库任务
+	go file.WatchIngestJobs(context.Background())
+	// 将文档目录导入共享知识库并监听变化，入库任务通过消息队列执行
+	go file.WatchSharedDocuments(context.Background())
+	// 定期对账上传目录、文档记录和向量数据，清理孤儿数据
+	go file.WatchReconcile(context.Background())
 
-	err := StartServer(host, port) // 启动 HTTP 服务
+	// 收到退出信号后停止 HTTP 服务，再保存内存向量存储的快照
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := StartServer(ctx, host, port) // 启动 HTTP 服务
+	if closeErr := vectorstore.Close(); closeErr != nil {
+		log.Printf("close vector store error: %v", closeErr)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"GopherAI/common/code"
+	"GopherAI/config"
+	"GopherAI/controller"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理员鉴权，需要在 jwt.Auth() 之后使用
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := new(controller.Response)
+
+		if !IsAdmin(c.GetString("userName")) {
+			c.JSON(http.StatusOK, res.CodeOf(code.CodeForbidden))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin 判断用户是否为管理员（配置文件 adminConfig.usernames）
+func IsAdmin(userName string) bool {
+	if userName == "" {
+		return false
+	}
+	for _, name := range config.GetConfig().AdminUsernames {
+		if name == userName {
+			return true
+		}
+	}
+	return false
+}
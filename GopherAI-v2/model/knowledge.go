@@ -0,0 +1,93 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SharedKnowledgeBaseOwner 共享知识库的所有者
+// 共享知识库由服务端从 ragModelConfig.docDir 导入，所有用户都可以检索，但不能上传或删除其中的文档
+const SharedKnowledgeBaseOwner = "shared"
+
+// KnowledgeBase 知识库，一个用户可以有多个知识库，检索时在选中的知识库内的所有文档中搜索
+type KnowledgeBase struct {
+	ID          uint                `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserName    string              `gorm:"type:varchar(50);index;not null" json:"username"`
+	Name        string              `gorm:"type:varchar(100);not null" json:"name"`
+	Description string              `gorm:"type:varchar(255)" json:"description"`
+	IsDefault   bool                `gorm:"not null;default:false" json:"is_default"` // 对话时默认使用的知识库
+	VectorIndex VectorIndexSettings `gorm:"embedded;embeddedPrefix:index_" json:"vector_index"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt      `gorm:"index" json:"-"`
+}
+
+// VectorIndexSettings 知识库的向量索引参数，创建知识库时确定，之后通过 cmd/migrate-index 修改
+type VectorIndexSettings struct {
+	Algorithm      string `gorm:"type:varchar(10)" json:"algorithm"`   // FLAT | HNSW
+	Metric         string `gorm:"type:varchar(10)" json:"metric"`      // COSINE | L2 | IP
+	VectorType     string `gorm:"type:varchar(10)" json:"vector_type"` // FLOAT32 | FLOAT64
+	M              int    `json:"m,omitempty"`                         // 以下仅 HNSW 使用
+	EfConstruction int    `json:"ef_construction,omitempty"`
+	EfRuntime      int    `json:"ef_runtime,omitempty"`
+}
+
+const (
+	DocumentStatusIndexing = "indexing"
+	DocumentStatusIndexed  = "indexed"
+	DocumentStatusFailed   = "failed"
+)
+
+// Document 知识库中的一个文档
+type Document struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	KnowledgeBaseID uint      `gorm:"index;not null" json:"knowledge_base_id"`
+	UserName        string    `gorm:"type:varchar(50);index;not null" json:"username"`
+	FileName        string    `gorm:"type:varchar(255);not null" json:"file_name"`   // 上传时的原始文件名
+	StoredName      string    `gorm:"type:varchar(100);not null" json:"stored_name"` // 服务器上保存的文件名（UUID），同时作为块 ID 前缀
+	FilePath        string    `gorm:"type:varchar(500);not null" json:"-"`
+	ContentHash     string    `gorm:"type:varchar(64);index" json:"content_hash"` // 文件内容的 SHA-256，用于去重
+	Tags            []string  `gorm:"type:text;serializer:json" json:"tags"`      // 文档标签，检索时可按标签过滤
+	Language        string    `gorm:"type:varchar(20)" json:"language"`           // 文档语言，如 zh、en
+	Size            int64     `json:"size"`
+	ChunkCount      int       `json:"chunk_count"`
+	Status          string    `gorm:"type:varchar(20);not null" json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+const (
+	IngestJobQueued    = "queued"
+	IngestJobParsing   = "parsing"
+	IngestJobEmbedding = "embedding"
+	IngestJobDone      = "done"
+	IngestJobFailed    = "failed"
+)
+
+// IngestJob 文档入库任务，上传后由消息队列异步完成解析、切块和向量化
+type IngestJob struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DocumentID      uint      `gorm:"index;not null" json:"document_id"`
+	KnowledgeBaseID uint      `gorm:"not null" json:"knowledge_base_id"`
+	UserName        string    `gorm:"type:varchar(50);index;not null" json:"username"`
+	Status          string    `gorm:"type:varchar(20);not null" json:"status"`
+	TotalChunks     int       `json:"total_chunks"`                                             // 切分出的块数量
+	IndexedChunks   int       `json:"indexed_chunks"`                                           // 已写入索引的块数量
+	SkippedChunks   int       `json:"skipped_chunks"`                                           // 内容未变化、无需重新向量化的块数量
+	RemovedChunks   int       `json:"removed_chunks"`                                           // 新版本中已不存在、从索引中删除的块数量
+	FailedChunks    []string  `gorm:"type:text;serializer:json" json:"failed_chunks,omitempty"` // 写入失败的块 ID，重试时只处理这些块
+	Error           string    `gorm:"type:text" json:"error,omitempty"`
+	Attempts        int       `json:"attempts"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// VectorIndex 向量索引的归属，检索和删除索引前校验当前用户是否为索引的所有者
+type VectorIndex struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name            string    `gorm:"type:varchar(191);uniqueIndex;not null" json:"name"` // 索引标识，如 u_<用户名>:kb_<知识库ID>
+	UserName        string    `gorm:"type:varchar(50);index;not null" json:"username"`
+	KnowledgeBaseID uint      `gorm:"index;not null" json:"knowledge_base_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -5,15 +5,29 @@ import (
 )
 
 type Message struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	SessionID string    `gorm:"index;not null;type:varchar(36)" json:"session_id"`
-	UserName  string    `gorm:"type:varchar(20)" json:"username"`
-	Content   string    `gorm:"type:text" json:"content"`
-	IsUser    bool      `gorm:"not null;" json:"is_user"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SessionID string     `gorm:"index;not null;type:varchar(36)" json:"session_id"`
+	UserName  string     `gorm:"type:varchar(20)" json:"username"`
+	Content   string     `gorm:"type:text" json:"content"`
+	IsUser    bool       `gorm:"not null;" json:"is_user"`
+	Citations []Citation `gorm:"type:text;serializer:json" json:"citations,omitempty"` // AI 回答引用的知识库文档块
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Citation 回答中引用的一个文档块，Index 对应回答中的 [n] 标记
+type Citation struct {
+	Index        int     `json:"index"`
+	DocumentName string  `json:"document_name"` // 上传时的原始文件名
+	ChunkID      string  `json:"chunk_id"`
+	Score        float64 `json:"score"`
+	Snippet      string  `json:"snippet"`
+	Page         int     `json:"page,omitempty"`
+	Section      string  `json:"section,omitempty"`
+	Cited        bool    `json:"cited"` // 回答中是否出现了该编号的引用标记
 }
 
 type History struct {
-	IsUser  bool   `json:"is_user"`
-	Content string `json:"content"`
+	IsUser    bool       `json:"is_user"`
+	Content   string     `json:"content"`
+	Citations []Citation `json:"citations,omitempty"`
 }
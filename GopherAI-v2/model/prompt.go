@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// PromptTemplate 提示词模板，每次修改都会生成一个新版本
+// 同一 名称+语言+模型 下只有一个版本处于生效状态
+type PromptTemplate struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_prompt_version" json:"name"`
+	Locale      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_prompt_version" json:"locale"`
+	ModelName   string    `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_prompt_version" json:"model_name"` // 为空表示对所有模型生效
+	Version     int       `gorm:"not null;uniqueIndex:idx_prompt_version" json:"version"`
+	Content     string    `gorm:"type:text;not null" json:"content"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	IsActive    bool      `gorm:"not null;default:false;index" json:"is_active"`
+	CreatedBy   string    `gorm:"type:varchar(50)" json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
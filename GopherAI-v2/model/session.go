@@ -7,12 +7,13 @@ import (
 )
 
 type Session struct {
-	ID        string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	UserName  string         `gorm:"index;not null" json:"username"`
-	Title     string         `gorm:"type:varchar(100)" json:"title"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserName         string         `gorm:"index;not null" json:"username"`
+	Title            string         `gorm:"type:varchar(100)" json:"title"`
+	KnowledgeBaseIDs []uint         `gorm:"type:text;serializer:json" json:"knowledge_base_ids"` // 会话关联的知识库，对话时在其中检索相关文档，与使用哪个模型无关
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type SessionInfo struct {
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"GopherAI/controller/ollama"
 	"GopherAI/controller/session"
 	"GopherAI/controller/tts"
 
@@ -15,6 +16,8 @@ func AIRouter(r *gin.RouterGroup) {
 		r.POST("/chat/send-new-session", session.CreateSessionAndSendMessage)
 		r.POST("/chat/send", session.ChatSend)
 		r.POST("/chat/history", session.ChatHistory)
+		r.GET("/chat/sessions/:id/knowledge-bases", session.GetSessionKnowledgeBases)
+		r.PUT("/chat/sessions/:id/knowledge-bases", session.UpdateSessionKnowledgeBases)
 
 		// TTS相关接口
 		r.POST("/chat/tts", tts.CreateTTSTask)
@@ -22,6 +25,9 @@ func AIRouter(r *gin.RouterGroup) {
 
 		r.POST("/chat/send-stream-new-session", session.CreateStreamSessionAndSendMessage)
 		r.POST("/chat/send-stream", session.ChatStreamSend)
+
+		// 本地 Ollama 模型相关接口
+		r.GET("/ollama/models", ollama.ListModels)
 	}
 
 }
@@ -2,10 +2,37 @@ package router
 
 import (
 	"GopherAI/controller/file"
+	"GopherAI/controller/knowledge"
 
 	"github.com/gin-gonic/gin"
 )
 
 func FileRouter(r *gin.RouterGroup) {
 	r.POST("/upload", file.UploadRagFile)
+	// 检索调试：返回检索结果和各阶段耗时，不调用模型
+	r.POST("/search", file.SearchDocuments)
+
+	// 文档管理
+	{
+		r.GET("/documents", file.ListDocuments)
+		r.GET("/documents/:id/download", file.DownloadDocument)
+		r.PUT("/documents/:id", file.RenameDocument)
+		r.PUT("/documents/:id/metadata", file.UpdateDocumentMetadata)
+		r.DELETE("/documents/:id", file.DeleteDocument)
+	}
+
+	// 文档入库任务
+	{
+		r.GET("/jobs/:id", file.GetIngestJob)
+		r.POST("/jobs/:id/retry", file.RetryIngestJob)
+	}
+
+	// 知识库相关接口
+	{
+		r.GET("/kb", knowledge.ListKnowledgeBases)
+		r.POST("/kb", knowledge.CreateKnowledgeBase)
+		r.DELETE("/kb/:id", knowledge.DeleteKnowledgeBase)
+		r.POST("/kb/:id/default", knowledge.SetDefaultKnowledgeBase)
+		r.GET("/kb/:id/documents", knowledge.ListDocuments)
+	}
 }
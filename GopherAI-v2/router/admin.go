@@ -0,0 +1,21 @@
+package router
+
+import (
+	"GopherAI/controller/prompt"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRouter(r *gin.RouterGroup) {
+
+	// 提示词模板管理
+	{
+		r.GET("/prompts", prompt.ListPrompts)
+		r.POST("/prompts", prompt.CreatePrompt)
+		r.POST("/prompts/preview", prompt.PreviewPrompt)
+		r.GET("/prompts/:id", prompt.GetPrompt)
+		r.PUT("/prompts/:id", prompt.UpdatePrompt)
+		r.DELETE("/prompts/:id", prompt.DeletePrompt)
+		r.POST("/prompts/:id/activate", prompt.ActivatePrompt)
+	}
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"GopherAI/middleware/admin"
 	"GopherAI/middleware/jwt"
 
 	"github.com/gin-gonic/gin"
@@ -32,5 +33,12 @@ func InitRouter() *gin.Engine {
 		FileRouter(FileGroup)
 	}
 
+	// 管理接口：需要登录且为管理员
+	{
+		AdminGroup := enterRouter.Group("/admin")
+		AdminGroup.Use(jwt.Auth(), admin.Auth())
+		AdminRouter(AdminGroup)
+	}
+
 	return r
 }
@@ -1,51 +1,103 @@
 package file
 
 import (
+	"GopherAI/common/code"
+	"GopherAI/common/rabbitmq"
 	"GopherAI/common/rag"
+	"GopherAI/common/redact"
 	"GopherAI/config"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"GopherAI/service/knowledge"
 	"GopherAI/utils"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded 上传后会超过用户的存储配额
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// ErrInvalidMetadata 文档的标签或语言不合法
+var ErrInvalidMetadata = errors.New("invalid document metadata")
+
+const (
+	maxDocumentTags = 20
+	maxTagLength    = 50
+	maxLanguageLen  = 20
 )
 
-// 上传rag相关文件（这里只允许文本文件）
-// 其实可以直接将其向量化进行保存，但这边依旧存储到服务器上以便后续可以在服务器上查看历史RAG文件
-func UploadRagFile(username string, file *multipart.FileHeader) (string, error) {
+// normalizeMetadata 整理文档的标签和语言：去掉首尾空白、空标签和重复的标签
+// 标签中不能包含逗号和竖线，二者分别是标签的存储分隔符和过滤表达式中的“或”
+func normalizeMetadata(tags []string, language string) ([]string, string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		if len(tag) > maxTagLength || strings.ContainsAny(tag, ",|") {
+			return nil, "", ErrInvalidMetadata
+		}
+		seen[strings.ToLower(tag)] = true
+		normalized = append(normalized, tag)
+	}
+	language = strings.TrimSpace(language)
+	if len(normalized) > maxDocumentTags || len(language) > maxLanguageLen || strings.ContainsAny(language, ",| ") {
+		return nil, "", ErrInvalidMetadata
+	}
+	return normalized, language, nil
+}
+
+// 上传rag相关文件（这里只允许文本文件）到指定知识库，knowledgeBaseID 为 0 时上传到默认知识库
+// 文件先存储到服务器上，再创建入库任务交给消息队列异步完成切块和向量化，上传请求立即返回
+// 同一知识库中内容完全相同的文档只保留一份，此时 duplicate 为 true 并返回已有文档；
+// 已有文档没有入库成功时同时返回它的入库任务，已入库时任务为 nil
+// tags 和 language 写入文档每个块的元数据，检索时可以按它们过滤；上传新版本时不传则沿用原来的值
+func UploadRagFile(username string, knowledgeBaseID uint, file *multipart.FileHeader, tags []string, language string) (*model.Document, *model.IngestJob, bool, error) {
 	// 校验文件类型和文件名
 	if err := utils.ValidateFile(file); err != nil {
 		log.Printf("File validation failed: %v", err)
-		return "", err
-	}
-
-	// 创建用户目录
-	userDir := filepath.Join("uploads", username)
-	if err := os.MkdirAll(userDir, 0755); err != nil {
-		log.Printf("Failed to create user directory %s: %v", userDir, err)
-		return "", err
-	}
-
-	// 删除用户目录中的所有现有文件及其索引（每个用户只能有一个文件）
-	files, err := os.ReadDir(userDir)
-	if err == nil {
-		for _, f := range files {
-			if !f.IsDir() {
-				filename := f.Name()
-				// 删除该文件对应的 Redis 索引
-				if err := rag.DeleteIndex(context.Background(), filename); err != nil {
-					log.Printf("Failed to delete index for %s: %v", filename, err)
-					// 继续执行，不因为索引删除失败而中断文件上传
-				}
-			}
-		}
+		return nil, nil, false, err
+	}
+	tags, language, err := normalizeMetadata(tags, language)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	kb, code_ := knowledge.GetKnowledgeBase(username, knowledgeBaseID)
+	if code_ != code.CodeSuccess {
+		return nil, nil, false, fmt.Errorf("knowledge base %d not available: %s", knowledgeBaseID, code_.Msg())
+	}
+
+	// 写入文件前先按上传的大小检查配额，超出时不必写入；同名文档视为新版本，只计算增加的大小
+	fileName := filepath.Base(file.Filename)
+	added := file.Size
+	if prev, err := knowledgeDao.GetDocumentByFileName(kb.ID, fileName); err == nil {
+		added -= prev.Size
 	}
-	// 删除用户目录中的所有文件
-	if err := utils.RemoveAllFilesInDir(userDir); err != nil {
-		log.Printf("Failed to clean user directory %s: %v", userDir, err)
-		return "", err
+	if err := checkQuota(username, added); err != nil {
+		return nil, nil, false, err
+	}
+
+	// 创建知识库目录
+	kbDir := knowledge.KnowledgeBaseDir(username, kb.ID)
+	if err := os.MkdirAll(kbDir, 0755); err != nil {
+		log.Printf("Failed to create knowledge base directory %s: %v", kbDir, err)
+		return nil, nil, false, err
 	}
 
 	// 生成UUID作为唯一文件名
@@ -53,49 +105,426 @@ func UploadRagFile(username string, file *multipart.FileHeader) (string, error)
 
 	ext := filepath.Ext(file.Filename)
 	filename := uuid + ext
-	filePath := filepath.Join(userDir, filename)
+	filePath := filepath.Join(kbDir, filename)
 
 	// 打开上传的文件
 	src, err := file.Open()
 	if err != nil {
 		log.Printf("Failed to open uploaded file: %v", err)
-		return "", err
+		return nil, nil, false, err
 	}
 	defer src.Close()
 
-	// 创建目标文件
+	// 创建目标文件，写入的同时计算内容哈希
 	dst, err := os.Create(filePath)
 	if err != nil {
 		log.Printf("Failed to create destination file %s: %v", filePath, err)
-		return "", err
+		return nil, nil, false, err
 	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, src); err != nil {
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dst, hasher), src)
+	dst.Close()
+	if err != nil {
 		log.Printf("Failed to copy file content: %v", err)
-		return "", err
+		os.Remove(filePath)
+		return nil, nil, false, err
 	}
+	contentHash := hex.EncodeToString(hasher.Sum(nil))
 
 	log.Printf("File uploaded successfully: %s", filePath)
 
-	// 创建 RAG 索引器并对文件进行向量化
-	indexer, err := rag.NewRAGIndexer(filename, config.GetConfig().RagModelConfig.RagEmbeddingModel)
-	if err != nil {
-		log.Printf("Failed to create RAG indexer: %v", err)
-		// 删除已上传的文件
+	// 同一知识库中已有内容完全相同的文档，不再重复上传；之前入库失败时重新入库
+	if existing, err := knowledgeDao.GetDocumentByContentHash(kb.ID, contentHash); err == nil {
+		log.Printf("Duplicate of document %d (%s), status %s", existing.ID, existing.FileName, existing.Status)
 		os.Remove(filePath)
-		return "", err
+		job, err := resumeDuplicateIngest(existing)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return existing, job, true, nil
 	}
 
-	// 读取文件内容并创建向量索引
-	if err := indexer.IndexFile(context.Background(), filePath); err != nil {
-		log.Printf("Failed to index file: %v", err)
-		// 删除已上传的文件和索引
+	// 配额的最终检查和文档记录的写入在同一把用户锁内完成，并发上传不会同时通过检查
+	unlock := lockUserStorage(username)
+	defer unlock()
+
+	// 同名文档视为新版本：覆盖原文件并沿用原文档记录，入库时只向量化变化的块
+	doc, err := knowledgeDao.GetDocumentByFileName(kb.ID, fileName)
+	isNew := err != nil
+
+	// 检查存储配额，新版本替换旧文件时只计算增加的大小
+	added = file.Size
+	if !isNew {
+		added -= doc.Size
+	}
+	if err := checkQuota(username, added); err != nil {
 		os.Remove(filePath)
-		rag.DeleteIndex(context.Background(), filename)
-		return "", err
+		return nil, nil, false, err
+	}
+	if isNew {
+		doc, err = knowledgeDao.CreateDocument(&model.Document{
+			KnowledgeBaseID: kb.ID,
+			UserName:        username,
+			FileName:        fileName,
+			StoredName:      filename,
+			FilePath:        filePath,
+			ContentHash:     contentHash,
+			Tags:            tags,
+			Language:        language,
+			Size:            file.Size,
+			Status:          model.DocumentStatusIndexing,
+		})
+		if err != nil {
+			log.Printf("Failed to create document record: %v", err)
+			os.Remove(filePath)
+			return nil, nil, false, err
+		}
+	} else {
+		if err := os.Rename(filePath, doc.FilePath); err != nil {
+			log.Printf("Failed to replace document %d file: %v", doc.ID, err)
+			os.Remove(filePath)
+			return nil, nil, false, err
+		}
+		filePath, filename = doc.FilePath, doc.StoredName
+		doc.ContentHash = contentHash
+		doc.Size = file.Size
+		if len(tags) > 0 {
+			doc.Tags = tags
+		}
+		if language != "" {
+			doc.Language = language
+		}
+		doc.Status = model.DocumentStatusIndexing
+		if err := knowledgeDao.SaveDocument(doc); err != nil {
+			log.Printf("Failed to update document record: %v", err)
+			return nil, nil, false, err
+		}
+		log.Printf("Document %d (%s) replaced by new version", doc.ID, fileName)
 	}
 
-	log.Printf("File indexed successfully: %s", filename)
-	return filePath, nil
+	// 创建入库任务并投递到消息队列
+	job, err := queueIngestJob(doc)
+	if err != nil {
+		log.Printf("Failed to create ingest job: %v", err)
+		if isNew {
+			os.Remove(filePath)
+			knowledgeDao.DeleteDocument(doc.ID)
+		}
+		return nil, nil, false, err
+	}
+
+	log.Printf("Ingest job %d queued for %s", job.ID, filename)
+	return doc, job, false, nil
+}
+
+// resumeDuplicateIngest 重复上传的文档还没有入库成功时恢复入库，返回对应的入库任务
+// 最近的任务仍在处理中时直接返回它；任务失败、已中断或不存在时重新投递
+func resumeDuplicateIngest(doc *model.Document) (*model.IngestJob, error) {
+	if doc.Status == model.DocumentStatusIndexed {
+		return nil, nil
+	}
+	job, err := knowledgeDao.GetLatestIngestJob(doc.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return queueIngestJob(doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case job.Status == model.IngestJobDone || job.Status == model.IngestJobFailed:
+		return queueIngestJob(doc)
+	case isIngestJobStale(job):
+		if err := requeueIngestJob(job); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// 每个用户一把锁，保证配额检查和文档记录的写入不会被同一用户的其他上传打断
+// 只在当前进程内有效，多实例部署时配额仍可能被并发上传少量超出
+var userStorageLocks sync.Map
+
+// lockUserStorage 锁定用户的存储空间，返回解锁函数
+func lockUserStorage(username string) func() {
+	v, _ := userStorageLocks.LoadOrStore(username, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// queueIngestJob 为文档创建入库任务并投递到消息队列
+// 投递失败时任务和文档标记为失败并正常返回，之后可以通过重试接口重新投递
+func queueIngestJob(doc *model.Document) (*model.IngestJob, error) {
+	job, err := knowledgeDao.CreateIngestJob(&model.IngestJob{
+		DocumentID:      doc.ID,
+		KnowledgeBaseID: doc.KnowledgeBaseID,
+		UserName:        doc.UserName,
+		Status:          model.IngestJobQueued,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := rabbitmq.RMQIngest.Publish(rabbitmq.GenerateIngestMQParam(job.ID)); err != nil {
+		log.Printf("Failed to publish ingest job %d: %v", job.ID, err)
+		job.Status = model.IngestJobFailed
+		job.Error = err.Error()
+		knowledgeDao.SaveIngestJob(job)
+		knowledgeDao.UpdateDocumentStatus(doc.ID, model.DocumentStatusFailed, 0)
+		doc.Status = model.DocumentStatusFailed
+	}
+	return job, nil
+}
+
+// GetIngestJob 查询入库任务的状态和进度
+func GetIngestJob(username string, jobID uint) (*model.IngestJob, code.Code) {
+	job, err := knowledgeDao.GetIngestJob(username, jobID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, code.CodeRecordNotFound
+	}
+	if err != nil {
+		log.Printf("GetIngestJob error: %v", err)
+		return nil, code.CodeServerBusy
+	}
+	return job, code.CodeSuccess
+}
+
+// RetryIngestJob 重新执行失败的入库任务，只有部分块失败时只重试这些块
+// 长时间没有进度的排队中或处理中的任务（处理任务的进程已退出）同样可以重试
+func RetryIngestJob(username string, jobID uint) (*model.IngestJob, code.Code) {
+	job, code_ := GetIngestJob(username, jobID)
+	if code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	if job.Status != model.IngestJobFailed && !isIngestJobStale(job) {
+		return nil, code.CodeInvalidParams
+	}
+	if err := requeueIngestJob(job); err != nil {
+		log.Printf("RetryIngestJob error: %v", err)
+		return nil, code.CodeServerBusy
+	}
+	return job, code.CodeSuccess
+}
+
+// requeueIngestJob 将已有的入库任务重新投递到消息队列，投递失败时任务标记为失败
+func requeueIngestJob(job *model.IngestJob) error {
+	job.Status = model.IngestJobQueued
+	if err := knowledgeDao.SaveIngestJob(job); err != nil {
+		return fmt.Errorf("save ingest job %d: %w", job.ID, err)
+	}
+	if err := knowledgeDao.UpdateDocumentStatus(job.DocumentID, model.DocumentStatusIndexing, job.IndexedChunks); err != nil {
+		log.Printf("Requeue ingest job %d: update document error: %v", job.ID, err)
+	}
+	if err := rabbitmq.RMQIngest.Publish(rabbitmq.GenerateIngestMQParam(job.ID)); err != nil {
+		job.Status = model.IngestJobFailed
+		job.Error = err.Error()
+		knowledgeDao.SaveIngestJob(job)
+		knowledgeDao.UpdateDocumentStatus(job.DocumentID, model.DocumentStatusFailed, job.IndexedChunks)
+		return fmt.Errorf("publish ingest job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// StorageUsage 用户的存储空间使用情况
+type StorageUsage struct {
+	Used  int64 `json:"used"`  // 已使用的字节数
+	Quota int64 `json:"quota"` // 配额字节数，0 表示不限制
+}
+
+// storageQuota 每个用户的存储配额（字节），0 表示不限制
+func storageQuota() int64 {
+	return config.GetConfig().StorageQuotaMB * 1024 * 1024
+}
+
+// checkQuota 检查增加 added 字节后是否超过用户的存储配额
+func checkQuota(username string, added int64) error {
+	quota := storageQuota()
+	if quota <= 0 || added <= 0 {
+		return nil
+	}
+	used, err := knowledgeDao.SumDocumentSize(username)
+	if err != nil {
+		return fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	if used+added > quota {
+		log.Printf("User %s storage quota exceeded: used %d, adding %d, quota %d", username, used, added, quota)
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// ListDocuments 列出用户上传的文档，knowledgeBaseID 为 0 时列出所有知识库的文档
+func ListDocuments(username string, knowledgeBaseID uint) ([]model.Document, *StorageUsage, code.Code) {
+	docs, err := knowledgeDao.GetDocumentsByUserName(username, knowledgeBaseID)
+	if err != nil {
+		log.Printf("ListDocuments error: %v", err)
+		return nil, nil, code.CodeServerBusy
+	}
+	used, err := knowledgeDao.SumDocumentSize(username)
+	if err != nil {
+		log.Printf("ListDocuments SumDocumentSize error: %v", err)
+		return nil, nil, code.CodeServerBusy
+	}
+	return docs, &StorageUsage{Used: used, Quota: storageQuota()}, code.CodeSuccess
+}
+
+// GetDocument 获取用户的文档，用于下载原始文件
+func GetDocument(username string, id uint) (*model.Document, code.Code) {
+	doc, err := knowledgeDao.GetUserDocument(username, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, code.CodeRecordNotFound
+	}
+	if err != nil {
+		log.Printf("GetDocument error: %v", err)
+		return nil, code.CodeServerBusy
+	}
+	return doc, code.CodeSuccess
+}
+
+// DeleteDocument 删除文档：索引中的块、服务器上的文件、文档记录和入库任务
+func DeleteDocument(username string, id uint) code.Code {
+	doc, code_ := GetDocument(username, id)
+	if code_ != code.CodeSuccess {
+		return code_
+	}
+
+	removed, err := rag.DeleteDocumentChunks(context.Background(), username, doc.KnowledgeBaseID, doc.FilePath)
+	if errors.Is(err, rag.ErrIndexForbidden) {
+		return code.CodeForbidden
+	}
+	if err != nil {
+		log.Printf("DeleteDocument DeleteDocumentChunks error: %v", err)
+		return code.CodeServerBusy
+	}
+	if err := os.Remove(doc.FilePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("DeleteDocument Remove error: %v", err)
+	}
+	if err := knowledgeDao.DeleteDocumentWithJobs(doc.ID); err != nil {
+		log.Printf("DeleteDocument error: %v", err)
+		return code.CodeServerBusy
+	}
+	log.Printf("Document %d (%s) deleted with %d chunks", doc.ID, doc.FileName, removed)
+	return code.CodeSuccess
+}
+
+// RenameDocument 修改文档的显示名称，扩展名不能改变（文件按扩展名解析），同一知识库中不能重名
+// 服务器上保存的文件名和块 ID 不变，引用来源会显示新的名称
+func RenameDocument(username string, id uint, name string) (*model.Document, code.Code) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 255 || filepath.Base(name) != name {
+		return nil, code.CodeInvalidParams
+	}
+	doc, code_ := GetDocument(username, id)
+	if code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	if !strings.EqualFold(filepath.Ext(name), filepath.Ext(doc.FileName)) {
+		return nil, code.CodeInvalidParams
+	}
+	if name == doc.FileName {
+		return doc, code.CodeSuccess
+	}
+	if _, err := knowledgeDao.GetDocumentByFileName(doc.KnowledgeBaseID, name); err == nil {
+		return nil, code.CodeFileExist
+	}
+
+	doc.FileName = name
+	if err := knowledgeDao.SaveDocument(doc); err != nil {
+		log.Printf("RenameDocument error: %v", err)
+		return nil, code.CodeServerBusy
+	}
+	return doc, code.CodeSuccess
+}
+
+// UpdateDocumentMetadata 修改文档的标签和语言，并重新入库使索引中的块带上新的元数据
+// 入库是增量的，文件内容未变化时不会重新向量化，只更新块的元数据
+func UpdateDocumentMetadata(username string, id uint, tags []string, language string) (*model.Document, *model.IngestJob, code.Code) {
+	tags, language, err := normalizeMetadata(tags, language)
+	if err != nil {
+		return nil, nil, code.CodeInvalidParams
+	}
+	doc, code_ := GetDocument(username, id)
+	if code_ != code.CodeSuccess {
+		return nil, nil, code_
+	}
+
+	doc.Tags = tags
+	doc.Language = language
+	doc.Status = model.DocumentStatusIndexing
+	if err := knowledgeDao.SaveDocument(doc); err != nil {
+		log.Printf("UpdateDocumentMetadata error: %v", err)
+		return nil, nil, code.CodeServerBusy
+	}
+	job, err := queueIngestJob(doc)
+	if err != nil {
+		log.Printf("UpdateDocumentMetadata create ingest job error: %v", err)
+		return nil, nil, code.CodeServerBusy
+	}
+	return doc, job, code.CodeSuccess
+}
+
+// SearchResult 检索调试结果
+type SearchResult struct {
+	Chunks  []SearchChunk `json:"chunks"`
+	Trace   *rag.Trace    `json:"trace"`    // 改写后的查询、各阶段耗时和候选数量
+	TotalMs float64       `json:"total_ms"` // 检索的总耗时
+}
+
+// SearchChunk 检索到的块，分数在 Metadata 中：score 为 RRF 融合分数，distance 为向量距离，rerank_score 为重排分数
+type SearchChunk struct {
+	Rank     int            `json:"rank"`
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// SearchDocuments 使用与对话相同的检索流程（查询改写、混合检索、距离阈值、重排）在知识库中检索，不调用模型
+// 用于排查回答质量问题；knowledgeBaseIDs 为空时在默认知识库中检索，filterExpr 的语法见 rag.ParseFilter
+func SearchDocuments(ctx context.Context, username string, knowledgeBaseIDs []uint, query, filterExpr string) (*SearchResult, code.Code) {
+	filter, err := rag.ParseFilter(filterExpr)
+	if err != nil {
+		return nil, code.CodeInvalidParams
+	}
+	if len(knowledgeBaseIDs) == 0 {
+		knowledgeBaseIDs = []uint{0}
+	}
+	resolved := make([]uint, 0, len(knowledgeBaseIDs))
+	for _, id := range knowledgeBaseIDs {
+		kb, code_ := knowledge.GetReadableKnowledgeBase(username, id)
+		if code_ != code.CodeSuccess {
+			return nil, code_
+		}
+		resolved = append(resolved, kb.ID)
+	}
+
+	// 改写模型、向量模型和 API 重排都可能是外部服务，与对话一样先脱敏再改写和检索
+	if redactor := redact.GetGlobalRedactor(); redactor != nil {
+		query = redactor.NewSession().Redact(query)
+	}
+
+	ctx, trace := rag.WithTrace(ctx)
+	start := time.Now()
+	docs, err := rag.RetrieveFromKnowledgeBases(ctx, username, resolved, rag.RewriteQuery(ctx, nil, query), filter)
+	if errors.Is(err, rag.ErrIndexForbidden) {
+		return nil, code.CodeForbidden
+	}
+	if err != nil {
+		log.Printf("SearchDocuments error: %v", err)
+		return nil, code.CodeServerBusy
+	}
+
+	result := &SearchResult{
+		Chunks:  make([]SearchChunk, 0, len(docs)),
+		Trace:   trace,
+		TotalMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	for i, doc := range docs {
+		result.Chunks = append(result.Chunks, SearchChunk{
+			Rank:     i + 1,
+			ID:       doc.ID,
+			Content:  doc.Content,
+			Metadata: doc.MetaData,
+		})
+	}
+	return result, code.CodeSuccess
 }
@@ -0,0 +1,71 @@
+package file
+
+import (
+	"GopherAI/common/rag"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// ingestJobStaleAfter 排队中或处理中的任务超过这段时间没有更新，视为处理任务的进程已退出或消息已丢失
+	// 执行中的任务每隔 rag.IngestHeartbeatInterval 更新一次，阈值需要远大于心跳间隔
+	ingestJobStaleAfter = 15 * rag.IngestHeartbeatInterval
+	// maxIngestAttempts 任务执行的次数上限，每次执行都中断的任务（如解析时进程崩溃）不再自动重新投递
+	maxIngestAttempts = 3
+)
+
+// isIngestJobStale 任务仍处于排队或处理中，但已经长时间没有进度
+func isIngestJobStale(job *model.IngestJob) bool {
+	switch job.Status {
+	case model.IngestJobQueued, model.IngestJobParsing, model.IngestJobEmbedding:
+		return time.Since(job.UpdatedAt) > ingestJobStaleAfter
+	}
+	return false
+}
+
+// WatchIngestJobs 启动时以及之后每隔 ingestJobStaleAfter 恢复中断的入库任务
+func WatchIngestJobs(ctx context.Context) {
+	RecoverIngestJobs()
+	ticker := time.NewTicker(ingestJobStaleAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			RecoverIngestJobs()
+		}
+	}
+}
+
+// RecoverIngestJobs 重新投递长时间没有进度的入库任务，已执行 maxIngestAttempts 次的任务标记为失败，之后可以手动重试
+func RecoverIngestJobs() {
+	jobs, err := knowledgeDao.GetStaleIngestJobs(time.Now().Add(-ingestJobStaleAfter))
+	if err != nil {
+		log.Printf("[ingest] list stale jobs failed: %v", err)
+		return
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Attempts >= maxIngestAttempts {
+			job.Status = model.IngestJobFailed
+			job.Error = fmt.Sprintf("interrupted after %d attempts", job.Attempts)
+			if err := knowledgeDao.SaveIngestJob(job); err != nil {
+				log.Printf("[ingest] fail job %d: %v", job.ID, err)
+				continue
+			}
+			knowledgeDao.UpdateDocumentStatus(job.DocumentID, model.DocumentStatusFailed, job.IndexedChunks)
+			log.Printf("[ingest] job %d marked failed: %s", job.ID, job.Error)
+			continue
+		}
+		if err := requeueIngestJob(job); err != nil {
+			log.Printf("[ingest] requeue job %d: %v", job.ID, err)
+			continue
+		}
+		log.Printf("[ingest] stale job %d requeued", job.ID)
+	}
+}
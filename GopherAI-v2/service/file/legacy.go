@@ -0,0 +1,199 @@
+package file
+
+import (
+	"GopherAI/common/code"
+	"GopherAI/common/vectorstore"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"GopherAI/service/knowledge"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// legacyFileIndexPattern 旧版本按文件建立的索引标识，即保存的文件名 <UUID>.<扩展名>
+var legacyFileIndexPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.[A-Za-z0-9]+$`)
+
+// MigrateLegacyUploads 迁移按知识库管理之前的数据：先将用户目录下的文件导入默认知识库，再删除按文件建立的旧索引
+// 返回导入的文件数量和删除的索引数量，dryRun 为 true 时只打印将要执行的操作
+func MigrateLegacyUploads(ctx context.Context, dryRun, inline bool) (int, int, error) {
+	imported, err := ImportLegacyUploads(ctx, dryRun, inline)
+	if err != nil {
+		return imported, 0, fmt.Errorf("import legacy uploads: %w", err)
+	}
+	dropped, err := DropLegacyFileIndexes(ctx, dryRun)
+	if err != nil {
+		return imported, dropped, fmt.Errorf("drop legacy indexes: %w", err)
+	}
+	return imported, dropped, nil
+}
+
+// ImportLegacyUploads 将按知识库管理之前上传的文件导入用户的默认知识库，返回导入的文件数量
+// 旧版本每个用户只有一个文件，直接保存在 uploads/<用户名>/<UUID>.<扩展名>，检索时使用按文件建立的索引；
+// 文件移动到默认知识库目录（保存的文件名不变）并创建文档记录后重新入库，移走的文件不会再次导入
+// inline 为 true 时在当前进程中直接入库（命令行工具没有消息队列的消费者），否则投递到消息队列
+func ImportLegacyUploads(ctx context.Context, dryRun, inline bool) (int, error) {
+	users, err := os.ReadDir(knowledge.UploadDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, user := range users {
+		if !user.IsDir() {
+			continue
+		}
+		userDir := filepath.Join(knowledge.UploadDir, user.Name())
+		files, err := os.ReadDir(userDir)
+		if err != nil {
+			log.Printf("[legacy] read %s failed: %v", userDir, err)
+			continue
+		}
+		for _, f := range files {
+			// 子目录是知识库目录，隐藏文件不是上传的文档
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(userDir, f.Name())
+			if dryRun {
+				log.Printf("[legacy] would import %s into default knowledge base of %s", path, user.Name())
+				imported++
+				continue
+			}
+			doc, err := importLegacyUpload(user.Name(), path)
+			if err != nil {
+				log.Printf("[legacy] import %s failed: %v", path, err)
+				continue
+			}
+			imported++
+			if inline {
+				err = reingestDocument(ctx, doc)
+			} else {
+				_, err = queueIngestJob(doc)
+			}
+			if err != nil {
+				log.Printf("[legacy] ingest document %d failed: %v", doc.ID, err)
+			}
+			log.Printf("[legacy] %s imported as document %d", path, doc.ID)
+		}
+	}
+	return imported, nil
+}
+
+// importLegacyUpload 将文件移动到用户的默认知识库目录并创建文档记录
+// 旧版本没有记录原始文件名，文档名使用保存的文件名
+func importLegacyUpload(userName, path string) (*model.Document, error) {
+	if err := detectSharedFile(path); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	contentHash, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kb, code_ := knowledge.GetKnowledgeBase(userName, 0)
+	if code_ != code.CodeSuccess {
+		return nil, fmt.Errorf("get default knowledge base: %s", code_.Msg())
+	}
+
+	kbDir := knowledge.KnowledgeBaseDir(userName, kb.ID)
+	if err := os.MkdirAll(kbDir, 0755); err != nil {
+		return nil, err
+	}
+	storedName := filepath.Base(path)
+	storedPath := filepath.Join(kbDir, storedName)
+	// 多个实例同时导入时只有一个能移走文件
+	if err := os.Rename(path, storedPath); err != nil {
+		return nil, err
+	}
+	doc, err := knowledgeDao.CreateDocument(&model.Document{
+		KnowledgeBaseID: kb.ID,
+		UserName:        userName,
+		FileName:        storedName,
+		StoredName:      storedName,
+		FilePath:        storedPath,
+		ContentHash:     contentHash,
+		Size:            info.Size(),
+		Status:          model.DocumentStatusIndexing,
+	})
+	if err != nil {
+		if err := os.Rename(storedPath, path); err != nil {
+			log.Printf("[legacy] move %s back failed: %v", storedPath, err)
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// DropLegacyFileIndexes 删除旧版本按文件建立的索引（rag_docs:<文件名>:*）及其数据，返回删除的数量
+// 包括旧版本重新上传时不带 DD 删除索引留下的数据；文件仍在用户目录下（尚未导入）的索引保留
+// 旧索引中整篇文档只有一个块，向量模型也可能已经不同，文件导入知识库后重新入库，不迁移旧的块
+func DropLegacyFileIndexes(ctx context.Context, dryRun bool) (int, error) {
+	pending, err := legacyUploadNames()
+	if err != nil {
+		return 0, err
+	}
+	store := vectorstore.Default()
+	names, err := store.Collections(ctx)
+	if err != nil {
+		return 0, err
+	}
+	dropped := 0
+	for _, name := range names {
+		if !legacyFileIndexPattern.MatchString(name) || pending[name] {
+			continue
+		}
+		if dryRun {
+			log.Printf("[legacy] would drop index %s", name)
+			dropped++
+			continue
+		}
+		if err := store.DropCollection(ctx, name); err != nil {
+			log.Printf("[legacy] drop index %s failed: %v", name, err)
+			continue
+		}
+		if err := knowledgeDao.DeleteVectorIndex(name); err != nil {
+			log.Printf("[legacy] delete owner of %s failed: %v", name, err)
+		}
+		dropped++
+		log.Printf("[legacy] index %s dropped", name)
+	}
+	return dropped, nil
+}
+
+// legacyUploadNames 仍在用户目录下、尚未导入知识库的文件名
+func legacyUploadNames() (map[string]bool, error) {
+	names := make(map[string]bool)
+	users, err := os.ReadDir(knowledge.UploadDir)
+	if os.IsNotExist(err) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if !user.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(knowledge.UploadDir, user.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if !f.IsDir() {
+				names[f.Name()] = true
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,405 @@
+package file
+
+import (
+	"GopherAI/common/rag"
+	"GopherAI/common/rag/splitter"
+	"GopherAI/common/vectorstore"
+	"GopherAI/config"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"GopherAI/service/knowledge"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 对账发现的差异类型
+const (
+	IssueOrphanFile        = "orphan_file"        // 上传目录中没有文档记录的文件：上传中途失败或删除文档时没有删掉文件
+	IssueMissingFile       = "missing_file"       // 文档记录的文件已不存在：删除记录和索引中的块
+	IssueOrphanDocument    = "orphan_document"    // 所属知识库已删除的文档记录
+	IssueUnindexedDocument = "unindexed_document" // 状态为已入库，但索引中没有任何块：重新入库
+	IssueOrphanChunks      = "orphan_chunks"      // 索引中没有文档记录的块
+	IssueOrphanIndex       = "orphan_index"       // 没有对应知识库的索引及其数据
+)
+
+// ReconcileIssue 一处差异及其处理结果
+type ReconcileIssue struct {
+	Kind       string `json:"kind"`
+	Index      string `json:"index,omitempty"`
+	Path       string `json:"path,omitempty"`
+	DocumentID uint   `json:"document_id,omitempty"`
+	Document   string `json:"document,omitempty"` // 块 ID 中的文档 ID（保存的文件名去掉扩展名）
+	Chunks     int    `json:"chunks,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	Fixed      bool   `json:"fixed"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ReconcileReport 对账结果，DryRun 为 true 时只记录差异，不做修复
+type ReconcileReport struct {
+	DryRun     bool              `json:"dry_run"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Files      int               `json:"files"`     // 上传目录中知识库文件的数量
+	Documents  int               `json:"documents"` // 文档记录数
+	Indexes    int               `json:"indexes"`   // 向量存储中知识库索引的数量
+	Chunks     int               `json:"chunks"`    // 知识库索引中的块数
+	Issues     []*ReconcileIssue `json:"issues"`
+}
+
+// Counts 按差异类型统计：发现的数量、修复的数量、涉及的块数
+func (r *ReconcileReport) Counts() (found, fixed, chunks map[string]int) {
+	found, fixed, chunks = make(map[string]int), make(map[string]int), make(map[string]int)
+	for _, issue := range r.Issues {
+		found[issue.Kind]++
+		if issue.Fixed {
+			fixed[issue.Kind]++
+		}
+		chunks[issue.Kind] += issue.Chunks
+	}
+	return found, fixed, chunks
+}
+
+// Summary 一行汇总，用于日志
+func (r *ReconcileReport) Summary() string {
+	found, fixed, chunks := r.Counts()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d files, %d documents, %d indexes, %d chunks checked in %dms", r.Files, r.Documents, r.Indexes, r.Chunks, r.DurationMs)
+	if len(r.Issues) == 0 {
+		b.WriteString("; no issues")
+		return b.String()
+	}
+	kinds := make([]string, 0, len(found))
+	for kind := range found {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "; %s=%d", kind, found[kind])
+		if chunks[kind] > 0 {
+			fmt.Fprintf(&b, " (%d chunks)", chunks[kind])
+		}
+		if !r.DryRun {
+			fmt.Fprintf(&b, " fixed=%d", fixed[kind])
+		}
+	}
+	return b.String()
+}
+
+var reconcileMux sync.Mutex
+
+// WatchReconcile 按 storageConfig.reconcileInterval 定期对账，差异写入日志，reconcileFix 开启时才修复
+func WatchReconcile(ctx context.Context) {
+	conf := config.GetConfig().StorageConfig
+	if conf.StorageReconcileInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(conf.StorageReconcileInterval) * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := Reconcile(ctx, !conf.StorageReconcileFix); err != nil {
+				log.Printf("[reconcile] failed: %v", err)
+			}
+		}
+	}
+}
+
+// Reconcile 对比上传目录、MySQL 中的知识库和文档记录、向量存储中的索引和块，报告并修复差异：
+// 删除没有记录的文件、没有文件的记录、没有文档的块和没有知识库的索引，重新入库丢失了块的文档
+// 最近 reconcileGrace 分钟内修改过的文件和文档不计入差异，正在上传或入库的数据不会被误删
+func Reconcile(ctx context.Context, dryRun bool) (*ReconcileReport, error) {
+	reconcileMux.Lock()
+	defer reconcileMux.Unlock()
+
+	grace := time.Duration(config.GetConfig().StorageReconcileGrace) * time.Minute
+	r := &reconciler{
+		ctx:    ctx,
+		store:  vectorstore.Default(),
+		cutoff: time.Now().Add(-grace),
+		report: &ReconcileReport{DryRun: dryRun, StartedAt: time.Now(), Issues: make([]*ReconcileIssue, 0)},
+	}
+
+	kbs, err := knowledgeDao.GetAllKnowledgeBases()
+	if err != nil {
+		return nil, fmt.Errorf("list knowledge bases: %w", err)
+	}
+	docs, err := knowledgeDao.GetAllDocuments()
+	if err != nil {
+		return nil, fmt.Errorf("list documents: %w", err)
+	}
+	r.report.Documents = len(docs)
+	r.kbs = make(map[uint]*model.KnowledgeBase, len(kbs))
+	for i := range kbs {
+		r.kbs[kbs[i].ID] = &kbs[i]
+	}
+
+	if err := r.checkFiles(docs); err != nil {
+		return nil, err
+	}
+	chunks, err := r.checkIndexes(docs)
+	if err != nil {
+		return nil, err
+	}
+	r.checkDocuments(docs, chunks)
+
+	r.report.DurationMs = time.Since(r.report.StartedAt).Milliseconds()
+	log.Printf("[reconcile] dry_run=%t: %s", dryRun, r.report.Summary())
+	return r.report, nil
+}
+
+type reconciler struct {
+	ctx    context.Context
+	store  vectorstore.Store
+	cutoff time.Time // 在此之后修改过的文件和文档不计入差异
+	kbs    map[uint]*model.KnowledgeBase
+	report *ReconcileReport
+}
+
+// fix 记录差异，非 dry-run 时执行修复
+func (r *reconciler) fix(issue *ReconcileIssue, action func() error) {
+	r.report.Issues = append(r.report.Issues, issue)
+	if r.report.DryRun {
+		log.Printf("[reconcile] found %s: index=%q path=%q document=%d chunks=%d", issue.Kind, issue.Index, issue.Path, issue.DocumentID, issue.Chunks)
+		return
+	}
+	if err := action(); err != nil {
+		issue.Error = err.Error()
+		log.Printf("[reconcile] fix %s failed: index=%q path=%q document=%d: %v", issue.Kind, issue.Index, issue.Path, issue.DocumentID, err)
+		return
+	}
+	issue.Fixed = true
+	log.Printf("[reconcile] fixed %s: index=%q path=%q document=%d chunks=%d", issue.Kind, issue.Index, issue.Path, issue.DocumentID, issue.Chunks)
+}
+
+// checkFiles 上传目录中没有文档记录的文件
+// 只检查知识库目录（<用户名>/<知识库ID>/<文件>）中的文件，其他位置的文件（如按知识库管理之前直接保存在用户目录下的文件）不做处理
+func (r *reconciler) checkFiles(docs []model.Document) error {
+	referenced := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		referenced[filepath.Clean(doc.FilePath)] = true
+	}
+	err := filepath.WalkDir(knowledge.UploadDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !isKnowledgeBaseFile(path) {
+			return nil
+		}
+		r.report.Files++
+		if referenced[filepath.Clean(path)] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(r.cutoff) {
+			return nil
+		}
+		r.fix(&ReconcileIssue{Kind: IssueOrphanFile, Path: path, Size: info.Size()}, func() error {
+			return os.Remove(path)
+		})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("walk %s: %w", knowledge.UploadDir, err)
+	}
+	return nil
+}
+
+// isKnowledgeBaseFile 文件位于 <UploadDir>/<用户名>/<知识库ID>/ 下
+func isKnowledgeBaseFile(path string) bool {
+	rel, err := filepath.Rel(knowledge.UploadDir, path)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 3 {
+		return false
+	}
+	_, err = strconv.ParseUint(parts[1], 10, 64)
+	return err == nil
+}
+
+// knowledgeBaseIndexPattern 知识库索引的标识：u_<所有者>:kb_<ID>，以及迁移前的 kb_<ID>
+// 只有这两种索引由对账处理；按文件建立的旧索引由 cmd/migrate-namespace 迁移，rag-eval 的临时索引（eval_*）由其自己删除
+var knowledgeBaseIndexPattern = regexp.MustCompile(`^(?:u_[^:]+:)?kb_(\d+)$`)
+
+// checkIndexes 没有对应知识库的索引和没有文档记录的块，返回每个知识库索引中各文档的块数量
+func (r *reconciler) checkIndexes(docs []model.Document) (map[string]map[string]int, error) {
+	names, err := r.store.Collections(r.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list indexes: %w", err)
+	}
+
+	// 知识库索引 -> 文档 ID（块 ID 前缀）
+	expected := make(map[string]map[string]bool, len(r.kbs))
+	for _, kb := range r.kbs {
+		expected[rag.KnowledgeBaseIndex(kb.UserName, kb.ID)] = make(map[string]bool)
+	}
+	for _, doc := range docs {
+		if kb, ok := r.kbs[doc.KnowledgeBaseID]; ok {
+			expected[rag.KnowledgeBaseIndex(kb.UserName, kb.ID)][rag.DocumentKey(doc.FilePath)] = true
+		}
+	}
+
+	chunks := make(map[string]map[string]int, len(names))
+	for _, name := range names {
+		m := knowledgeBaseIndexPattern.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		kbID, _ := strconv.ParseUint(m[1], 10, 64)
+		r.report.Indexes++
+
+		ids, err := r.store.List(r.ctx, name, vectorstore.Filter{})
+		if err != nil {
+			return nil, fmt.Errorf("list chunks of %s: %w", name, err)
+		}
+		r.report.Chunks += len(ids)
+
+		documents, ok := expected[name]
+		if !ok {
+			r.checkOrphanIndex(name, uint(kbID), len(ids))
+			continue
+		}
+		counts := make(map[string]int)
+		for _, id := range ids {
+			if document, ok := splitter.DocumentOfChunk(id); ok {
+				counts[document]++
+			}
+		}
+		chunks[name] = counts
+		r.checkOrphanChunks(name, uint(kbID), documents, counts)
+	}
+	return chunks, nil
+}
+
+// checkOrphanChunks 索引中没有文档记录的块
+// 入库时总是先创建文档记录再写入块，列出块之后重新查询知识库的文档，对账开始后才上传并入库的文档不会被当作孤儿
+func (r *reconciler) checkOrphanChunks(name string, kbID uint, documents map[string]bool, counts map[string]int) {
+	var current map[string]bool
+	for document, n := range counts {
+		if documents[document] {
+			continue
+		}
+		if current == nil {
+			docs, err := knowledgeDao.GetDocumentsByKnowledgeBase(kbID)
+			if err != nil {
+				log.Printf("[reconcile] list documents of knowledge base %d failed: %v", kbID, err)
+				return
+			}
+			current = make(map[string]bool, len(docs))
+			for _, doc := range docs {
+				current[rag.DocumentKey(doc.FilePath)] = true
+			}
+		}
+		if current[document] {
+			continue
+		}
+		r.fix(&ReconcileIssue{Kind: IssueOrphanChunks, Index: name, Document: document, Chunks: n}, func() error {
+			_, err := r.store.Delete(r.ctx, name, vectorstore.Filter{Documents: []string{document}})
+			return err
+		})
+	}
+}
+
+// checkOrphanIndex 没有对应知识库的索引：知识库已删除但删除索引失败等
+// 删除前重新查询知识库，对账开始后才创建的知识库的索引不会被当作孤儿；
+// 迁移前的旧命名空间索引（kb_<id>）在知识库仍存在且有元信息时保留，由 cmd/migrate-namespace 迁移，没有元信息的视为孤儿
+func (r *reconciler) checkOrphanIndex(name string, kbID uint, chunks int) {
+	kb, err := knowledgeDao.GetKnowledgeBaseByID(kbID)
+	if err == nil {
+		if name == rag.KnowledgeBaseIndex(kb.UserName, kb.ID) {
+			return
+		}
+		if name == rag.LegacyKnowledgeBaseIndex(kb.ID) {
+			collection, err := r.store.GetCollection(r.ctx, name)
+			if err != nil || collection != nil {
+				log.Printf("[reconcile] legacy index %s not migrated yet, skipped", name)
+				return
+			}
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("[reconcile] get knowledge base %d failed: %v", kbID, err)
+		return
+	}
+	r.fix(&ReconcileIssue{Kind: IssueOrphanIndex, Index: name, Chunks: chunks}, func() error {
+		if err := r.store.DropCollection(r.ctx, name); err != nil {
+			return err
+		}
+		return knowledgeDao.DeleteVectorIndex(name)
+	})
+}
+
+// checkDocuments 所属知识库已删除、文件已丢失或索引中没有块的文档记录
+func (r *reconciler) checkDocuments(docs []model.Document, chunks map[string]map[string]int) {
+	for i := range docs {
+		doc := &docs[i]
+		if doc.UpdatedAt.After(r.cutoff) {
+			continue
+		}
+		kb, ok := r.kbs[doc.KnowledgeBaseID]
+		if !ok {
+			r.fix(&ReconcileIssue{Kind: IssueOrphanDocument, Path: doc.FilePath, DocumentID: doc.ID}, func() error {
+				if err := os.Remove(doc.FilePath); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				return knowledgeDao.DeleteDocumentWithJobs(doc.ID)
+			})
+			continue
+		}
+
+		indexName := rag.KnowledgeBaseIndex(kb.UserName, kb.ID)
+		n := chunks[indexName][rag.DocumentKey(doc.FilePath)]
+		if _, err := os.Stat(doc.FilePath); os.IsNotExist(err) {
+			r.fix(&ReconcileIssue{Kind: IssueMissingFile, Index: indexName, Path: doc.FilePath, DocumentID: doc.ID, Chunks: n}, func() error {
+				if _, err := rag.DeleteDocumentChunks(r.ctx, kb.UserName, kb.ID, doc.FilePath); err != nil {
+					return err
+				}
+				return knowledgeDao.DeleteDocumentWithJobs(doc.ID)
+			})
+			continue
+		}
+
+		if doc.Status == model.DocumentStatusIndexed && doc.ChunkCount > 0 && n == 0 {
+			r.fix(&ReconcileIssue{Kind: IssueUnindexedDocument, Index: indexName, Path: doc.FilePath, DocumentID: doc.ID}, func() error {
+				return reingestDocument(r.ctx, doc)
+			})
+		}
+	}
+}
+
+// reingestDocument 创建入库任务并直接执行，不经过消息队列，命令行工具中也可以使用
+func reingestDocument(ctx context.Context, doc *model.Document) error {
+	if err := knowledgeDao.UpdateDocumentStatus(doc.ID, model.DocumentStatusIndexing, 0); err != nil {
+		return err
+	}
+	job, err := knowledgeDao.CreateIngestJob(&model.IngestJob{
+		DocumentID:      doc.ID,
+		KnowledgeBaseID: doc.KnowledgeBaseID,
+		UserName:        doc.UserName,
+		Status:          model.IngestJobQueued,
+	})
+	if err != nil {
+		return err
+	}
+	return rag.RunIngestJob(ctx, job.ID)
+}
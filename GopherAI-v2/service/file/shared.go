@@ -0,0 +1,320 @@
+package file
+
+import (
+	"GopherAI/common/rag"
+	"GopherAI/common/rag/loader"
+	"GopherAI/config"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"GopherAI/service/knowledge"
+	"GopherAI/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 共享知识库的名称和说明
+const (
+	sharedKnowledgeBaseName = "团队文档"
+	sharedKnowledgeBaseDesc = "由服务端从文档目录导入，所有用户都可以检索"
+)
+
+// sharedFileState 上次同步时文件的大小和修改时间，未变化的文件不再计算哈希
+type sharedFileState struct {
+	size    int64
+	modTime time.Time
+}
+
+var (
+	sharedFiles   = make(map[string]sharedFileState) // 相对路径 -> 上次同步时的状态
+	sharedSyncMux sync.Mutex
+)
+
+// WatchSharedDocuments 启动时将 ragModelConfig.docDir 导入共享知识库，之后按 docWatchInterval 轮询目录变化
+// 轮询不依赖 inotify，目录位于网络存储或容器挂载卷时同样可用；多实例部署时只应在一个实例上配置 docDir
+func WatchSharedDocuments(ctx context.Context) {
+	conf := config.GetConfig()
+	if conf.RagDocDir == "" {
+		return
+	}
+	if err := SyncSharedDocuments(ctx); err != nil {
+		log.Printf("[shared-docs] sync %s failed: %v", conf.RagDocDir, err)
+	}
+	if conf.RagDocWatchInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(conf.RagDocWatchInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := SyncSharedDocuments(ctx); err != nil {
+				log.Printf("[shared-docs] sync %s failed: %v", conf.RagDocDir, err)
+			}
+		}
+	}
+}
+
+// SyncSharedDocuments 对比 docDir 与共享知识库中的文档，增量同步：
+// 新增的文件创建文档并入库，内容变化的文件作为新版本重新入库（只向量化变化的块），已删除的文件从知识库中删除
+// 文件复制到知识库目录后再入库，入库过程中 docDir 中的文件被修改不会影响本次入库
+func SyncSharedDocuments(ctx context.Context) error {
+	sharedSyncMux.Lock()
+	defer sharedSyncMux.Unlock()
+
+	dir := config.GetConfig().RagDocDir
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	kb, err := getOrCreateSharedKnowledgeBase()
+	if err != nil {
+		return err
+	}
+	docs, err := knowledgeDao.GetDocumentsByKnowledgeBase(kb.ID)
+	if err != nil {
+		return fmt.Errorf("list shared documents: %w", err)
+	}
+	existing := make(map[string]*model.Document, len(docs))
+	for i := range docs {
+		existing[docs[i].FileName] = &docs[i]
+	}
+
+	seen := make(map[string]bool)
+	var added, updated, removed int
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 跳过隐藏文件和目录（.git、编辑器临时文件等）
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		seen[name] = true
+
+		doc := existing[name]
+		changed, err := syncSharedFile(kb, path, name, doc)
+		if err != nil {
+			log.Printf("[shared-docs] sync %s failed: %v", name, err)
+			return nil
+		}
+		if changed && doc == nil {
+			added++
+		} else if changed {
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// docDir 中已不存在的文件
+	for name, doc := range existing {
+		if seen[name] {
+			continue
+		}
+		if err := deleteSharedDocument(ctx, doc); err != nil {
+			log.Printf("[shared-docs] delete %s failed: %v", name, err)
+			continue
+		}
+		delete(sharedFiles, name)
+		removed++
+	}
+
+	if added+updated+removed > 0 {
+		log.Printf("[shared-docs] synced %s: %d added, %d updated, %d removed", dir, added, updated, removed)
+	}
+	return nil
+}
+
+// getOrCreateSharedKnowledgeBase 获取共享知识库，不存在时创建
+func getOrCreateSharedKnowledgeBase() (*model.KnowledgeBase, error) {
+	kb, err := knowledgeDao.GetSharedKnowledgeBase()
+	if err == nil {
+		return kb, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("get shared knowledge base: %w", err)
+	}
+	kb, err = knowledgeDao.CreateKnowledgeBase(&model.KnowledgeBase{
+		UserName:    model.SharedKnowledgeBaseOwner,
+		Name:        sharedKnowledgeBaseName,
+		Description: sharedKnowledgeBaseDesc,
+		VectorIndex: rag.IndexSettings(rag.IndexOptions(model.VectorIndexSettings{})),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create shared knowledge base: %w", err)
+	}
+	log.Printf("[shared-docs] shared knowledge base %d created", kb.ID)
+	return kb, nil
+}
+
+// syncSharedFile 同步单个文件，文件为新增或内容有变化并已创建入库任务时返回 true
+func syncSharedFile(kb *model.KnowledgeBase, path, name string, doc *model.Document) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	state := sharedFileState{size: info.Size(), modTime: info.ModTime()}
+	if last, ok := sharedFiles[name]; ok && last == state && doc != nil {
+		return false, nil
+	}
+
+	// 不支持的文件类型只记录一次，文件不变时不再重复检查
+	if err := detectSharedFile(path); err != nil {
+		if _, ok := sharedFiles[name]; !ok {
+			log.Printf("[shared-docs] skip %s: %v", name, err)
+		}
+		sharedFiles[name] = state
+		return false, nil
+	}
+
+	contentHash, err := hashFile(path)
+	if err != nil {
+		return false, err
+	}
+	if doc != nil && doc.ContentHash == contentHash && doc.Status != model.DocumentStatusFailed {
+		sharedFiles[name] = state
+		return false, nil
+	}
+
+	if doc == nil {
+		kbDir := knowledge.KnowledgeBaseDir(model.SharedKnowledgeBaseOwner, kb.ID)
+		if err := os.MkdirAll(kbDir, 0755); err != nil {
+			return false, err
+		}
+		storedName := utils.GenerateUUID() + filepath.Ext(path)
+		storedPath := filepath.Join(kbDir, storedName)
+		if err := copyFile(path, storedPath); err != nil {
+			return false, err
+		}
+		doc, err = knowledgeDao.CreateDocument(&model.Document{
+			KnowledgeBaseID: kb.ID,
+			UserName:        model.SharedKnowledgeBaseOwner,
+			FileName:        name,
+			StoredName:      storedName,
+			FilePath:        storedPath,
+			ContentHash:     contentHash,
+			Size:            info.Size(),
+			Status:          model.DocumentStatusIndexing,
+		})
+		if err != nil {
+			os.Remove(storedPath)
+			return false, err
+		}
+	} else {
+		// 新版本：覆盖保存的文件并沿用原文档记录，块 ID 前缀不变，入库时只向量化变化的块
+		if err := copyFile(path, doc.FilePath); err != nil {
+			return false, err
+		}
+		doc.ContentHash = contentHash
+		doc.Size = info.Size()
+		doc.Status = model.DocumentStatusIndexing
+		if err := knowledgeDao.SaveDocument(doc); err != nil {
+			return false, err
+		}
+	}
+
+	job, err := queueIngestJob(doc)
+	if err != nil {
+		return false, err
+	}
+	sharedFiles[name] = state
+	log.Printf("[shared-docs] ingest job %d queued for %s", job.ID, name)
+	return true, nil
+}
+
+// deleteSharedDocument 删除共享知识库中的文档：索引中的块、保存的文件、文档记录和入库任务
+func deleteSharedDocument(ctx context.Context, doc *model.Document) error {
+	if _, err := rag.DeleteDocumentChunks(ctx, model.SharedKnowledgeBaseOwner, doc.KnowledgeBaseID, doc.FilePath); err != nil {
+		return err
+	}
+	if err := os.Remove(doc.FilePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("[shared-docs] remove %s failed: %v", doc.FilePath, err)
+	}
+	if err := knowledgeDao.DeleteDocumentWithJobs(doc.ID); err != nil {
+		return err
+	}
+	log.Printf("[shared-docs] document %d (%s) deleted", doc.ID, doc.FileName)
+	return nil
+}
+
+// detectSharedFile 与上传相同，按扩展名和内容嗅探校验文件类型
+func detectSharedFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	head := make([]byte, loader.SniffLimit)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	_, err = loader.Detect(path, head[:n])
+	return err
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// copyFile 先写临时文件再重命名，入库任务不会读到写了一半的文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
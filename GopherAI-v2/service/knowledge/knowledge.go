@@ -0,0 +1,175 @@
+package knowledge
+
+import (
+	"GopherAI/common/code"
+	"GopherAI/common/rag"
+	knowledgeDao "GopherAI/dao/knowledge"
+	"GopherAI/model"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 用户第一次上传文件时自动创建的知识库名称
+const defaultKnowledgeBaseName = "默认知识库"
+
+// CreateKnowledgeBase 创建知识库，settings 中未设置的向量索引参数使用配置文件中的默认值
+func CreateKnowledgeBase(userName, name, description string, settings model.VectorIndexSettings) (*model.KnowledgeBase, code.Code) {
+	if name == "" {
+		return nil, code.CodeInvalidParams
+	}
+	opts := rag.IndexOptions(settings)
+	if err := opts.Validate(); err != nil {
+		log.Println("CreateKnowledgeBase invalid vector index settings:", err)
+		return nil, code.CodeInvalidParams
+	}
+	kbs, err := knowledgeDao.GetKnowledgeBasesByUserName(userName)
+	if err != nil {
+		log.Println("CreateKnowledgeBase GetKnowledgeBasesByUserName error:", err)
+		return nil, code.CodeServerBusy
+	}
+
+	kb, err := knowledgeDao.CreateKnowledgeBase(&model.KnowledgeBase{
+		UserName:    userName,
+		Name:        name,
+		Description: description,
+		IsDefault:   len(kbs) == 0, // 第一个知识库自动设为默认
+		VectorIndex: rag.IndexSettings(opts),
+	})
+	if err != nil {
+		log.Println("CreateKnowledgeBase error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return kb, code.CodeSuccess
+}
+
+// ListKnowledgeBases 列出用户的知识库，共享知识库（已导入时）排在最后
+func ListKnowledgeBases(userName string) ([]model.KnowledgeBase, code.Code) {
+	kbs, err := knowledgeDao.GetKnowledgeBasesByUserName(userName)
+	if err != nil {
+		log.Println("ListKnowledgeBases error:", err)
+		return nil, code.CodeServerBusy
+	}
+	shared, err := knowledgeDao.GetSharedKnowledgeBase()
+	if err == nil {
+		kbs = append(kbs, *shared)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Println("ListKnowledgeBases GetSharedKnowledgeBase error:", err)
+	}
+	return kbs, code.CodeSuccess
+}
+
+// GetKnowledgeBase 获取用户的知识库，id 为 0 时获取默认知识库，不存在则自动创建
+func GetKnowledgeBase(userName string, id uint) (*model.KnowledgeBase, code.Code) {
+	if id == 0 {
+		return getOrCreateDefaultKnowledgeBase(userName)
+	}
+	kb, err := knowledgeDao.GetKnowledgeBase(userName, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, code.CodeRecordNotFound
+		}
+		log.Println("GetKnowledgeBase error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return kb, code.CodeSuccess
+}
+
+// GetReadableKnowledgeBase 获取用户可以检索的知识库，除用户自己的知识库外还可以是共享知识库
+// 只用于检索和查看，上传、删除等修改操作使用 GetKnowledgeBase
+func GetReadableKnowledgeBase(userName string, id uint) (*model.KnowledgeBase, code.Code) {
+	if id == 0 {
+		return getOrCreateDefaultKnowledgeBase(userName)
+	}
+	kb, err := knowledgeDao.GetReadableKnowledgeBase(userName, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, code.CodeRecordNotFound
+		}
+		log.Println("GetReadableKnowledgeBase error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return kb, code.CodeSuccess
+}
+
+func getOrCreateDefaultKnowledgeBase(userName string) (*model.KnowledgeBase, code.Code) {
+	kb, err := knowledgeDao.GetDefaultKnowledgeBase(userName)
+	if err == nil {
+		return kb, code.CodeSuccess
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Println("GetDefaultKnowledgeBase error:", err)
+		return nil, code.CodeServerBusy
+	}
+
+	kb, err = knowledgeDao.CreateKnowledgeBase(&model.KnowledgeBase{
+		UserName:    userName,
+		Name:        defaultKnowledgeBaseName,
+		IsDefault:   true,
+		VectorIndex: rag.IndexSettings(rag.IndexOptions(model.VectorIndexSettings{})),
+	})
+	if err != nil {
+		log.Println("CreateDefaultKnowledgeBase error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return kb, code.CodeSuccess
+}
+
+// SetDefaultKnowledgeBase 设置对话时默认使用的知识库
+func SetDefaultKnowledgeBase(userName string, id uint) code.Code {
+	if _, code_ := GetKnowledgeBase(userName, id); code_ != code.CodeSuccess {
+		return code_
+	}
+	if err := knowledgeDao.SetDefaultKnowledgeBase(userName, id); err != nil {
+		log.Println("SetDefaultKnowledgeBase error:", err)
+		return code.CodeServerBusy
+	}
+	return code.CodeSuccess
+}
+
+// DeleteKnowledgeBase 删除知识库：索引、向量数据、文件和数据库记录
+func DeleteKnowledgeBase(userName string, id uint) code.Code {
+	kb, code_ := GetKnowledgeBase(userName, id)
+	if code_ != code.CodeSuccess {
+		return code_
+	}
+
+	if err := rag.DropKnowledgeBaseIndex(context.Background(), userName, kb.ID); err != nil {
+		log.Println("DeleteKnowledgeBase DropKnowledgeBaseIndex error:", err)
+		return code.CodeServerBusy
+	}
+	if err := os.RemoveAll(KnowledgeBaseDir(userName, kb.ID)); err != nil {
+		log.Println("DeleteKnowledgeBase RemoveAll error:", err)
+	}
+	if err := knowledgeDao.DeleteKnowledgeBase(kb.ID); err != nil {
+		log.Println("DeleteKnowledgeBase error:", err)
+		return code.CodeServerBusy
+	}
+	return code.CodeSuccess
+}
+
+func ListDocuments(userName string, id uint) ([]model.Document, code.Code) {
+	kb, code_ := GetReadableKnowledgeBase(userName, id)
+	if code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	docs, err := knowledgeDao.GetDocumentsByKnowledgeBase(kb.ID)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return docs, code.CodeSuccess
+}
+
+// UploadDir 上传文件的根目录，结构为 <UploadDir>/<用户名>/<知识库ID>/<UUID>.<扩展名>
+const UploadDir = "uploads"
+
+// KnowledgeBaseDir 知识库文件的存放目录
+func KnowledgeBaseDir(userName string, id uint) string {
+	return filepath.Join(UploadDir, userName, strconv.FormatUint(uint64(id), 10))
+}
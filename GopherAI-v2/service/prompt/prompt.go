@@ -0,0 +1,114 @@
+package prompt
+
+import (
+	"GopherAI/common/code"
+	promptTpl "GopherAI/common/prompt"
+	promptDao "GopherAI/dao/prompt"
+	"GopherAI/model"
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+func ListPromptTemplates(name string) ([]model.PromptTemplate, code.Code) {
+	templates, err := promptDao.ListPromptTemplates(name)
+	if err != nil {
+		log.Println("ListPromptTemplates error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return templates, code.CodeSuccess
+}
+
+func GetPromptTemplate(id uint) (*model.PromptTemplate, code.Code) {
+	template, err := promptDao.GetPromptTemplateByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, code.CodeRecordNotFound
+		}
+		log.Println("GetPromptTemplate error:", err)
+		return nil, code.CodeServerBusy
+	}
+	return template, code.CodeSuccess
+}
+
+// CreatePromptTemplate 保存模板的新版本，保存前校验模板语法
+func CreatePromptTemplate(userName string, template *model.PromptTemplate) (*model.PromptTemplate, code.Code) {
+	if template.Name == "" || template.Content == "" {
+		return nil, code.CodeInvalidParams
+	}
+	if template.Locale == "" {
+		template.Locale = promptTpl.DefaultTarget("").Locale
+	}
+	if _, err := promptTpl.Parse(template.Name, template.Content); err != nil {
+		log.Println("CreatePromptTemplate parse error:", err)
+		return nil, code.CodeInvalidParams
+	}
+
+	template.CreatedBy = userName
+	created, err := promptDao.CreatePromptTemplateVersion(template)
+	if err != nil {
+		log.Println("CreatePromptTemplate error:", err)
+		return nil, code.CodeServerBusy
+	}
+	promptTpl.GetRegistry().Invalidate()
+	return created, code.CodeSuccess
+}
+
+// UpdatePromptTemplate 修改模板：不覆盖旧版本，而是基于旧版本生成新版本
+func UpdatePromptTemplate(userName string, id uint, content, description string) (*model.PromptTemplate, code.Code) {
+	old, code_ := GetPromptTemplate(id)
+	if code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	template := &model.PromptTemplate{
+		Name:        old.Name,
+		Locale:      old.Locale,
+		ModelName:   old.ModelName,
+		Content:     content,
+		Description: description,
+	}
+	if template.Description == "" {
+		template.Description = old.Description
+	}
+	return CreatePromptTemplate(userName, template)
+}
+
+// ActivatePromptTemplate 将指定版本设为生效，可用于回滚
+func ActivatePromptTemplate(id uint) (*model.PromptTemplate, code.Code) {
+	template, err := promptDao.ActivatePromptTemplate(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, code.CodeRecordNotFound
+		}
+		log.Println("ActivatePromptTemplate error:", err)
+		return nil, code.CodeServerBusy
+	}
+	promptTpl.GetRegistry().Invalidate()
+	return template, code.CodeSuccess
+}
+
+func DeletePromptTemplate(id uint) code.Code {
+	if _, code_ := GetPromptTemplate(id); code_ != code.CodeSuccess {
+		return code_
+	}
+	if err := promptDao.DeletePromptTemplate(id); err != nil {
+		log.Println("DeletePromptTemplate error:", err)
+		return code.CodeServerBusy
+	}
+	promptTpl.GetRegistry().Invalidate()
+	return code.CodeSuccess
+}
+
+// PreviewPromptTemplate 使用示例变量渲染模板
+// content 为空时渲染当前生效的模板（按 locale、modelName 选择）
+func PreviewPromptTemplate(name, content, locale, modelName string, vars map[string]any) (string, error) {
+	if content != "" {
+		return promptTpl.Preview(name, content, vars)
+	}
+	target := promptTpl.DefaultTarget(modelName)
+	if locale != "" {
+		target.Locale = locale
+	}
+	return promptTpl.GetRegistry().Render(name, target, vars)
+}
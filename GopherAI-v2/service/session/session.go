@@ -3,17 +3,86 @@ package session
 import (
 	"GopherAI/common/aihelper"
 	"GopherAI/common/code"
+	"GopherAI/common/rag"
+	"GopherAI/config"
 	"GopherAI/dao/session"
 	"GopherAI/model"
+	"GopherAI/service/knowledge"
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 var ctx = context.Background()
 
+// buildModelConfig 构建创建模型所需的参数
+func buildModelConfig(userName string, modelName string) map[string]interface{} {
+	return map[string]interface{}{
+		"username":  userName,                         // 用于 RAG 模型获取用户文档
+		"baseURL":   config.GetConfig().OllamaBaseUrl, // Ollama 服务地址
+		"modelName": modelName,                        // Ollama 模型名称，为空时使用配置中的默认模型
+	}
+}
+
+// retrievalContext 校验检索过滤表达式并放入 context，RAG 模型检索知识库时只在匹配的文档中检索
+func retrievalContext(filter string) (context.Context, error) {
+	if _, err := rag.ParseFilter(filter); err != nil {
+		return nil, err
+	}
+	return rag.WithFilter(ctx, filter), nil
+}
+
+// resolveKnowledgeBases 校验当前用户可以检索这些知识库（自己的或共享的），0 表示默认知识库，返回去重后的知识库 ID
+func resolveKnowledgeBases(userName string, knowledgeBaseIDs []uint) ([]uint, code.Code) {
+	resolved := make([]uint, 0, len(knowledgeBaseIDs))
+	seen := make(map[uint]bool, len(knowledgeBaseIDs))
+	for _, id := range knowledgeBaseIDs {
+		kb, code_ := knowledge.GetReadableKnowledgeBase(userName, id)
+		if code_ != code.CodeSuccess {
+			return nil, code_
+		}
+		if !seen[kb.ID] {
+			seen[kb.ID] = true
+			resolved = append(resolved, kb.ID)
+		}
+	}
+	return resolved, code.CodeSuccess
+}
+
+// getUserSession 获取当前用户的会话，会话属于其他用户时返回 CodeForbidden
+func getUserSession(userName string, sessionID string) (*model.Session, code.Code) {
+	sess, err := session.GetSessionByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, code.CodeRecordNotFound
+	}
+	if err != nil {
+		log.Println("GetSessionByID error:", err)
+		return nil, code.CodeServerBusy
+	}
+	if sess.UserName != userName {
+		return nil, code.CodeForbidden
+	}
+	return sess, code.CodeSuccess
+}
+
+// getSessionHelper 获取会话的 AIHelper，并同步会话关联的知识库
+// 服务启动时从消息恢复的 AIHelper 不知道会话关联了哪些知识库，因此每次对话前都以数据库中的会话为准
+func getSessionHelper(userName string, sess *model.Session, modelType string, modelName string) (*aihelper.AIHelper, error) {
+	manager := aihelper.GetGlobalManager()
+	config := buildModelConfig(userName, modelName)
+	helper, err := manager.GetOrCreateAIHelper(userName, sess.ID, modelType, config)
+	if err != nil {
+		return nil, err
+	}
+	helper.SetKnowledgeBases(sess.KnowledgeBaseIDs)
+	return helper, nil
+}
+
 func GetUserSessionsByUserName(userName string) ([]model.SessionInfo, error) {
 	//获取用户的所有会话ID
 
@@ -32,46 +101,58 @@ func GetUserSessionsByUserName(userName string) ([]model.SessionInfo, error) {
 	return SessionInfos, nil
 }
 
-func CreateSessionAndSendMessage(userName string, userQuestion string, modelType string) (string, string, code.Code) {
+// knowledgeBaseIDs 为会话关联的知识库，对话时在其中检索相关文档，之后可以通过 UpdateSessionKnowledgeBases 修改
+func CreateSessionAndSendMessage(userName string, userQuestion string, modelType string, modelName string, filter string, knowledgeBaseIDs []uint) (string, string, []model.Citation, code.Code) {
+	reqCtx, err := retrievalContext(filter)
+	if err != nil {
+		log.Println("CreateSessionAndSendMessage invalid filter:", err)
+		return "", "", nil, code.CodeInvalidParams
+	}
+	knowledgeBaseIDs, code_ := resolveKnowledgeBases(userName, knowledgeBaseIDs)
+	if code_ != code.CodeSuccess {
+		return "", "", nil, code_
+	}
+
 	//1：创建一个新的会话
 	newSession := &model.Session{
-		ID:       uuid.New().String(),
-		UserName: userName,
-		Title:    userQuestion, // 可以根据需求设置标题，这边暂时用用户第一次的问题作为标题
+		ID:               uuid.New().String(),
+		UserName:         userName,
+		Title:            userQuestion, // 可以根据需求设置标题，这边暂时用用户第一次的问题作为标题
+		KnowledgeBaseIDs: knowledgeBaseIDs,
 	}
 	createdSession, err := session.CreateSession(newSession)
 	if err != nil {
 		log.Println("CreateSessionAndSendMessage CreateSession error:", err)
-		return "", "", code.CodeServerBusy
+		return "", "", nil, code.CodeServerBusy
 	}
 
 	//2：获取AIHelper并通过其管理消息
-	manager := aihelper.GetGlobalManager()
-	config := map[string]interface{}{
-		"apiKey":   "your-api-key", // TODO: 从配置中获取
-		"username": userName,       // 用于 RAG 模型获取用户文档
-	}
-	helper, err := manager.GetOrCreateAIHelper(userName, createdSession.ID, modelType, config)
+	helper, err := getSessionHelper(userName, createdSession, modelType, modelName)
 	if err != nil {
 		log.Println("CreateSessionAndSendMessage GetOrCreateAIHelper error:", err)
-		return "", "", code.AIModelFail
+		return "", "", nil, code.AIModelFail
 	}
 
 	//3：生成AI回复
-	aiResponse, err_ := helper.GenerateResponse(userName, ctx, userQuestion)
+	aiResponse, err_ := helper.GenerateResponse(userName, reqCtx, userQuestion)
 	if err_ != nil {
 		log.Println("CreateSessionAndSendMessage GenerateResponse error:", err_)
-		return "", "", code.AIModelFail
+		return "", "", nil, code.AIModelFail
 	}
 
-	return createdSession.ID, aiResponse.Content, code.CodeSuccess
+	return createdSession.ID, aiResponse.Content, aiResponse.Citations, code.CodeSuccess
 }
 
-func CreateStreamSessionOnly(userName string, userQuestion string) (string, code.Code) {
+func CreateStreamSessionOnly(userName string, userQuestion string, knowledgeBaseIDs []uint) (string, code.Code) {
+	knowledgeBaseIDs, code_ := resolveKnowledgeBases(userName, knowledgeBaseIDs)
+	if code_ != code.CodeSuccess {
+		return "", code_
+	}
 	newSession := &model.Session{
-		ID:       uuid.New().String(),
-		UserName: userName,
-		Title:    userQuestion,
+		ID:               uuid.New().String(),
+		UserName:         userName,
+		Title:            userQuestion,
+		KnowledgeBaseIDs: knowledgeBaseIDs,
 	}
 	createdSession, err := session.CreateSession(newSession)
 	if err != nil {
@@ -81,7 +162,13 @@ func CreateStreamSessionOnly(userName string, userQuestion string) (string, code
 	return createdSession.ID, code.CodeSuccess
 }
 
-func StreamMessageToExistingSession(userName string, sessionID string, userQuestion string, modelType string, writer http.ResponseWriter) code.Code {
+func StreamMessageToExistingSession(userName string, sessionID string, userQuestion string, modelType string, modelName string, filter string, writer http.ResponseWriter) code.Code {
+	reqCtx, err := retrievalContext(filter)
+	if err != nil {
+		log.Println("StreamMessageToExistingSession invalid filter:", err)
+		return code.CodeInvalidParams
+	}
+
 	// 确保 writer 支持 Flush
 	flusher, ok := writer.(http.Flusher)
 	if !ok {
@@ -89,12 +176,11 @@ func StreamMessageToExistingSession(userName string, sessionID string, userQuest
 		return code.CodeServerBusy
 	}
 
-	manager := aihelper.GetGlobalManager()
-	config := map[string]interface{}{
-		"apiKey":   "your-api-key", // TODO: 从配置中获取
-		"username": userName,       // 用于 RAG 模型获取用户文档
+	sess, code_ := getUserSession(userName, sessionID)
+	if code_ != code.CodeSuccess {
+		return code_
 	}
-	helper, err := manager.GetOrCreateAIHelper(userName, sessionID, modelType, config)
+	helper, err := getSessionHelper(userName, sess, modelType, modelName)
 	if err != nil {
 		log.Println("StreamMessageToExistingSession GetOrCreateAIHelper error:", err)
 		return code.AIModelFail
@@ -113,12 +199,23 @@ func StreamMessageToExistingSession(userName string, sessionID string, userQuest
 		log.Println("[SSE] Flushed")
 	}
 
-	_, err_ := helper.StreamResponse(userName, ctx, cb, userQuestion)
+	aiMsg, err_ := helper.StreamResponse(userName, reqCtx, cb, userQuestion)
 	if err_ != nil {
 		log.Println("StreamMessageToExistingSession StreamResponse error:", err_)
 		return code.AIModelFail
 	}
 
+	// 引用来源通过单独的 citations 事件下发，前端据此展示回答中 [n] 对应的文档
+	if len(aiMsg.Citations) > 0 {
+		data, _ := json.Marshal(aiMsg.Citations)
+		_, err = writer.Write([]byte("event: citations\ndata: " + string(data) + "\n\n"))
+		if err != nil {
+			log.Println("StreamMessageToExistingSession write citations error:", err)
+			return code.AIModelFail
+		}
+		flusher.Flush()
+	}
+
 	_, err = writer.Write([]byte("data: [DONE]\n\n"))
 	if err != nil {
 		log.Println("StreamMessageToExistingSession write DONE error:", err)
@@ -129,14 +226,14 @@ func StreamMessageToExistingSession(userName string, sessionID string, userQuest
 	return code.CodeSuccess
 }
 
-func CreateStreamSessionAndSendMessage(userName string, userQuestion string, modelType string, writer http.ResponseWriter) (string, code.Code) {
+func CreateStreamSessionAndSendMessage(userName string, userQuestion string, modelType string, modelName string, filter string, knowledgeBaseIDs []uint, writer http.ResponseWriter) (string, code.Code) {
 
-	sessionID, code_ := CreateStreamSessionOnly(userName, userQuestion)
+	sessionID, code_ := CreateStreamSessionOnly(userName, userQuestion, knowledgeBaseIDs)
 	if code_ != code.CodeSuccess {
 		return "", code_
 	}
 
-	code_ = StreamMessageToExistingSession(userName, sessionID, userQuestion, modelType, writer)
+	code_ = StreamMessageToExistingSession(userName, sessionID, userQuestion, modelType, modelName, filter, writer)
 	if code_ != code.CodeSuccess {
 
 		return sessionID, code_
@@ -145,26 +242,32 @@ func CreateStreamSessionAndSendMessage(userName string, userQuestion string, mod
 	return sessionID, code.CodeSuccess
 }
 
-func ChatSend(userName string, sessionID string, userQuestion string, modelType string) (string, code.Code) {
+func ChatSend(userName string, sessionID string, userQuestion string, modelType string, modelName string, filter string) (string, []model.Citation, code.Code) {
+	reqCtx, err := retrievalContext(filter)
+	if err != nil {
+		log.Println("ChatSend invalid filter:", err)
+		return "", nil, code.CodeInvalidParams
+	}
+
 	//1：获取AIHelper
-	manager := aihelper.GetGlobalManager()
-	config := map[string]interface{}{
-		"username": userName, // 用于 RAG 模型获取用户文档（若当前用户选择了RAG模型，该字段将会被用到）
+	sess, code_ := getUserSession(userName, sessionID)
+	if code_ != code.CodeSuccess {
+		return "", nil, code_
 	}
-	helper, err := manager.GetOrCreateAIHelper(userName, sessionID, modelType, config)
+	helper, err := getSessionHelper(userName, sess, modelType, modelName)
 	if err != nil {
 		log.Println("ChatSend GetOrCreateAIHelper error:", err)
-		return "", code.AIModelFail
+		return "", nil, code.AIModelFail
 	}
 
 	//2：生成AI回复
-	aiResponse, err_ := helper.GenerateResponse(userName, ctx, userQuestion)
+	aiResponse, err_ := helper.GenerateResponse(userName, reqCtx, userQuestion)
 	if err_ != nil {
 		log.Println("ChatSend GenerateResponse error:", err_)
-		return "", code.AIModelFail
+		return "", nil, code.AIModelFail
 	}
 
-	return aiResponse.Content, code.CodeSuccess
+	return aiResponse.Content, aiResponse.Citations, code.CodeSuccess
 }
 
 func GetChatHistory(userName string, sessionID string) ([]model.History, code.Code) {
@@ -182,15 +285,47 @@ func GetChatHistory(userName string, sessionID string) ([]model.History, code.Co
 	for i, msg := range messages {
 		isUser := i%2 == 0
 		history = append(history, model.History{
-			IsUser:  isUser,
-			Content: msg.Content,
+			IsUser:    isUser,
+			Content:   msg.Content,
+			Citations: msg.Citations,
 		})
 	}
 
 	return history, code.CodeSuccess
 }
 
-func ChatStreamSend(userName string, sessionID string, userQuestion string, modelType string, writer http.ResponseWriter) code.Code {
+func ChatStreamSend(userName string, sessionID string, userQuestion string, modelType string, modelName string, filter string, writer http.ResponseWriter) code.Code {
+
+	return StreamMessageToExistingSession(userName, sessionID, userQuestion, modelType, modelName, filter, writer)
+}
 
-	return StreamMessageToExistingSession(userName, sessionID, userQuestion, modelType, writer)
+// GetSessionKnowledgeBases 获取会话关联的知识库
+func GetSessionKnowledgeBases(userName string, sessionID string) ([]uint, code.Code) {
+	sess, code_ := getUserSession(userName, sessionID)
+	if code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	if sess.KnowledgeBaseIDs == nil {
+		return []uint{}, code.CodeSuccess
+	}
+	return sess.KnowledgeBaseIDs, code.CodeSuccess
+}
+
+// UpdateSessionKnowledgeBases 修改会话关联的知识库，从下一条消息开始生效，传空列表表示不再检索
+func UpdateSessionKnowledgeBases(userName string, sessionID string, knowledgeBaseIDs []uint) ([]uint, code.Code) {
+	if _, code_ := getUserSession(userName, sessionID); code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	knowledgeBaseIDs, code_ := resolveKnowledgeBases(userName, knowledgeBaseIDs)
+	if code_ != code.CodeSuccess {
+		return nil, code_
+	}
+	if err := session.UpdateSessionKnowledgeBases(sessionID, knowledgeBaseIDs); err != nil {
+		log.Println("UpdateSessionKnowledgeBases error:", err)
+		return nil, code.CodeServerBusy
+	}
+	if helper, exists := aihelper.GetGlobalManager().GetAIHelper(userName, sessionID); exists {
+		helper.SetKnowledgeBases(knowledgeBaseIDs)
+	}
+	return knowledgeBaseIDs, code.CodeSuccess
 }
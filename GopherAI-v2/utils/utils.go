@@ -1,16 +1,17 @@
 package utils
 
 import (
+	"GopherAI/common/rag/loader"
 	"GopherAI/model"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/rand"
 	"mime/multipart"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/schema"
@@ -86,13 +87,21 @@ func RemoveAllFilesInDir(dir string) error {
 	return nil
 }
 
-// ValidateFile 校验文件是否为允许的文本文件（.md 或 .txt）
+// ValidateFile 校验文件类型：扩展名必须受支持，并且文件内容（嗅探得到的 MIME 类型）与扩展名一致
 func ValidateFile(file *multipart.FileHeader) error {
-	// 校验文件扩展名
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if ext != ".md" && ext != ".txt" {
-		return fmt.Errorf("文件类型不正确，只允许 .md 或 .txt 文件，当前扩展名: %s", ext)
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
 	}
+	defer src.Close()
 
-	return nil
+	// 读取文件头用于内容嗅探
+	head := make([]byte, loader.SniffLimit)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	_, err = loader.Detect(file.Filename, head[:n])
+	return err
 }